@@ -3,8 +3,37 @@ package config
 import (
 	"path"
 	"path/filepath"
+	"time"
 )
 
 // Define config variables
 var DBFile, _ = filepath.Abs(path.Join("pkg/db", "book.db"))
 var LogFile, _ = filepath.Abs(path.Join("./", "api.log"))
+
+// QueryTimeout is the maximum time for the storage to complete a request, 0 means no timeout
+var QueryTimeout = 10 * time.Second
+
+// ShutdownTimeout is the grace period for the in-flight requests to complete when the server is shutting down
+var ShutdownTimeout = 15 * time.Second
+
+// RequireIfMatch will reject the book updates that do not have the If-Match header with 428 Precondition Required
+var RequireIfMatch = false
+
+// ActorHeader is the request header that name who made the book changes in the book history,
+// the client ip is recorded when the request does not have it.
+// It is not authenticated, set it from an authenticating proxy when the history need to be trusted
+var ActorHeader = "X-Actor"
+
+// PurgeRetention is how long the deleted books are kept before they are purged
+var PurgeRetention = 30 * 24 * time.Hour
+
+// PurgeInterval is how often the deleted books that are older than the retention are purged, 0 means never
+var PurgeInterval time.Duration
+
+// AdminToken is the bearer token of the administrator requests like include_deleted and purge,
+// empty means every administrator request is rejected
+var AdminToken string
+
+// CacheControl is the Cache-Control header of the book read responses, empty to leave it out.
+// The default no-cache let the clients keep the responses but revalidate them with the ETag or Last-Modified
+var CacheControl = "no-cache"
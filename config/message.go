@@ -5,23 +5,75 @@ const (
 	DBConnectErrMsg   = "failed to connect to database"
 	DBOperationErrMsg = "request failed. Verify data meets any requirements " +
 		"(i.e. uniqueness, null, etc...) and try again"
-	DBCloseErrMsg = "fail to close database"
+	DBCloseErrMsg         = "fail to close database"
+	DBMigrateErrMsg       = "fail to migrate database"
+	QueryTimeoutErrMsg    = "request took too long to complete. Please narrow down the request and try again"
+	QueryCanceledErrMsg   = "request was canceled before it completed"
+	DuplicateISBNErrMsg   = "isbn is already used by another book"
+	BatchRolledBackErrMsg = "some of the books could not be inserted, none of the books were inserted. " +
+		"See results for the books that failed"
 
 	// Operation error messages
-	InvalidDataErrMsg         = "invalid data passing."
-	UnsupportedContentType    = "unsupported Content-Type."
-	DataCouldNotBeEmptyErrMsg = "field is empty or not define.  Please fill out all required fields"
-	FailToSaveLogErrMsg       = "fail to save log:"
-	BadRequestErrMsg          = "bad Request. Please check your relative path"
+	InvalidDataErrMsg          = "invalid data passing."
+	UnsupportedContentType     = "unsupported Content-Type."
+	DataCouldNotBeEmptyErrMsg  = "field is empty or not define.  Please fill out all required fields"
+	FailToSaveLogErrMsg        = "fail to save log:"
+	BadRequestErrMsg           = "bad Request. Please check your relative path"
+	BookNotFoundErrMsg         = "book not found"
+	InvalidIDErrMsg            = "book_id must be a positive integer"
+	NoFieldsToUpdateErrMsg     = "no fields to update. Please fill out at least one field besides book_id"
+	InternalErrMsg             = "unexpected server error"
+	ShutdownErrMsg             = "fail to shut down server gracefully"
+	UnknownCommandErrMsg       = "unknown command. Usage: goapp [flags] migrate up|down [steps]|status or goapp [flags] purge [retention]"
+	InvalidCursorErrMsg        = "cursor is not valid. Use the next_cursor or prev_cursor of the previous response"
+	InvalidOrderByErrMsg       = "order_by must be one of:"
+	InvalidFieldsErrMsg        = "fields must be a comma separated list of:"
+	VersionConflictErrMsg      = "book was changed by another request. Get the book again and retry with its ETag"
+	IfMatchRequiredErrMsg      = "If-Match header with the book ETag is required to update the book"
+	InvalidPatchErrMsg         = "patch could not be applied to the book."
+	PatchTestFailedErrMsg      = "test operation of the patch does not match the book"
+	InvalidPatchedBookErrMsg   = "patched book is not valid. See errors for the fields that failed"
+	PatchedBookNotObjectErrMsg = "patched book must be a JSON object"
+	ReadOnlyFieldErrMsg        = "field is read-only and could not be changed"
+	UnknownFieldErrMsg         = "field is not a book field"
+	StringFieldErrMsg          = "field must be a string or null"
+	DeletedBookNotFoundErrMsg  = "deleted book not found"
+	InvalidRetentionErrMsg     = "older_than must be a duration like 720h or 30m"
+	PurgeErrMsg                = "fail to purge deleted books"
+	HistoryNotFoundErrMsg      = "book history not found. Use the history_id of a change of the book that it is reverted to"
+	RevertQueryErrMsg          = "history_id or at is required to revert the book"
+	InvalidISBNErrMsg          = "isbn is not a valid ISBN-10 or ISBN-13. See errors for the reason"
+	ISBNLengthErrMsg           = "isbn must have 10 or 13 digits"
+	ISBNCharacterErrMsg        = "isbn may only have digits, hyphens and spaces, and X as the ISBN-10 check digit"
+	ISBNChecksumErrMsg         = "isbn check digit does not match the other digits"
+	ISBNPrefixErrMsg           = "ISBN-13 must start with 978 or 979"
+	InvalidPublishedErrMsg     = "published is not a valid publication date. See errors for the reason"
+	PublishedFormatErrMsg      = "published must be a year, year and month or full date like 1949, 1949-06, June 1949 or 1949-06-08"
+	PublishedRangeErrMsg       = "published has a month or day that does not exist"
+	AuthorNotFoundErrMsg       = "author not found"
+	InvalidAuthorIDErrMsg      = "author_id must be a positive integer"
+	AuthorHasBooksErrMsg       = "author is credited for books. Set the authors of the books without the author first"
+	BookAuthorsRequiredErrMsg  = "at least one author is required"
+	InvalidBookAuthorsErrMsg   = "book authors are not valid. See errors for the authors that failed"
+	AuthorRoleErrMsg           = "role must be one of:"
+	UnknownAuthorErrMsg        = "Create the author before the book credit it"
+	PublisherNotFoundErrMsg    = "publisher not found"
+	InvalidPublisherIDErrMsg   = "publisher_id must be a positive integer"
+	PublisherHasBooksErrMsg    = "publisher has books. Merge it into another publisher or change the publisher of the books first"
+	UnknownPublisherErrMsg     = "Create the publisher first or use the publisher name"
+	DuplicatePublisherErrMsg   = "publisher name is already used by another publisher"
+	AdminRequiredErrMsg        = "request is only allowed for the administrators. Send the admin token as Authorization: Bearer <token>"
 
 	// Operation warning messages
 	FieldsBeEmptyWarningMsg     = "following fields were not included in the update:"
 	NoDataUpdateWarningMsg      = "no data update"
-	NoQueryDataPassedWarningMsg = "no data to pass in query. All string fields were empty and/or book_id is 0"
+	NoQueryDataPassedWarningMsg = "no data to pass in query. All string fields were empty and book_id is 0"
 
 	// Operation success messages
 	HomepageMsg      = "Welcome To Book Library!"
 	AddSuccessMsg    = "Data successfully added."
+	AddPartialMsg    = "Some of the data could not be added. See results for the books that failed"
 	UpdateSuccessMsg = "Data successfully updated."
 	DeleteSuccessMsg = "Data successfully deleted."
+	PurgeSuccessMsg  = "Deleted data successfully purged."
 )
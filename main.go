@@ -1,9 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"goapp/config"
 	"goapp/pkg/api"
 	"goapp/pkg/db"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
@@ -18,17 +28,185 @@ import (
 
 // Main function to start up the server and all services.
 func main() {
-	// Enter --addr/--debug flag options if you don't want to use default setup
-	// By default the server will start on localhost:8080 and debug level is set to false
+	// Enter --addr/--debug/--storage/--db-url flag options if you don't want to use default setup
+	// By default the server will start on localhost:8080 with the local sqlite database and debug level is set to false
 	address := flag.String("addr", ":8080", "host address")
 	debug := flag.Bool("debug", false, "debug mode")
+	storage := flag.String("storage", "sql", "storage backend, sql (uses --db-url) or memory")
+	dbURL := flag.String("db-url", "sqlite://"+config.DBFile, "database url, sqlite://<file path> or postgres://<dsn>")
+	queryTimeout := flag.Duration("query-timeout", config.QueryTimeout, "maximum time for a request database query, 0 to disable")
+	shutdownTimeout := flag.Duration("shutdown-timeout", config.ShutdownTimeout, "grace period for in-flight requests on shutdown")
+	autoMigrate := flag.Bool("auto-migrate", true, "apply pending database migrations on startup")
+	requireIfMatch := flag.Bool("require-if-match", config.RequireIfMatch, "reject book updates without the If-Match header")
+	purgeRetention := flag.Duration("purge-retention", config.PurgeRetention, "how long the deleted books are kept before they are purged")
+	purgeInterval := flag.Duration("purge-interval", config.PurgeInterval, "how often the deleted books are purged, 0 to disable")
+	actorHeader := flag.String("actor-header", config.ActorHeader, "request header that name who made the book changes in the book history")
+	adminToken := flag.String("admin-token", os.Getenv("ADMIN_TOKEN"), "bearer token of the admin requests like include_deleted and purge, ADMIN_TOKEN by default")
+	cacheControl := flag.String("cache-control", config.CacheControl, "Cache-Control header of the book read responses, empty to leave it out")
 	flag.Parse()
-	d := db.OpenSqliteStorage()
+	config.QueryTimeout = *queryTimeout
+	config.ShutdownTimeout = *shutdownTimeout
+	config.RequireIfMatch = *requireIfMatch
+	config.CacheControl = *cacheControl
+	config.AdminToken = *adminToken
+	config.ActorHeader = *actorHeader
+	config.PurgeRetention = *purgeRetention
+	config.PurgeInterval = *purgeInterval
+	d, err := openStorage(*storage, *dbURL)
+	if err != nil {
+		log.Fatal().Err(err).Msg(config.DBConnectErrMsg)
+	}
 	defer d.CloseDB()
+
+	if flag.NArg() > 0 {
+		if err := runCommand(d, flag.Args()); err != nil {
+			log.Error().Err(err).Msg(config.DBMigrateErrMsg)
+			d.CloseDB()
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *autoMigrate {
+		if err := d.MigrateUp(); err != nil {
+			log.Fatal().Err(err).Msg(config.DBMigrateErrMsg)
+		}
+	}
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go schedulePurge(purgeCtx, d)
+
 	router := gin.New()
-	router.Use(gin.Recovery())
+	router.Use(gin.CustomRecovery(api.RecoveryHandler))
 	server := api.GetServer(*address, router, d)
 	server.StartLogging(debug)
 	log.Info().Msgf("Server is running port -> %s", *address)
-	log.Fatal().Err(server.StartServer()).Msg("fail to start server")
+	if err := serve(server); err != nil {
+		log.Error().Err(err).Msg("server stopped with error")
+		d.CloseDB()
+		os.Exit(1)
+	}
+	log.Info().Msg("Server stopped")
+}
+
+// serve will run the server until SIGINT or SIGTERM is received,
+// then shut it down gracefully within the configured shutdown timeout
+func serve(server *api.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.StartServer() }()
+	select {
+	case err := <-errc:
+		server.StopLogging()
+		return err
+	case <-ctx.Done():
+		stop()
+	}
+
+	log.Info().Msgf("Server is shutting down, waiting up to %s for in-flight requests", config.ShutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// schedulePurge will purge the deleted books that are older than the retention every purge interval
+// until the context is done, it will return right away when the interval is 0
+func schedulePurge(ctx context.Context, d db.Storage) {
+	if config.PurgeInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(config.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := d.PurgeBooks(ctx, config.PurgeRetention)
+			if err != nil {
+				log.Error().Err(err).Msg(config.PurgeErrMsg)
+				continue
+			}
+			if n > 0 {
+				log.Info().Msgf("Purged %d books that are deleted longer than %s ago", n, config.PurgeRetention)
+			}
+		}
+	}
+}
+
+// openStorage to open the storage backend, memory storage will start empty and is lost on exit
+func openStorage(storage string, dbURL string) (db.Database, error) {
+	switch storage {
+	case "sql":
+		return db.OpenStorage(dbURL)
+	case "memory":
+		return db.NewMemoryStorage(), nil
+	}
+	return nil, fmt.Errorf("unsupported storage: %s", storage)
+}
+
+// runCommand to run the sub command instead of starting the server.
+// Supported commands are: migrate up, migrate down [steps], migrate status and purge [retention]
+func runCommand(d db.Database, args []string) error {
+	if args[0] == "purge" {
+		return runPurge(d, args[1:])
+	}
+	if len(args) < 2 || args[0] != "migrate" {
+		return errors.New(config.UnknownCommandErrMsg)
+	}
+
+	switch args[1] {
+	case "up":
+		return d.MigrateUp()
+	case "down":
+		steps := 1
+		if len(args) > 2 {
+			n, err := strconv.Atoi(args[2])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid steps %q: %s", args[2], config.UnknownCommandErrMsg)
+			}
+			steps = n
+		}
+		return d.MigrateDown(steps)
+	case "status":
+		status, err := d.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, m := range status {
+			applied := "pending"
+			if m.Applied {
+				applied = m.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s %s\n", m.Version, m.Name, applied)
+		}
+		return nil
+	}
+	return errors.New(config.UnknownCommandErrMsg)
+}
+
+// runPurge will purge the deleted books that are older than the retention argument, or the configured retention
+func runPurge(d db.Database, args []string) error {
+	retention := config.PurgeRetention
+	if len(args) > 0 {
+		r, err := time.ParseDuration(args[0])
+		if err != nil || r < 0 {
+			return errors.New(config.InvalidRetentionErrMsg)
+		}
+		retention = r
+	}
+	n, err := d.PurgeBooks(context.Background(), retention)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("purged %d books that are deleted longer than %s ago\n", n, retention)
+	return nil
 }
@@ -0,0 +1,311 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"goapp/config"
+	"goapp/pkg/db"
+	"goapp/pkg/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authorLocation will return the path of the single author resource
+func authorLocation(id int) string {
+	return fmt.Sprintf("/v1/authors/%d", id)
+}
+
+// listAuthorsRequest godoc
+//
+//	@Summary		List Authors
+//	@Description	For listing authors per page, ordered by surname and name.
+//	@Description	With q only the authors that have it in their name or surname are listed, case-insensitive.
+//	@Tags			authors
+//	@Produce		json
+//	@Param			q			query	string	false	"Text in the author name or surname."
+//	@Param			page_id		query	int		false	"Page of the authors, default 1."
+//	@Param			page_size	query	int		false	"Number of authors in a page, default 25."
+//	@Success		200	{array}		model.Author
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/authors [get]
+func (s *Server) listAuthorsRequest(c *gin.Context) {
+	var req model.ListAuthorRequest
+	if err := c.ShouldBindQuery(&req); !ValidateBinding(c, err, &req, http.StatusBadRequest) {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	as, err := s.db.ListAuthors(ctx, &db.AuthorQuery{Name: req.Q, Limit: req.PageSize, OffSet: (req.PageID - 1) * req.PageSize})
+	if err != nil {
+		HandleDBError(c, "listAuthorsRequest", err)
+	} else {
+		c.JSON(http.StatusOK, as)
+	}
+}
+
+// getAuthorRequest godoc
+//
+//	@Summary		Get Author by author_id
+//	@Description	For getting an author by id.
+//	@Tags			authors
+//	@Produce		json
+//	@Param			id	path	int	true	"The author_id to be found."
+//	@Success		200	{object}	model.Author
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/authors/{id} [get]
+func (s *Server) getAuthorRequest(c *gin.Context) {
+	id, ok := ValidateAuthorID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	a, err := s.db.GetAuthor(ctx, id)
+	if err != nil {
+		HandleDBError(c, "getAuthorRequest", err)
+	} else {
+		c.JSON(http.StatusOK, a)
+	}
+}
+
+// insertAuthorRequest godoc
+//
+//	@Summary		Insert Author
+//	@Description	For inserting an author that the books could credit.
+//	@Description	Will return 201 with the new author and the Location header that point to it.
+//	@Tags			authors
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body	model.Author	true	"Fields Required: surname. author_id and updated_at will be ignored."
+//	@Success		201	{object}	model.Author
+//	@Header			201	{string}	Location	"/v1/authors/{id} of the inserted author"
+//	@Failure		400	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/authors [post]
+func (s *Server) insertAuthorRequest(c *gin.Context) {
+	if !ValidateContentType(c) {
+		return
+	}
+	var a model.Author
+	if err := c.ShouldBindJSON(&a); !ValidateBinding(c, err, &a, http.StatusUnprocessableEntity) {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	if err := s.db.InsertAuthor(ctx, &a); err != nil {
+		HandleDBError(c, "insertAuthorRequest", err)
+		return
+	}
+	c.Header("Location", authorLocation(a.ID))
+	c.JSON(http.StatusCreated, a)
+}
+
+// updateAuthorRequest godoc
+//
+//	@Summary		Update Author by author_id
+//	@Description	For updating the name and surname of an author by id.
+//	@Description	The books that credit the author first get the new author_name and author_surname with a new version,
+//	@Description	the change is recorded in their history with the authors operation.
+//	@Description	Will return the updated author.
+//	@Tags			authors
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int				true	"The author_id to be updated."
+//	@Param			body	body	model.Author	true	"Fields Required: surname. author_id and updated_at will be ignored."
+//	@Success		200	{object}	model.Author
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/authors/{id} [put]
+func (s *Server) updateAuthorRequest(c *gin.Context) {
+	if !ValidateContentType(c) {
+		return
+	}
+	id, ok := ValidateAuthorID(c)
+	if !ok {
+		return
+	}
+	var a model.Author
+	if err := c.ShouldBindJSON(&a); !ValidateBinding(c, err, &a, http.StatusUnprocessableEntity) {
+		return
+	}
+	a.ID = id
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	if err := s.db.UpdateAuthor(ctx, &a); err != nil {
+		HandleDBError(c, "updateAuthorRequest", err)
+		return
+	}
+	a, err := s.db.GetAuthor(ctx, id)
+	if err != nil {
+		HandleDBError(c, "updateAuthorRequest", err)
+	} else {
+		c.JSON(http.StatusOK, a)
+	}
+}
+
+// deleteAuthorRequest godoc
+//
+//	@Summary		Delete Author
+//	@Description	For deleting an author by id, will return 409 if any book still credit the author, the deleted books too.
+//	@Tags			authors
+//	@Produce		json
+//	@Param			id	path	int	true	"The author_id to be deleted."
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/authors/{id} [delete]
+func (s *Server) deleteAuthorRequest(c *gin.Context) {
+	id, ok := ValidateAuthorID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	if err := s.db.DeleteAuthor(ctx, id); err != nil {
+		HandleDBError(c, "deleteAuthorRequest", err)
+	} else {
+		ValidateRowsAffected(c, 1, config.DeleteSuccessMsg)
+	}
+}
+
+// authorBooksRequest godoc
+//
+//	@Summary		Author Books
+//	@Description	For listing the books that credit an author with the role and position of the author, ordered by published date.
+//	@Description	The deleted books are left out, will return 404 if there is no such author.
+//	@Tags			authors
+//	@Produce		json
+//	@Param			id			path	int	true	"The author_id of the books."
+//	@Param			page_id		query	int	false	"Page of the books, default 1."
+//	@Param			page_size	query	int	false	"Number of books in a page, default 25."
+//	@Success		200	{array}		model.AuthorBook
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/authors/{id}/books [get]
+func (s *Server) authorBooksRequest(c *gin.Context) {
+	id, ok := ValidateAuthorID(c)
+	if !ok {
+		return
+	}
+	var req model.AuthorBooksRequest
+	if err := c.ShouldBindQuery(&req); !ValidateBinding(c, err, &req, http.StatusBadRequest) {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	abs, err := s.db.ListAuthorBooks(ctx, &db.AuthorBooksQuery{AuthorID: id, Limit: req.PageSize,
+		OffSet: (req.PageID - 1) * req.PageSize})
+	if err != nil {
+		HandleDBError(c, "authorBooksRequest", err)
+	} else {
+		c.JSON(http.StatusOK, abs)
+	}
+}
+
+// bookAuthorsRequest godoc
+//
+//	@Summary		Book Authors
+//	@Description	For listing the authors that a book credit in the credit order, with their role and position.
+//	@Tags			books
+//	@Produce		json
+//	@Param			id	path	int	true	"The book_id of the authors."
+//	@Success		200	{array}		model.BookAuthor
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/books/{id}/authors [get]
+func (s *Server) bookAuthorsRequest(c *gin.Context) {
+	id, ok := ValidateBookID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	bas, err := s.db.GetBookAuthors(ctx, id)
+	if err != nil {
+		HandleDBError(c, "bookAuthorsRequest", err)
+	} else {
+		c.JSON(http.StatusOK, bas)
+	}
+}
+
+// setBookAuthorsRequest godoc
+//
+//	@Summary		Set Book Authors
+//	@Description	For replacing the authors of a book, the authors are credited in the order of the list.
+//	@Description	The role is one of author (default), editor, translator and illustrator, an author could be listed once per role.
+//	@Description	The book get the name of the first author as author_name and author_surname with a new version when it is changed.
+//	@Description	Will return the authors of the book, 404 if there is no such book and 422 if an author does not exist.
+//	@Tags			books
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int					true	"The book_id of the authors."
+//	@Param			body	body	[]model.BookAuthor	true	"Fields Required: author_id. name, surname and position will be ignored."
+//	@Success		200	{array}		model.BookAuthor
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/books/{id}/authors [put]
+func (s *Server) setBookAuthorsRequest(c *gin.Context) {
+	if !ValidateContentType(c) {
+		return
+	}
+	id, ok := ValidateBookID(c)
+	if !ok {
+		return
+	}
+	var bas []model.BookAuthor
+	if err := c.ShouldBindJSON(&bas); !ValidateBinding(c, err, bas, http.StatusUnprocessableEntity) {
+		return
+	}
+	if !ValidateBookAuthors(c, bas) {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	err := s.db.SetBookAuthors(ctx, id, bas)
+	if errors.Is(err, db.ErrAuthorNotFound) {
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, config.InvalidBookAuthorsErrMsg,
+			FieldError{Field: "author_id", Code: "exists", Message: fmt.Sprintf("%s. %s", err.Error(), config.UnknownAuthorErrMsg)}))
+		return
+	}
+	if err != nil {
+		HandleDBError(c, "setBookAuthorsRequest", err)
+		return
+	}
+	if bas, err = s.db.GetBookAuthors(ctx, id); err != nil {
+		HandleDBError(c, "setBookAuthorsRequest", err)
+	} else {
+		c.JSON(http.StatusOK, bas)
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"goapp/config"
+	"goapp/pkg/model"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// renderCached will response with the json body and its Cache-Control, ETag and Last-Modified headers,
+// or with 304 Not Modified when the If-None-Match or If-Modified-Since validators of the request still match.
+// When the etag is empty the weak ETag of the body and the page headers is used, a zero modified time is left out.
+// The fields and the other query parameters are part of the cached url, the response also Vary by the Authorization
+// as the deleted books are only returned to the administrators.
+func renderCached(c *gin.Context, obj interface{}, etag string, modified time.Time) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		log.Error().Msgf("renderCached failed: %s", err.Error())
+		AbortWithProblem(c, NewProblem(http.StatusInternalServerError, CodeInternal, config.InternalErrMsg))
+		return
+	}
+	if etag == "" {
+		etag = bodyETag(body, c.Writer.Header().Get("Link"), c.Writer.Header().Get("X-Total-Count"))
+	}
+	c.Header("ETag", etag)
+	c.Header("Vary", "Authorization")
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if config.CacheControl != "" {
+		c.Header("Cache-Control", config.CacheControl)
+	}
+	if notModified(c.Request, etag, modified) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// notModified will check if the request validators match the current ETag or modified time.
+// If-Modified-Since is only used when there is no If-None-Match as RFC 9110 define.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			if tag = strings.TrimSpace(tag); tag == "*" || weakETag(tag) == weakETag(etag) {
+				return true
+			}
+		}
+		return false
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modified.IsZero() {
+		return false
+	}
+	// the header only has second precision
+	return !modified.Truncate(time.Second).After(ims)
+}
+
+// weakETag will return the opaque tag without the weak prefix, the If-None-Match use the weak comparison
+func weakETag(tag string) string {
+	return strings.TrimPrefix(tag, "W/")
+}
+
+// bodyETag will return the weak ETag of the response body and the headers that are part of the response
+func bodyETag(body []byte, headers ...string) string {
+	h := sha256.New()
+	h.Write(body)
+	for _, v := range headers {
+		h.Write([]byte("\n" + v))
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
+
+// lastModified will return the latest updated_at of the books
+func lastModified(bks []model.Book) time.Time {
+	var t time.Time
+	for _, bk := range bks {
+		if bk.UpdatedAt.After(t) {
+			t = bk.UpdatedAt
+		}
+	}
+	return t
+}
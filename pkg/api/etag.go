@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"goapp/config"
+	"goapp/pkg/db"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bookETag will return the strong entity tag of the book version like "3".
+// A sparse fieldset is another representation of the book, so its fields are added to the tag like "3+isbn+title"
+// in sorted order, the fields that are asked in another order are the same representation.
+func bookETag(version int, fields ...string) string {
+	tag := strconv.Itoa(version)
+	if len(fields) > 0 {
+		sorted := append([]string(nil), fields...)
+		sort.Strings(sorted)
+		tag += "+" + strings.Join(sorted, "+")
+	}
+	return `"` + tag + `"`
+}
+
+// setBookETag will set the ETag header of the book version, there is none when the version is not known
+func setBookETag(c *gin.Context, version int) {
+	if version > 0 {
+		c.Header("ETag", bookETag(version))
+	}
+}
+
+// parseETagVersions will return the book versions of the If-Match entity tags and if it is the * wildcard.
+// The tag of a sparse fieldset has the same version as the whole book, so the fields after the + are ignored.
+// Weak and malformed tags could never match a book version so they are skipped.
+func parseETagVersions(header string) ([]int, bool) {
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, _, _ := strings.Cut(tag[1:len(tag)-1], "+")
+		if v, err := strconv.Atoi(version); err == nil && v > 0 {
+			versions = append(versions, v)
+		}
+	}
+	return versions, false
+}
+
+// ifMatchVersion will return the version that the book need to have to be updated, 0 for any version.
+// Without the If-Match header it is the version of the request body, or it will response with 428
+// when the header is required. When none of the If-Match tags are the current book version
+// it will response with 412 and return false.
+func (s *Server) ifMatchVersion(ctx context.Context, c *gin.Context, id int, version int) (int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		if config.RequireIfMatch {
+			AbortWithProblem(c, NewProblem(http.StatusPreconditionRequired, CodePreconditionRequired, config.IfMatchRequiredErrMsg))
+			return 0, false
+		}
+		return version, true
+	}
+	versions, wildcard := parseETagVersions(header)
+	switch {
+	case wildcard:
+		return 0, true
+	case len(versions) == 0:
+		AbortWithProblem(c, NewProblem(http.StatusPreconditionFailed, CodeVersionConflict, config.VersionConflictErrMsg))
+		return 0, false
+	case len(versions) == 1:
+		return versions[0], true
+	}
+	// with several tags the update is conditional on the one that is the current version
+	bk, err := s.db.GetBook(ctx, id, "book_id", "version")
+	if err != nil {
+		HandleDBError(c, "ifMatchVersion", err)
+		return 0, false
+	}
+	for _, v := range versions {
+		if v == bk.Version {
+			return v, true
+		}
+	}
+	HandleDBError(c, "ifMatchVersion", db.ErrVersionConflict)
+	return 0, false
+}
+
+// setUpdatedETag will set the ETag header of the new version of the updated book
+func (s *Server) setUpdatedETag(ctx context.Context, c *gin.Context, id int) {
+	if bk, err := s.db.GetBook(ctx, id, "book_id", "version"); err == nil {
+		setBookETag(c, bk.Version)
+	}
+}
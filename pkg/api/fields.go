@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"goapp/config"
+	"goapp/pkg/db"
+	"goapp/pkg/model"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateFields will parse the comma separated book fields of the fields query parameter
+// and response with 400 if any of them is not a book field, the fields are nil when the parameter is empty
+func ValidateFields(c *gin.Context) ([]string, bool) {
+	param := strings.TrimSpace(c.Query("fields"))
+	if param == "" {
+		return nil, true
+	}
+	var fields []string
+	used := map[string]bool{}
+	for _, f := range strings.Split(param, ",") {
+		f = strings.TrimSpace(f)
+		if !db.IsBookField(f) {
+			msg := fmt.Sprintf("%s %s", config.InvalidFieldsErrMsg, strings.Join(db.BookFields, ", "))
+			AbortWithProblem(c, NewProblem(http.StatusBadRequest, CodeInvalidQuery, fmt.Sprintf("unknown field %q", f),
+				FieldError{Field: "fields", Code: "oneof", Message: msg}))
+			return nil, false
+		}
+		if !used[f] {
+			used[f] = true
+			fields = append(fields, f)
+		}
+	}
+	return fields, true
+}
+
+// withColumns will return the storage columns of the requested fields with the extra columns that are needed
+// to build the response, nil to select all columns when there is no requested field
+func withColumns(fields []string, extra ...string) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+	cols := append([]string{}, fields...)
+	for _, col := range extra {
+		found := false
+		for _, f := range fields {
+			found = found || f == col
+		}
+		if !found {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// shapeBook will return the book with only the requested fields, the book as it is if there is no field
+func shapeBook(bk model.Book, fields []string) interface{} {
+	if len(fields) == 0 {
+		return bk
+	}
+	return bookFields(&bk, fields)
+}
+
+// shapeBooks will return the books with only the requested fields, the books as they are if there is no field
+func shapeBooks(bks []model.Book, fields []string) interface{} {
+	if len(fields) == 0 {
+		return bks
+	}
+	shaped := make([]map[string]interface{}, len(bks))
+	for i := range bks {
+		shaped[i] = bookFields(&bks[i], fields)
+	}
+	return shaped
+}
+
+// shapeSearchResults will return the search results with only the requested book fields, the rank and the snippet
+func shapeSearchResults(results []db.SearchResult, fields []string) interface{} {
+	if len(fields) == 0 {
+		return results
+	}
+	shaped := make([]map[string]interface{}, len(results))
+	for i := range results {
+		shaped[i] = bookFields(&results[i].Book, fields)
+		shaped[i]["rank"] = results[i].Rank
+		shaped[i]["snippet"] = results[i].Snippet
+	}
+	return shaped
+}
+
+// bookFields will return the json field name and value of the requested book fields
+func bookFields(bk *model.Book, fields []string) map[string]interface{} {
+	v := reflect.ValueOf(bk).Elem()
+	t := v.Type()
+	m := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		for i := 0; i < t.NumField(); i++ {
+			if strings.Split(t.Field(i).Tag.Get("json"), ",")[0] == f {
+				m[f] = v.Field(i).Interface()
+			}
+		}
+	}
+	return m
+}
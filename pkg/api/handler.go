@@ -1,14 +1,20 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"goapp/config"
 	"goapp/pkg/db"
+	"goapp/pkg/isbn"
+	"goapp/pkg/jsonpatch"
 	"goapp/pkg/model"
 	"net/http"
+	"os"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
@@ -20,9 +26,11 @@ import (
 )
 
 type Server struct {
-	address *http.Server
-	router  *gin.Engine
-	db      db.Storage
+	address   *http.Server
+	router    *gin.Engine
+	db        db.Storage
+	logFile   *os.File
+	logWriter *switchWriter
 }
 
 // GetServer to initialize the api server and database
@@ -38,22 +46,100 @@ func GetServer(addr string, h *gin.Engine, d db.Storage) *Server {
 	}
 }
 
-// StartServer to start up the services
+// StartServer to start up the services.
+// It will block until the server fail or is shut down, after Shutdown it will return http.ErrServerClosed
 func (s *Server) StartServer() error {
+	s.SetupRoutes()
+	return s.address.ListenAndServe()
+}
+
+// Shutdown will stop accepting new connections and wait for the in-flight requests to complete
+// until the context is done, then flush and close the log file.
+// The database is not closed since it is owned by the caller.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.address.Shutdown(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg(config.ShutdownErrMsg)
+	}
+	s.StopLogging()
+	return err
+}
+
+// SetupRoutes to register the api routes on the router and return it.
+// Any middleware need to be added to the router before the routes are registered.
+func (s *Server) SetupRoutes() *gin.Engine {
 	docs.SwaggerInfo.BasePath = "/v1"
 	v1 := s.router.Group("/v1")
 	{
 		v1.GET("/", s.homePageRequest)
 		v1.GET("/books", s.listBooksRequest)
+		v1.GET("/books/search", s.fullTextSearchRequest)
 		v1.POST("/books/search", s.searchBooksRequest)
 		v1.POST("/books/get", s.getBooksRequest)
+		v1.GET("/books/:id", s.getBookRequest)
 		v1.POST("/books", s.insertBooksRequest)
 		v1.PUT("/books", s.updateBooksRequest)
 		v1.PATCH("/books", s.patchBooksRequest)
+		v1.PATCH("/books/:id", s.patchBookRequest)
 		v1.DELETE("/books/:id", s.deleteBooksRequest)
+		v1.POST("/books/:id/restore", s.restoreBookRequest)
+		v1.GET("/books/:id/history", s.bookHistoryRequest)
+		v1.POST("/books/:id/revert", s.revertBookRequest)
+		v1.POST("/books/purge", s.purgeBooksRequest)
+		v1.GET("/books/:id/authors", s.bookAuthorsRequest)
+		v1.PUT("/books/:id/authors", s.setBookAuthorsRequest)
+		v1.GET("/authors", s.listAuthorsRequest)
+		v1.POST("/authors", s.insertAuthorRequest)
+		v1.GET("/authors/:id", s.getAuthorRequest)
+		v1.PUT("/authors/:id", s.updateAuthorRequest)
+		v1.DELETE("/authors/:id", s.deleteAuthorRequest)
+		v1.GET("/authors/:id/books", s.authorBooksRequest)
+		v1.GET("/publishers", s.listPublishersRequest)
+		v1.POST("/publishers", s.insertPublisherRequest)
+		v1.GET("/publishers/duplicates", s.publisherDuplicatesRequest)
+		v1.GET("/publishers/:id", s.getPublisherRequest)
+		v1.PUT("/publishers/:id", s.updatePublisherRequest)
+		v1.DELETE("/publishers/:id", s.deletePublisherRequest)
+		v1.GET("/publishers/:id/books", s.publisherBooksRequest)
+		v1.POST("/publishers/:id/merge", s.mergePublishersRequest)
+		v1.GET("/isbn/:isbn", s.isbnRequest)
 		v1.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
-	return s.address.ListenAndServe()
+	s.router.NoRoute(s.notFoundRequest)
+	return s.router
+}
+
+// queryContext will return the request context with the configured query timeout,
+// so the storage query is stopped when the client disconnects or the timeout exceeded.
+// The book changes of the context are recorded in the book history as made by the request actor.
+// The context is also set as the request context, so HandleDBError could tell the timeout from the storage error.
+func (s *Server) queryContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	ctx := db.WithActor(c.Request.Context(), requestActor(c))
+	var cancel context.CancelFunc
+	if config.QueryTimeout <= 0 {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithTimeout(ctx, config.QueryTimeout)
+	}
+	c.Request = c.Request.WithContext(ctx)
+	return ctx, cancel
+}
+
+// maxActorLength is the longest actor that the book history could keep
+const maxActorLength = 100
+
+// requestActor will return the actor of the ActorHeader, the client ip when there is none.
+// The header is not authenticated, any client could name any actor, so the actor of the book history
+// is only what the client claims unless a proxy in front of the server sets the header.
+func requestActor(c *gin.Context) string {
+	actor := strings.TrimSpace(c.GetHeader(config.ActorHeader))
+	if actor == "" {
+		return c.ClientIP()
+	}
+	if r := []rune(actor); len(r) > maxActorLength {
+		actor = string(r[:maxActorLength])
+	}
+	return actor
 }
 
 // homePageRequest for accessing to home page
@@ -61,40 +147,228 @@ func (s *Server) homePageRequest(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": config.HomepageMsg})
 }
 
+// notFoundRequest for any path that does not match a route
+func (s *Server) notFoundRequest(c *gin.Context) {
+	AbortWithProblem(c, NewProblem(http.StatusNotFound, CodeNotFound, config.BadRequestErrMsg))
+}
+
 // listBooksRequest godoc
 //
 //	@Summary		Get Books
 //	@Description	For listing books per page.
-//	@Description	By default will order by book_id and displays 1000 books in a page.
+//	@Description	By default will order by book_id and displays 25 books in a page.
+//	@Description	order_by is a comma separated list of book fields, a field with - prefix is ordered descending, books with the same values are ordered by book_id.
+//	@Description	With pagination=cursor, or when a cursor is passed, will return the page with the next_cursor and prev_cursor to continue from,
+//	@Description	the page_id is ignored and the cursor is only valid for the same order_by.
+//	@Description	With meta=headers (default) the total count and the page links are in the X-Total-Count and Link headers,
+//	@Description	with meta=body the books are returned in the books field next to the total, page, page_size and page links.
+//	@Description	The cursor pagination always return the books field with the cursors.
+//	@Description	Will return 304 without the books when If-None-Match has the ETag of the page, or without If-None-Match when it is not modified since If-Modified-Since.
+//	@Description	Every book field can be used as a filter like author_surname=eq:Orwell&published=gte:1940&title=contains:farm,
+//	@Description	the operators are eq (default), ne, gt, gte, lt, lte, contains and starts, the filters are combined with AND.
+//	@Description	Filters that are prefixed with the same group name like any.title=contains:farm&any.title=contains:1984 are combined with OR.
+//	@Description	The published value is compared with its period, published=1949 match every date of 1949 and published=gt:1949-06 match from July 1949.
 //	@Tags			books
 //	@Produce		json
-//	@Param			order_by	query	string	false	"Order by field"	default(book_id)
+//	@Param			order_by	query	string	false	"Comma separated order by fields, - prefix for descending like author_surname,-published"	default(book_id)
 //	@Param			page_id		query	int		false	"Page number"		default(1)	minimum(1)
 //	@Param			page_size	query	int		false	"Results per page"	default(25)	minimum(5)	maximum(1000)
-//	@Success		200
-//	@Failure		400
-//	@Failure		500
+//	@Param			fields		query	string	false	"Comma separated book fields to return like book_id,title,author_surname, all fields by default"
+//	@Param			pagination	query	string	false	"Pagination mode"	Enums(page, cursor)	default(page)
+//	@Param			cursor		query	string	false	"next_cursor or prev_cursor of the previous page"
+//	@Param			meta		query	string	false	"Page metadata in the headers or in the response body"	Enums(headers, body)	default(headers)
+//	@Param			include_deleted	query	bool	false	"List the deleted books too, admin only"	default(false)
+//	@Param			Authorization	header	string	false	"Bearer admin token, required with include_deleted"
+//	@Param			author_surname	query	string	false	"Filter like eq:Orwell, every book field can be used as a filter"
+//	@Param			published		query	string	false	"Filter like gte:1940"
+//	@Param			title			query	string	false	"Filter like contains:farm"
+//	@Param			If-None-Match		header	string	false	"ETag of the cached page"
+//	@Param			If-Modified-Since	header	string	false	"Last-Modified of the cached page"
+//	@Success		200	{array}		model.Book
+//	@Success		200	{object}	ListBooksResponse
+//	@Success		304
+//	@Header			200	{integer}	X-Total-Count	"Number of all books, meta=headers only"
+//	@Header			200	{string}	Link			"RFC 8288 first, prev, next and last page links, meta=headers only"
+//	@Header			200,304	{string}	ETag			"Weak ETag of the page"
+//	@Header			200,304	{string}	Last-Modified	"Latest updated_at of the books in the page"
+//	@Header			200,304	{string}	Cache-Control	"Configured cache policy"
+//	@Header			200,304	{string}	Vary			"Authorization, the deleted books are only returned to the administrators"
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
 //	@Router			/books [get]
 func (s *Server) listBooksRequest(c *gin.Context) {
 	// Default page list configuration
 	var list *model.ListBookRequest
-	if err := c.ShouldBindQuery(&list); err != nil {
-		log.Error().Msgf("listBooksRequest failed: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": config.BadRequestErrMsg})
+	if err := c.ShouldBindQuery(&list); !ValidateBinding(c, err, list, http.StatusBadRequest) {
+		return
+	}
+	order, err := db.ParseOrderBy(list.OrderBy)
+	if err != nil {
+		msg := fmt.Sprintf("%s %s. Prefix the field with - for descending order", config.InvalidOrderByErrMsg,
+			strings.Join(db.BookColumns, ", "))
+		AbortWithProblem(c, NewProblem(http.StatusBadRequest, CodeInvalidQuery, err.Error(),
+			FieldError{Field: "order_by", Code: "oneof", Message: msg}))
+		return
+	}
+	filter, err := db.ParseFilter(c.Request.URL.Query(), listParams...)
+	if err != nil {
+		AbortWithProblem(c, NewProblem(http.StatusBadRequest, CodeInvalidQuery, config.InvalidDataErrMsg,
+			FieldError{Field: "filter", Code: "invalid", Message: err.Error()}))
+		return
+	}
+	if list.IncludeDeleted && !ValidateAdmin(c) {
+		return
+	}
+	filter.IncludeDeleted = list.IncludeDeleted
+	fields, ok := ValidateFields(c)
+	if !ok {
+		return
+	}
+	if list.Pagination == model.PaginationCursor || list.Cursor != "" {
+		// the order by columns are needed for the cursors
+		extra := make([]string, len(order))
+		for i, sf := range order {
+			extra[i] = sf.Column
+		}
+		p := &db.PageList{Columns: withColumns(fields, append(extra, "updated_at")...), Filter: filter, OrderBy: order}
+		s.listBooksByCursor(c, list, p, fields)
 		return
 	}
 
 	p := &db.PageList{
-		OrderBy: list.OrderBy,
+		Columns: withColumns(fields, "updated_at"),
+		Filter:  filter,
+		OrderBy: order,
 		Limit:   list.PageSize,
 		OffSet:  (list.PageID - 1) * list.PageSize,
 	}
-	bks, err := s.db.ListBooks(p)
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	bks, err := s.db.ListBooks(ctx, p)
+	if err != nil {
+		HandleDBError(c, "listBooksRequest", err)
+		return
+	}
+	total, err := s.db.CountBooks(ctx, p.Filter)
+	if err != nil {
+		HandleDBError(c, "listBooksRequest", err)
+		return
+	}
+
+	resp := &ListBooksResponse{Books: shapeBooks(bks, fields), Total: total, Page: list.PageID, PageSize: list.PageSize}
+	resp.setLinks(c)
+	if list.Meta == model.MetaBody {
+		renderCached(c, resp, "", lastModified(bks))
+		return
+	}
+	resp.SetHeaders(c)
+	renderCached(c, resp.Books, "", lastModified(bks))
+}
+
+// listParams are the query parameters of the list request that are not book filters
+var listParams = []string{"order_by", "page_id", "page_size", "pagination", "cursor", "meta", "fields", "include_deleted"}
+
+// listBooksByCursor will list the page of books that start from the cursor, or the first page if there is no cursor
+func (s *Server) listBooksByCursor(c *gin.Context, list *model.ListBookRequest, p *db.PageList, fields []string) {
+	// one more book is selected to know if there is another page
+	p.Limit = list.PageSize + 1
+	if list.Cursor != "" {
+		k, err := decodeCursor(list.Cursor, p.OrderBy)
+		if err != nil {
+			AbortWithProblem(c, NewProblem(http.StatusBadRequest, CodeInvalidQuery, config.InvalidDataErrMsg,
+				FieldError{Field: "cursor", Code: "invalid", Message: err.Error()}))
+			return
+		}
+		p.Keyset = k
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	bks, err := s.db.ListBooks(ctx, p)
+	if err != nil {
+		HandleDBError(c, "listBooksByCursor", err)
+		return
+	}
+	total, err := s.db.CountBooks(ctx, p.Filter)
+	if err != nil {
+		HandleDBError(c, "listBooksByCursor", err)
+		return
+	}
+
+	resp := cursorPage(bks, p, list.PageSize, fields)
+	resp.Total = total
+	resp.setCursorLinks(c)
+	if list.Meta == model.MetaHeaders {
+		resp.SetHeaders(c)
+	}
+	renderCached(c, resp, "", lastModified(bks))
+}
+
+// fullTextSearchRequest godoc
+//
+//	@Summary		Full-Text Search Books
+//	@Description	For full-text searching books by title, author, publisher and isbn, ranked by relevance.
+//	@Description	Every word of q has to match, "double quoted" words are matched as a phrase and a trailing * match the words that start with it.
+//	@Description	Boost will weight the fields of the rank like title:5,author:2, the default is title:3,author:2,publisher:1,isbn:1.
+//	@Description	Every result has the rank, higher is more relevant, and a snippet of the matched text where the matches are wrapped with <mark></mark>.
+//	@Description	The rest of the snippet is HTML escaped.
+//	@Tags			books
+//	@Produce		json
+//	@Param			q			query	string	true	"Search text"
+//	@Param			boost		query	string	false	"Field boosts, field:weight comma separated"
+//	@Param			fields		query	string	false	"Comma separated book fields to return like book_id,title,author_surname, all fields by default"
+//	@Param			page_id		query	int		false	"Page number"		default(1)	minimum(1)
+//	@Param			page_size	query	int		false	"Results per page"	default(25)	minimum(5)	maximum(1000)
+//	@Param			include_deleted	query	bool	false	"Search the deleted books too, admin only"	default(false)
+//	@Param			Authorization	header	string	false	"Bearer admin token, required with include_deleted"
+//	@Success		200	{array}		db.SearchResult
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/books/search [get]
+func (s *Server) fullTextSearchRequest(c *gin.Context) {
+	var req *model.FullTextSearchRequest
+	if err := c.ShouldBindQuery(&req); !ValidateBinding(c, err, req, http.StatusBadRequest) {
+		return
+	}
+	terms, err := db.ParseSearchQuery(req.Q)
+	if err != nil {
+		AbortWithProblem(c, NewProblem(http.StatusBadRequest, CodeInvalidQuery, config.InvalidDataErrMsg,
+			FieldError{Field: "q", Code: "invalid", Message: err.Error()}))
+		return
+	}
+	boosts, err := db.ParseSearchBoosts(req.Boost)
 	if err != nil {
-		log.Error().Msgf("allBooksRequest failed: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
+		AbortWithProblem(c, NewProblem(http.StatusBadRequest, CodeInvalidQuery, config.InvalidDataErrMsg,
+			FieldError{Field: "boost", Code: "invalid", Message: err.Error()}))
+		return
+	}
+	if req.IncludeDeleted && !ValidateAdmin(c) {
+		return
+	}
+	fields, ok := ValidateFields(c)
+	if !ok {
+		return
+	}
+
+	q := &db.SearchQuery{
+		Columns:        withColumns(fields),
+		Terms:          terms,
+		Boosts:         boosts,
+		Limit:          req.PageSize,
+		OffSet:         (req.PageID - 1) * req.PageSize,
+		IncludeDeleted: req.IncludeDeleted,
+	}
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	results, err := s.db.SearchBooks(ctx, q)
+	if err != nil {
+		HandleDBError(c, "fullTextSearchRequest", err)
 	} else {
-		c.JSON(http.StatusOK, bks)
+		c.JSON(http.StatusOK, shapeSearchResults(results, fields))
 	}
 }
 
@@ -106,40 +380,41 @@ func (s *Server) listBooksRequest(c *gin.Context) {
 //	@Accept			json
 //	@Produce		json
 //	@Param			body	body	model.Book	false	"Fields Required: At least one. Empty fields will be ignored"
+//	@Param			fields		query	string	false	"Comma separated book fields to return like book_id,title,author_surname, all fields by default"
 //	@Success		200
-//	@Failure		415
-//	@Failure		500
+//	@Failure		400	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
 //	@Router			/books/search [post]
 func (s *Server) searchBooksRequest(c *gin.Context) {
 	if !ValidateContentType(c) {
 		return
 	}
 
+	fields, ok := ValidateFields(c)
+	if !ok {
+		return
+	}
 	var bk *model.Book
-	if err := c.ShouldBindJSON(&bk); err != nil {
-		log.Error().Msgf("%s: %s", config.InvalidDataErrMsg, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
+	if err := c.ShouldBindJSON(&bk); !ValidateBinding(c, err, bk, http.StatusUnprocessableEntity) {
 		return
 	}
 
-	v := reflect.Indirect(reflect.ValueOf(bk))
-	t := v.Type()
-	var str []string
-	for i := 0; i < v.NumField(); i++ {
-		if v.Field(i).Interface() != "" && !(t.Field(i).Type.String() == "int" && v.Field(i).Interface() == 0) {
-			str = append(str, fmt.Sprintf("%s LIKE '%%%v%%'", t.Field(i).Tag.Get("db"), v.Field(i).Interface()))
-		}
-	}
-	if !WarnEmptyData(c, str) {
+	normalizeFilterISBN(&bk.ISBN)
+	f := &db.BookFilter{Book: *bk, Mode: db.MatchAny, Columns: withColumns(fields)}
+	if cols, _ := f.Fields(); !WarnEmptyData(c, cols) {
 		return
 	}
 
-	bks, err := s.db.GetBooks(strings.Join(str, " OR "))
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	bks, err := s.db.GetBooks(ctx, f)
 	if err != nil {
-		log.Error().Msgf("searchBooksRequest failed: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
+		HandleDBError(c, "searchBooksRequest", err)
 	} else {
-		c.JSON(http.StatusOK, bks)
+		c.JSON(http.StatusOK, shapeBooks(bks, fields))
 	}
 }
 
@@ -151,136 +426,277 @@ func (s *Server) searchBooksRequest(c *gin.Context) {
 //	@Accept			json
 //	@Produce		json
 //	@Param			body	body	model.Book	false	"Fields Required: At least one. Empty fields will be ignored"
+//	@Param			fields		query	string	false	"Comma separated book fields to return like book_id,title,author_surname, all fields by default"
 //	@Success		200
-//	@Failure		415
-//	@Failure		500
+//	@Failure		400	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
 //	@Router			/books/get [post]
 func (s *Server) getBooksRequest(c *gin.Context) {
 	if !ValidateContentType(c) {
 		return
 	}
 
+	fields, ok := ValidateFields(c)
+	if !ok {
+		return
+	}
 	var bk *model.Book
-	if err := c.ShouldBindJSON(&bk); err != nil {
-		log.Error().Msgf("%s: %s", config.InvalidDataErrMsg, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
+	if err := c.ShouldBindJSON(&bk); !ValidateBinding(c, err, bk, http.StatusUnprocessableEntity) {
 		return
 	}
 
-	v := reflect.Indirect(reflect.ValueOf(bk))
-	t := v.Type()
-	var str []string
-	for i := 0; i < v.NumField(); i++ {
-		if v.Field(i).Interface() != "" && !(t.Field(i).Type.String() == "int" && v.Field(i).Interface() == 0) {
-			str = append(str, fmt.Sprintf("%s = '%v'", t.Field(i).Tag.Get("db"), v.Field(i).Interface()))
-		}
+	normalizeFilterISBN(&bk.ISBN)
+	f := &db.BookFilter{Book: *bk, Mode: db.MatchAll, Columns: withColumns(fields)}
+	if cols, _ := f.Fields(); !WarnEmptyData(c, cols) {
+		return
 	}
 
-	if !WarnEmptyData(c, str) {
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	bks, err := s.db.GetBooks(ctx, f)
+	if err != nil {
+		HandleDBError(c, "getBooksRequest", err)
+	} else {
+		c.JSON(http.StatusOK, shapeBooks(bks, fields))
+	}
+}
+
+// getBookRequest godoc
+//
+//	@Summary		Get Book by book_id
+//	@Description	For getting a single book by book_id.
+//	@Description	Will return 304 without the book when If-None-Match has the ETag of the book version, or without If-None-Match when it is not modified since If-Modified-Since.
+//	@Tags			books
+//	@Produce		json
+//	@Param			id		path		int		true	"The book_id to get."
+//	@Param			fields	query	string	false	"Comma separated book fields to return like book_id,title,author_surname, all fields by default"
+//	@Param			include_deleted	query	bool	false	"Return the book when it is deleted too, admin only"	default(false)
+//	@Param			Authorization	header	string	false	"Bearer admin token, required with include_deleted"
+//	@Param			If-None-Match		header	string	false	"ETag of the cached book"
+//	@Param			If-Modified-Since	header	string	false	"Last-Modified of the cached book"
+//	@Success		200	{object}	model.Book
+//	@Success		304
+//	@Header			200,304	{string}	ETag			"Book version and the fields of a sparse fieldset, to send with If-Match on update"
+//	@Header			200,304	{string}	Last-Modified	"updated_at of the book"
+//	@Header			200,304	{string}	Cache-Control	"Configured cache policy"
+//	@Header			200,304	{string}	Vary			"Authorization, the deleted books are only returned to the administrators"
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/books/{id} [get]
+func (s *Server) getBookRequest(c *gin.Context) {
+	id, ok := ValidateBookID(c)
+	if !ok {
+		return
+	}
+	var req model.GetBookRequest
+	if err := c.ShouldBindQuery(&req); !ValidateBinding(c, err, &req, http.StatusBadRequest) {
+		return
+	}
+	if req.IncludeDeleted && !ValidateAdmin(c) {
+		return
+	}
+	fields, ok := ValidateFields(c)
+	if !ok {
 		return
 	}
 
-	bks, err := s.db.GetBooks(strings.Join(str, " AND "))
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	cols := withColumns(fields, "version", "updated_at")
+	var bk model.Book
+	var err error
+	if req.IncludeDeleted {
+		bk, err = s.getBookWithDeleted(ctx, id, cols)
+	} else {
+		bk, err = s.db.GetBook(ctx, id, cols...)
+	}
 	if err != nil {
-		log.Error().Msgf("getBooksRequest failed: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
+		HandleDBError(c, "getBookRequest", err)
 	} else {
-		c.JSON(http.StatusOK, bks)
+		renderCached(c, shapeBook(bk, fields), bookETag(bk.Version, fields...), bk.UpdatedAt)
 	}
 }
 
+// bookLocation will return the path of the single book resource
+func bookLocation(id int) string {
+	return fmt.Sprintf("/v1/books/%d", id)
+}
+
 // insertBooksRequest godoc
 //
 //	@Summary		Insert Books
-//	@Description	For inserting single/multiple books.
-//	@Description	Will return number of rows that are inserted, if there is no row updated, will return no data update with 0 row affected.
+//	@Description	For inserting single/multiple books in one transaction.
+//	@Description	With mode all_or_nothing (default) none of the books are inserted if any of them fail, the 409/422 problem will list the outcome of every book in results.
+//	@Description	With mode best_effort every valid book is inserted, will return 207 with the outcome of every book if some of them failed.
+//	@Description	Will return 201 with number of rows that are inserted, the new book_ids and the outcome of every book, if there is no book passing through, will return no data update with 0 row affected.
+//	@Description	When a single book is inserted the Location header will point to the new book.
 //	@Tags			books
 //	@Accept			json
 //	@Produce		json
-//	@Param			body	body	[]model.Book	true	"Fields Required: ALL except book_id. Fields cannot be empty. Unique fields: isbn. If book_id is included it will be ignored."
+//	@Param			mode	query	string			false	"Insert mode"	Enums(all_or_nothing, best_effort)	default(all_or_nothing)
+//	@Param			body	body	[]model.Book	true	"Fields Required: ALL except book_id and publisher_id. Fields cannot be empty. Unique fields: isbn, a valid ISBN-10 or ISBN-13 that is stored as ISBN-13. published: a year, year and month or full date that is stored like 1949, 1949-06 or 1949-06-08. If book_id is included it will be ignored. publisher: the name or an alias of a publisher that is created when there is none, or publisher_id of an existing publisher that win over the name."
 //	@Success		200
-//	@Failure		415
-//	@Failure		500
+//	@Success		201	{object}	InsertBooksResponse
+//	@Success		207	{object}	InsertBooksResponse
+//	@Header			201	{string}	Location	"/v1/books/{id} of the inserted book, single book only"
+//	@Failure		400	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
 //	@Router			/books [post]
 func (s *Server) insertBooksRequest(c *gin.Context) {
 	if !ValidateContentType(c) {
 		return
 	}
-	var bks []*model.Book
-	if err := c.ShouldBindJSON(&bks); err != nil {
-		log.Error().Msgf("%s: %s", config.InvalidDataErrMsg, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
+	var req *model.InsertBookRequest
+	if err := c.ShouldBindQuery(&req); !ValidateBinding(c, err, req, http.StatusBadRequest) {
+		return
+	}
+	var bks []model.Book
+	if err := c.ShouldBindJSON(&bks); !ValidateBinding(c, err, bks, http.StatusUnprocessableEntity) {
+		return
+	}
+	if len(bks) == 0 {
+		ValidateRowsAffected(c, 0, config.AddSuccessMsg)
 		return
 	}
 
-	var str []string
+	mode := db.AllOrNothing
+	if req.Mode == model.InsertBestEffort {
+		mode = db.BestEffort
+	}
+	results := make([]InsertBookResult, len(bks))
+	var valid []model.Book
+	var validIndex []int
+	var empty []string
+	var invalid []FieldError
+	var invalidProblem *Problem
 	for i := 0; i < len(bks); i++ {
-		v := reflect.Indirect(reflect.ValueOf(bks[i]))
+		results[i] = InsertBookResult{Index: i}
+		v := reflect.ValueOf(bks[i])
 		t := v.Type()
+		var fields []string
 		for j := 0; j < v.NumField(); j++ {
 			if t.Field(j).Type.String() == "string" && v.Field(j).Interface() == "" {
-				log.Error().Msgf("%s %s", t.Field(j).Tag.Get("json"),
-					errors.New(config.DataCouldNotBeEmptyErrMsg))
-				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s %s",
-					t.Field(j).Tag.Get("json"), config.DataCouldNotBeEmptyErrMsg)})
-				return
+				fields = append(fields, t.Field(j).Tag.Get("json"))
+				empty = append(empty, fmt.Sprintf("[%d].%s", i, t.Field(j).Tag.Get("json")))
+			}
+		}
+		if p := RequiredFieldsProblem(fields); p != nil {
+			results[i].fail(p.Code, p.Detail, p.Errors...)
+			continue
+		}
+		if p := normalizeBookFields(&bks[i].ISBN, &bks[i].Published); p != nil {
+			results[i].fail(p.Code, p.Detail, p.Errors...)
+			if invalidProblem == nil {
+				invalidProblem = p
+			}
+			for _, fe := range p.Errors {
+				fe.Field = fmt.Sprintf("[%d].%s", i, fe.Field)
+				invalid = append(invalid, fe)
 			}
+			continue
 		}
-		str = append(str, fmt.Sprintf("('%v', '%v', '%v', '%v', '%v', '%v')",
-			bks[i].ISBN, bks[i].Title, bks[i].AuthorName, bks[i].AuthorSurname, bks[i].Published, bks[i].Publisher))
+		valid = append(valid, bks[i])
+		validIndex = append(validIndex, i)
+	}
+	if p := RequiredFieldsProblem(empty); p != nil && mode == db.AllOrNothing {
+		log.Error().Msgf("%s %s", strings.Join(empty, ", "), config.DataCouldNotBeEmptyErrMsg)
+		p.Errors = append(p.Errors, invalid...)
+		AbortWithProblem(c, p)
+		return
+	}
+	if len(invalid) > 0 && mode == db.AllOrNothing {
+		log.Error().Msgf("%s %d fields", invalidProblem.Detail, len(invalid))
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, invalidProblem.Code, invalidProblem.Detail, invalid...))
+		return
 	}
 
-	rowsAffected, err := s.db.InsertBooks(strings.Join(str, ","))
-	if err != nil {
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	inserted, err := s.db.InsertBooks(ctx, valid, mode)
+	if err != nil && !errors.Is(err, db.ErrBatchRolledBack) {
+		HandleDBError(c, "insertBooksRequest", err)
+		return
+	}
+	for i, r := range inserted {
+		results[validIndex[i]].setOutcome(r)
+	}
+
+	if errors.Is(err, db.ErrBatchRolledBack) {
 		log.Error().Msgf("insertBooksRequest failed: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
-	} else {
-		ValidateRowsAffected(c, rowsAffected, config.AddSuccessMsg)
+		status := http.StatusInternalServerError
+		for _, r := range results {
+			if r.Error != nil && (r.Error.Code == CodeDuplicateISBN || r.Error.Code == CodeDuplicatePublisher) {
+				status = http.StatusConflict
+			} else if r.Error != nil && r.Error.Code == CodeValidationFailed && status != http.StatusConflict {
+				status = http.StatusUnprocessableEntity
+			}
+		}
+		p := NewProblem(status, CodeBatchRolledBack, config.BatchRolledBackErrMsg)
+		p.Results = results
+		AbortWithProblem(c, p)
+		return
 	}
+	ValidateInsertResults(c, results)
 }
 
 // updateBooksRequest godoc
 //
 //	@Summary		Update Book by book_id
 //	@Description	For updating a book by book_id.
-//	@Description	Will return number of row that is updated, if there is no row updated, will return no data update with 0 row affected.
+//	@Description	With If-Match the book is only updated when it still has the version of the ETag, otherwise will return 412.
+//	@Description	Without If-Match the version of the body is used the same way, the header can be required by the server with 428.
+//	@Description	Will return number of row that is updated and the ETag of the new version, if there is no row updated, will return no data update with 0 row affected.
 //	@Tags			books
 //	@Accept			json
 //	@Produce		json
-//	@Param			body	body	model.Book	true	"Fields Required: ALL. Fields cannot be empty. Unique fields: isbn."
+//	@Param			If-Match	header	string		false	"ETag of the book version that is updated"
+//	@Param			body		body	model.Book	true	"Fields Required: ALL except publisher_id, version and updated_at. Fields cannot be empty. Unique fields: isbn, a valid ISBN-10 or ISBN-13 that is stored as ISBN-13. published: a year, year and month or full date that is stored like 1949, 1949-06 or 1949-06-08. publisher: the name or an alias of a publisher that is created when there is none, or publisher_id of an existing publisher that win over the name."
 //	@Success		200
-//	@Failure		415
-//	@Failure		500
+//	@Header			200	{string}	ETag	"New book version"
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
 //	@Router			/books [put]
 func (s *Server) updateBooksRequest(c *gin.Context) {
 	if !ValidateContentType(c) {
 		return
 	}
 	var bk *model.Book
-	if err := c.ShouldBindJSON(&bk); err != nil {
-		log.Error().Msgf("%s: %s", config.InvalidDataErrMsg, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
+	if err := c.ShouldBindJSON(&bk); !ValidateBinding(c, err, bk, http.StatusUnprocessableEntity) {
 		return
 	}
 
-	v := reflect.Indirect(reflect.ValueOf(bk))
-	t := v.Type()
-	for j := 0; j < v.NumField(); j++ {
-		if (t.Field(j).Type.String() == "string" && v.Field(j).Interface() == "") ||
-			(t.Field(j).Type.String() == "int" && v.Field(j).Interface() == 0) {
-			log.Error().Msgf("%s %s", t.Field(j).Tag.Get("json"),
-				errors.New(config.DataCouldNotBeEmptyErrMsg))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s %s",
-				t.Field(j).Tag.Get("json"), config.DataCouldNotBeEmptyErrMsg)})
-			return
-		}
+	if !ValidateRequiredFields(c, bk, true) || !ValidateBookFields(c, &bk.ISBN, &bk.Published) {
+		return
 	}
 
-	rowsAffected, err := s.db.UpdateBooks(bk)
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	var ok bool
+	if bk.Version, ok = s.ifMatchVersion(ctx, c, bk.ID, bk.Version); !ok {
+		return
+	}
+	rowsAffected, err := s.db.UpdateBooks(ctx, bk)
 	if err != nil {
-		log.Error().Msgf("updateBooksRequest failed: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
-	} else {
+		HandleDBError(c, "updateBooksRequest", err)
+	} else if ValidateBookFound(c, rowsAffected) {
+		s.setUpdatedETag(ctx, c, bk.ID)
 		ValidateRowsAffected(c, rowsAffected, config.UpdateSuccessMsg)
 	}
 }
@@ -289,50 +705,75 @@ func (s *Server) updateBooksRequest(c *gin.Context) {
 //
 //	@Summary		Update Book by book_id
 //	@Description	For updating a book by book_id.
-//	@Description	Will return number of row that is updated, if there is no row updated, will return no data update with 0 row affected.
+//	@Description	With If-Match the book is only updated when it still has the version of the ETag, otherwise will return 412.
+//	@Description	Without If-Match the version of the body is used the same way, the header can be required by the server with 428.
+//	@Description	Will return number of row that is updated and the ETag of the new version, if there is no row updated, will return no data update with 0 row affected.
 //	@Tags			books
 //	@Accept			json
 //	@Produce		json
-//	@Param			body	body	model.Book	true	"Fields Required: book_id. Empty fields will be ignored. Unique fields: isbn."
+//	@Param			If-Match	header	string			false	"ETag of the book version that is updated"
+//	@Param			body		body	model.PatchBook	true	"Fields Required: book_id. Empty fields will be ignored. Unique fields: isbn, a valid ISBN-10 or ISBN-13 that is stored as ISBN-13. published: a year, year and month or full date that is stored like 1949, 1949-06 or 1949-06-08. publisher_id: an existing publisher that the book get the name of."
 //	@Success		200
-//	@Failure		415
-//	@Failure		500
+//	@Header			200	{string}	ETag	"New book version"
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
 //	@Router			/books [patch]
 func (s *Server) patchBooksRequest(c *gin.Context) {
 	if !ValidateContentType(c) {
 		return
 	}
 	var bk *model.PatchBook
-	if err := c.ShouldBindJSON(&bk); err != nil {
-		log.Error().Msgf("%s: %s", config.InvalidDataErrMsg, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
+	if err := c.ShouldBindJSON(&bk); !ValidateBinding(c, err, bk, http.StatusUnprocessableEntity) {
 		return
 	}
 
 	v := reflect.Indirect(reflect.ValueOf(bk))
 	t := v.Type()
-	var str []string
 	var emptyFields []string
+	stringFields := 0
 	for i := 0; i < v.NumField(); i++ {
-		if t.Field(i).Type.String() == "int" && v.Field(i).Interface() == 0 {
-			log.Error().Msgf("%s %s", t.Field(i).Tag.Get("json"),
-				errors.New(config.DataCouldNotBeEmptyErrMsg))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s %s",
-				t.Field(i).Tag.Get("json"), config.DataCouldNotBeEmptyErrMsg)})
-			return
-		} else if t.Field(i).Type.String() == "string" && v.Field(i).Interface() == "" {
+		if t.Field(i).Type.String() != "string" {
+			continue
+		}
+		stringFields++
+		if v.Field(i).Interface() == "" {
 			emptyFields = append(emptyFields, t.Field(i).Tag.Get("json"))
-		} else {
-			str = append(str, fmt.Sprintf("%s = '%v'", t.Field(i).Tag.Get("db"), v.Field(i).Interface()))
 		}
 	}
+	if len(emptyFields) == stringFields && bk.PublisherID == 0 {
+		log.Error().Msg(config.NoFieldsToUpdateErrMsg)
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, config.NoFieldsToUpdateErrMsg))
+		return
+	}
+	var isbnValue, published *string
+	if bk.ISBN != "" {
+		isbnValue = &bk.ISBN
+	}
+	if bk.Published != "" {
+		published = &bk.Published
+	}
+	if !ValidateBookFields(c, isbnValue, published) {
+		return
+	}
 
-	rowsAffected, err := s.db.PatchBooks(fmt.Sprintf("%s WHERE book_id = %v",
-		strings.Join(str, ","), bk.ID))
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	var ok bool
+	if bk.Version, ok = s.ifMatchVersion(ctx, c, bk.ID, bk.Version); !ok {
+		return
+	}
+	rowsAffected, err := s.db.PatchBooks(ctx, bk)
 	if err != nil {
-		log.Error().Msgf("patchBooksRequest failed: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
-	} else {
+		HandleDBError(c, "patchBooksRequest", err)
+	} else if ValidateBookFound(c, rowsAffected) {
+		s.setUpdatedETag(ctx, c, bk.ID)
 		if msg := WarnFieldsCannotBeEmpty(emptyFields); msg != "" {
 			c.JSON(http.StatusOK, gin.H{"message": config.UpdateSuccessMsg, "rows_affected": rowsAffected,
 				"warning": msg})
@@ -342,24 +783,363 @@ func (s *Server) patchBooksRequest(c *gin.Context) {
 	}
 }
 
+// patchBookRequest godoc
+//
+//	@Summary		Patch Book by book_id
+//	@Description	For patching a book by book_id with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) document.
+//	@Description	A field that is set to null or removed by the patch is cleared, isbn and title could not be cleared.
+//	@Description	book_id, version and updated_at are read-only, a JSON Patch test operation can check them.
+//	@Description	With If-Match the book is only patched when it still has the version of the ETag, otherwise will return 412.
+//	@Description	Will return the patched book with the ETag of its new version.
+//	@Tags			books
+//	@Accept			application/merge-patch+json
+//	@Accept			application/json-patch+json
+//	@Produce		json
+//	@Param			id			path	int		true	"The book_id to patch."
+//	@Param			If-Match	header	string	false	"ETag of the book version that is patched"
+//	@Param			body		body	object	true	"Merge patch object or JSON Patch operations array"
+//	@Success		200	{object}	model.Book
+//	@Header			200	{string}	ETag	"New book version"
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/books/{id} [patch]
+func (s *Server) patchBookRequest(c *gin.Context) {
+	c.Header("Accept-Patch", jsonpatch.MergePatchType+", "+jsonpatch.JSONPatchType)
+	id, ok := ValidateBookID(c)
+	if !ok {
+		return
+	}
+	if !ValidateContentType(c, jsonpatch.MergePatchType, jsonpatch.JSONPatchType) {
+		return
+	}
+	patch, err := c.GetRawData()
+	if err != nil || len(bytes.TrimSpace(patch)) == 0 {
+		AbortWithProblem(c, NewProblem(http.StatusBadRequest, CodeInvalidJSON, "request body is empty"))
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	bk, err := s.db.GetBook(ctx, id)
+	if err != nil {
+		HandleDBError(c, "patchBookRequest", err)
+		return
+	}
+	version, ok := s.ifMatchVersion(ctx, c, id, 0)
+	if !ok {
+		return
+	}
+	if version != 0 && version != bk.Version {
+		HandleDBError(c, "patchBookRequest", db.ErrVersionConflict)
+		return
+	}
+	patched, p := applyBookPatch(bk, c.ContentType(), patch)
+	if p != nil {
+		log.Error().Msgf("patchBookRequest failed: %s", p.Error())
+		AbortWithProblem(c, p)
+		return
+	}
+
+	// the patch is written only if nobody changed the book since it was read
+	patched.ID, patched.Version = id, bk.Version
+	rowsAffected, err := s.db.ApplyBookPatch(ctx, &patched)
+	if err != nil {
+		HandleDBError(c, "patchBookRequest", err)
+		return
+	}
+	if !ValidateBookFound(c, rowsAffected) {
+		return
+	}
+	if bk, err = s.db.GetBook(ctx, id); err != nil {
+		HandleDBError(c, "patchBookRequest", err)
+		return
+	}
+	setBookETag(c, bk.Version)
+	c.JSON(http.StatusOK, bk)
+}
+
 // deleteBooksRequest godoc
 //
 //	@Summary		Delete Book
 //	@Description	For deleting book by id.
+//	@Description	The book is only marked as deleted, it could be restored until it is purged.
 //	@Description	Header is required for content-type.
 //	@Description	Will return number of row that is deleted, if there is no row deleted, will return no data update with 0 row affected.
 //	@Tags			books
 //	@Produce		json
-//	@Param			id	path	string	true	"The book_id to be deleted."
+//	@Param			id	path	int		true	"The book_id to be deleted."
 //	@Success		200
-//	@Failure		500
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
 //	@Router			/books/{id} [delete]
 func (s *Server) deleteBooksRequest(c *gin.Context) {
-	rowsAffected, err := s.db.DeleteBooks(c.Param("id"))
+	id, ok := ValidateBookID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	rowsAffected, err := s.db.DeleteBooks(ctx, id)
 	if err != nil {
-		log.Error().Msgf("deleteBooksRequest failed: %s", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": config.DBOperationErrMsg})
-	} else {
+		HandleDBError(c, "deleteBooksRequest", err)
+	} else if ValidateBookFound(c, rowsAffected) {
 		ValidateRowsAffected(c, rowsAffected, config.DeleteSuccessMsg)
 	}
 }
+
+// getBookWithDeleted will return the book with the book_id when it is deleted too, or ErrBookNotFound if there is none
+func (s *Server) getBookWithDeleted(ctx context.Context, id int, columns []string) (model.Book, error) {
+	bks, err := s.db.GetBooks(ctx, &db.BookFilter{Book: model.Book{ID: id}, Mode: db.MatchAll,
+		Columns: columns, IncludeDeleted: true})
+	if err != nil {
+		return model.Book{}, err
+	}
+	if len(bks) == 0 {
+		return model.Book{}, db.ErrBookNotFound
+	}
+	return bks[0], nil
+}
+
+// restoreBookRequest godoc
+//
+//	@Summary		Restore Book
+//	@Description	For restoring a deleted book by id, it is listed again with the same book_id.
+//	@Description	Will return the restored book with the ETag of its new version, or 404 if there is no deleted book with the id.
+//	@Tags			books
+//	@Produce		json
+//	@Param			id	path	int		true	"The book_id to be restored."
+//	@Success		200	{object}	model.Book
+//	@Header			200	{string}	ETag	"New book version"
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/books/{id}/restore [post]
+func (s *Server) restoreBookRequest(c *gin.Context) {
+	id, ok := ValidateBookID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	rowsAffected, err := s.db.RestoreBooks(ctx, id)
+	if err != nil {
+		HandleDBError(c, "restoreBookRequest", err)
+		return
+	}
+	if rowsAffected == 0 {
+		AbortWithProblem(c, NewProblem(http.StatusNotFound, CodeNotFound, config.DeletedBookNotFoundErrMsg))
+		return
+	}
+	bk, err := s.db.GetBook(ctx, id)
+	if err != nil {
+		HandleDBError(c, "restoreBookRequest", err)
+		return
+	}
+	setBookETag(c, bk.Version)
+	c.JSON(http.StatusOK, bk)
+}
+
+// purgeBooksRequest godoc
+//
+//	@Summary		Purge Deleted Books
+//	@Description	For removing the books that are deleted longer than older_than ago for good, they could not be restored anymore.
+//	@Description	Will return number of row that is purged.
+//	@Tags			books
+//	@Produce		json
+//	@Param			older_than	query	string	false	"Retention of the deleted books like 720h, the configured retention by default"
+//	@Param			Authorization	header	string	true	"Bearer admin token"
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/books/purge [post]
+func (s *Server) purgeBooksRequest(c *gin.Context) {
+	if !ValidateAdmin(c) {
+		return
+	}
+	var req model.PurgeBookRequest
+	if err := c.ShouldBindQuery(&req); !ValidateBinding(c, err, &req, http.StatusBadRequest) {
+		return
+	}
+	retention := config.PurgeRetention
+	if req.OlderThan != "" {
+		d, err := time.ParseDuration(req.OlderThan)
+		if err != nil || d < 0 {
+			AbortWithProblem(c, NewProblem(http.StatusBadRequest, CodeInvalidQuery, config.InvalidRetentionErrMsg,
+				FieldError{Field: "older_than", Code: "invalid", Message: config.InvalidRetentionErrMsg}))
+			return
+		}
+		retention = d
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	rowsAffected, err := s.db.PurgeBooks(ctx, retention)
+	if err != nil {
+		HandleDBError(c, "purgeBooksRequest", err)
+	} else {
+		ValidateRowsAffected(c, rowsAffected, config.PurgeSuccessMsg)
+	}
+}
+
+// bookHistoryRequest godoc
+//
+//	@Summary		Book History
+//	@Description	For listing every change of a book by id with the book before and after it, the latest change first.
+//	@Description	The operation is insert, update, patch, delete, restore, purge, revert, authors when the book follow its first author or publisher when it follow its publisher, and the actor is the X-Actor header of the change, or the client ip. The X-Actor header is not authenticated.
+//	@Description	The history is kept when the book is deleted or purged, will return 404 if there is no such book and no history.
+//	@Tags			books
+//	@Produce		json
+//	@Param			id			path	int	true	"The book_id of the history."
+//	@Param			page_id		query	int	false	"Page of the history, default 1."
+//	@Param			page_size	query	int	false	"Number of changes in a page, default 25."
+//	@Success		200	{array}		model.BookHistory
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/books/{id}/history [get]
+func (s *Server) bookHistoryRequest(c *gin.Context) {
+	id, ok := ValidateBookID(c)
+	if !ok {
+		return
+	}
+	var req model.BookHistoryRequest
+	if err := c.ShouldBindQuery(&req); !ValidateBinding(c, err, &req, http.StatusBadRequest) {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	hs, err := s.db.ListBookHistory(ctx, &db.HistoryQuery{BookID: id, Limit: req.PageSize, OffSet: (req.PageID - 1) * req.PageSize})
+	if err != nil {
+		HandleDBError(c, "bookHistoryRequest", err)
+		return
+	}
+	if len(hs) == 0 && req.PageID == 1 {
+		// a book that is there since before the history was recorded does not have any change yet
+		if _, err = s.getBookWithDeleted(ctx, id, []string{"book_id"}); err != nil {
+			HandleDBError(c, "bookHistoryRequest", err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, hs)
+}
+
+// revertBookRequest godoc
+//
+//	@Summary		Revert Book
+//	@Description	For setting the book fields back to how they were after a change of the book history, the revert is recorded as a new change.
+//	@Description	The change is selected by history_id, or by at to revert the book to the latest change at or before that time.
+//	@Description	Will return the reverted book with the ETag of its new version, 404 if there is no such book or change and 409 if the isbn is used by another book now.
+//	@Description	The isbn and published of the change are validated again like an update, 422 is returned when they are not valid anymore.
+//	@Description	With the If-Match header the book is only reverted when it has that version, or 412 is returned.
+//	@Tags			books
+//	@Produce		json
+//	@Param			id			path	int		true	"The book_id to be reverted."
+//	@Param			history_id	query	int		false	"The history_id of the change to revert the book to."
+//	@Param			at			query	string	false	"RFC 3339 time to revert the book to, used when there is no history_id."
+//	@Param			If-Match	header	string	false	"ETag of the book version that is reverted."
+//	@Success		200	{object}	model.Book
+//	@Header			200	{string}	ETag	"New book version"
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/books/{id}/revert [post]
+func (s *Server) revertBookRequest(c *gin.Context) {
+	id, ok := ValidateBookID(c)
+	if !ok {
+		return
+	}
+	var req model.RevertBookRequest
+	if err := c.ShouldBindQuery(&req); !ValidateBinding(c, err, &req, http.StatusBadRequest) {
+		return
+	}
+	if req.HistoryID == 0 && req.At.IsZero() {
+		log.Error().Msgf("%s: %s", config.InvalidDataErrMsg, config.RevertQueryErrMsg)
+		AbortWithProblem(c, NewProblem(http.StatusBadRequest, CodeInvalidQuery, config.RevertQueryErrMsg,
+			FieldError{Field: "history_id", Code: "required", Message: config.RevertQueryErrMsg}))
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	historyID := req.HistoryID
+	if historyID == 0 {
+		hs, err := s.db.ListBookHistory(ctx, &db.HistoryQuery{BookID: id, Until: req.At, Limit: 1})
+		if err != nil {
+			HandleDBError(c, "revertBookRequest", err)
+			return
+		}
+		if len(hs) == 0 {
+			HandleDBError(c, "revertBookRequest", db.ErrHistoryNotFound)
+			return
+		}
+		historyID = hs[0].HistoryID
+	}
+	version, ok := s.ifMatchVersion(ctx, c, id, 0)
+	if !ok {
+		return
+	}
+	if _, err := s.db.RevertBook(ctx, id, historyID, version); err != nil {
+		HandleDBError(c, "revertBookRequest", err)
+		return
+	}
+	bk, err := s.db.GetBook(ctx, id)
+	if err != nil {
+		HandleDBError(c, "revertBookRequest", err)
+		return
+	}
+	setBookETag(c, bk.Version)
+	c.JSON(http.StatusOK, bk)
+}
+
+// ISBNResponse is the canonical forms of a valid isbn.
+// ISBN10 is left out for the ISBN-13 that start with 979, Hyphenated and Agency when the isbn range is not known.
+type ISBNResponse struct {
+	ISBN13     string `json:"isbn13"`
+	ISBN10     string `json:"isbn10,omitempty"`
+	Hyphenated string `json:"hyphenated,omitempty"`
+	Agency     string `json:"agency,omitempty"`
+}
+
+// isbnRequest godoc
+//
+//	@Summary		Validate ISBN
+//	@Description	For checking an ISBN-10 or ISBN-13 with or without hyphens, the books are stored with the isbn13 of this response.
+//	@Description	Will return the ISBN-13, the ISBN-10 and the hyphenated form with the registration group agency, or 422 if the isbn is not valid.
+//	@Tags			isbn
+//	@Produce		json
+//	@Param			isbn	path	string	true	"The isbn to be checked."
+//	@Success		200	{object}	ISBNResponse
+//	@Failure		422	{object}	Problem
+//	@Router			/isbn/{isbn} [get]
+func (s *Server) isbnRequest(c *gin.Context) {
+	var resp ISBNResponse
+	resp.ISBN13 = c.Param("isbn")
+	if !ValidateBookFields(c, &resp.ISBN13, nil) {
+		return
+	}
+	resp.ISBN10, _ = isbn.To10(resp.ISBN13)
+	resp.Hyphenated, _ = isbn.Hyphenate(resp.ISBN13)
+	resp.Agency, _ = isbn.Agency(resp.ISBN13)
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,541 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"goapp/config"
+	"goapp/pkg/db"
+	"goapp/pkg/db/dbtest"
+	"goapp/pkg/model"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// newTestRouter will return the routes of a server with a memory storage of the dbtest books,
+// the book_id of the books are 1 to 5
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	bks := append([]model.Book(nil), dbtest.Books...)
+	return GetServer(":0", gin.New(), db.NewMemoryStorage(bks...)).SetupRoutes()
+}
+
+// serve will send the request with the body of the content type to the router and return the response
+func serve(r *gin.Engine, method, path, contentType, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decodeBody will decode the json response body into v
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("could not decode the response %s: %s", w.Body.String(), err)
+	}
+}
+
+// assertStatus will check the response status and stop the test when it is not the expected one
+func assertStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status = %d, want %d, body %s", w.Code, want, w.Body.String())
+	}
+}
+
+// assertProblem will check that the response is an application/problem+json problem with the status and code
+func assertProblem(t *testing.T, w *httptest.ResponseRecorder, status int, code string) Problem {
+	t.Helper()
+	assertStatus(t, w, status)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, ProblemContentType) {
+		t.Errorf("Content-Type = %q, want %q", ct, ProblemContentType)
+	}
+	var p Problem
+	decodeBody(t, w, &p)
+	if p.Status != status || p.Code != code || p.Title != http.StatusText(status) || p.Type != "about:blank" {
+		t.Errorf("problem = %+v, want status %d and code %s", p, status, code)
+	}
+	return p
+}
+
+// assertMessage will check the message and rows_affected of the response
+func assertMessage(t *testing.T, w *httptest.ResponseRecorder, msg string, rowsAffected int64) {
+	t.Helper()
+	assertStatus(t, w, http.StatusOK)
+	var got struct {
+		Message      string `json:"message"`
+		RowsAffected int64  `json:"rows_affected"`
+	}
+	decodeBody(t, w, &got)
+	if got.Message != msg || got.RowsAffected != rowsAffected {
+		t.Errorf("response = %+v, want %q with %d rows affected", got, msg, rowsAffected)
+	}
+}
+
+// getTestBook will return the book of the path from the router
+func getTestBook(t *testing.T, r *gin.Engine, path string) model.Book {
+	t.Helper()
+	w := serve(r, http.MethodGet, path, "", "")
+	assertStatus(t, w, http.StatusOK)
+	var bk model.Book
+	decodeBody(t, w, &bk)
+	return bk
+}
+
+// getTestBooks will return the books of the match all filter from the router
+func getTestBooks(t *testing.T, r *gin.Engine, filter string) []model.Book {
+	t.Helper()
+	w := serve(r, http.MethodPost, "/v1/books/get", "application/json", filter)
+	assertStatus(t, w, http.StatusOK)
+	var bks []model.Book
+	decodeBody(t, w, &bks)
+	return bks
+}
+
+func TestListBooksRequest(t *testing.T) {
+	r := newTestRouter()
+
+	w := serve(r, http.MethodGet, "/v1/books", "", "")
+	assertStatus(t, w, http.StatusOK)
+	var bks []model.Book
+	decodeBody(t, w, &bks)
+	if len(bks) != len(dbtest.Books) {
+		t.Fatalf("listed %d books, want %d", len(bks), len(dbtest.Books))
+	}
+	for i, bk := range bks {
+		if bk.ID != i+1 || bk.ISBN != dbtest.Books[i].ISBN || bk.Title != dbtest.Books[i].Title {
+			t.Errorf("book %d = %+v, want %+v", i, bk, dbtest.Books[i])
+		}
+	}
+	if got := w.Header().Get("X-Total-Count"); got != "5" {
+		t.Errorf("X-Total-Count = %q, want 5", got)
+	}
+
+	w = serve(r, http.MethodGet, "/v1/books?page_size=5&order_by=-published", "", "")
+	assertStatus(t, w, http.StatusOK)
+	bks = nil
+	decodeBody(t, w, &bks)
+	want := []int{5, 1, 2, 3, 4}
+	if len(bks) != len(want) {
+		t.Fatalf("listed %d books, want %d", len(bks), len(want))
+	}
+	for i, bk := range bks {
+		if bk.ID != want[i] {
+			t.Errorf("book %d ordered by -published = %d, want %d", i, bk.ID, want[i])
+		}
+	}
+
+	w = serve(r, http.MethodGet, "/v1/books?page_id=2&page_size=5", "", "")
+	assertStatus(t, w, http.StatusOK)
+	if body := strings.TrimSpace(w.Body.String()); body != "[]" {
+		t.Errorf("second page = %s, want []", body)
+	}
+
+	assertProblem(t, serve(r, http.MethodGet, "/v1/books?order_by=price", "", ""), http.StatusBadRequest, CodeInvalidQuery)
+	assertProblem(t, serve(r, http.MethodGet, "/v1/books?page_size=abc", "", ""), http.StatusBadRequest, CodeInvalidQuery)
+	assertProblem(t, serve(r, http.MethodGet, "/v1/nothing", "", ""), http.StatusNotFound, CodeNotFound)
+}
+
+func TestSearchBooksRequest(t *testing.T) {
+	r := newTestRouter()
+
+	w := serve(r, http.MethodPost, "/v1/books/search", "application/json", `{"title":"the","publisher":"harper"}`)
+	assertStatus(t, w, http.StatusOK)
+	var bks []model.Book
+	decodeBody(t, w, &bks)
+	if len(bks) != 3 || bks[0].ID != 3 || bks[1].ID != 4 || bks[2].ID != 5 {
+		t.Errorf("search = %+v, want the books 3, 4 and 5", bks)
+	}
+
+	if bks = getTestBooks(t, r, `{"author_surname":"Orwell","published":"1945"}`); len(bks) != 1 || bks[0].ID != 2 {
+		t.Errorf("get = %+v, want Animal Farm", bks)
+	}
+	if bks = getTestBooks(t, r, `{"author_surname":"orwell"}`); len(bks) != 0 {
+		t.Errorf("get = %+v, want no book for the other case", bks)
+	}
+	if bks = getTestBooks(t, r, `{"isbn":"0-451-52493-4"}`); len(bks) != 1 || bks[0].ID != 1 {
+		t.Errorf("get = %+v, want the book 1 by its ISBN-10", bks)
+	}
+	w = serve(r, http.MethodPost, "/v1/books/search", "application/json", `{"isbn":"0451526341"}`)
+	assertStatus(t, w, http.StatusOK)
+	bks = nil
+	decodeBody(t, w, &bks)
+	if len(bks) != 1 || bks[0].ID != 2 {
+		t.Errorf("search = %+v, want the book 2 by its ISBN-10", bks)
+	}
+	w = serve(r, http.MethodGet, "/v1/books?isbn=978-0-451-52493-5", "", "")
+	assertStatus(t, w, http.StatusOK)
+	bks = nil
+	decodeBody(t, w, &bks)
+	if len(bks) != 1 || bks[0].ID != 1 {
+		t.Errorf("list = %+v, want the book 1 by its hyphenated isbn", bks)
+	}
+
+	assertProblem(t, serve(r, http.MethodPost, "/v1/books/get", "application/json", `{}`),
+		http.StatusUnprocessableEntity, CodeEmptyFilter)
+	assertProblem(t, serve(r, http.MethodPost, "/v1/books/search", "application/json", `{"title":`),
+		http.StatusBadRequest, CodeInvalidJSON)
+	assertProblem(t, serve(r, http.MethodPost, "/v1/books/search", "text/plain", `{"title":"the"}`),
+		http.StatusUnsupportedMediaType, CodeUnsupportedMediaType)
+}
+
+func TestGetBookRequest(t *testing.T) {
+	r := newTestRouter()
+
+	w := serve(r, http.MethodGet, "/v1/books/3", "", "")
+	assertStatus(t, w, http.StatusOK)
+	var bk model.Book
+	decodeBody(t, w, &bk)
+	if bk.ID != 3 || bk.Title != "The Great Gatsby" || bk.Version != 1 || bk.PublisherID != 2 {
+		t.Errorf("book = %+v, want The Great Gatsby with book_id 3, version 1 and publisher_id 2", bk)
+	}
+	if got := w.Header().Get("ETag"); got != `"1"` {
+		t.Errorf("ETag = %q, want %q", got, `"1"`)
+	}
+	w = serve(r, http.MethodGet, "/v1/books/3?fields=title,isbn", "", "")
+	assertStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("ETag"); got != `"1+isbn+title"` {
+		t.Errorf("ETag = %q with fields, want %q", got, `"1+isbn+title"`)
+	}
+
+	p := assertProblem(t, serve(r, http.MethodGet, "/v1/books/99", "", ""), http.StatusNotFound, CodeNotFound)
+	if p.Detail != config.BookNotFoundErrMsg || p.Instance != "/v1/books/99" {
+		t.Errorf("problem = %+v, want %q of /v1/books/99", p, config.BookNotFoundErrMsg)
+	}
+	assertProblem(t, serve(r, http.MethodGet, "/v1/books/abc", "", ""), http.StatusBadRequest, CodeBadRequest)
+}
+
+func TestInsertBooksRequest(t *testing.T) {
+	r := newTestRouter()
+
+	w := serve(r, http.MethodPost, "/v1/books", "application/json", `[{"isbn":"0-306-40615-2","title":"Signals",
+		"author_name":"Ann","author_surname":"Smith","published":"June 1998","publisher":"Harper"}]`)
+	assertStatus(t, w, http.StatusCreated)
+	var res InsertBooksResponse
+	decodeBody(t, w, &res)
+	if res.Message != config.AddSuccessMsg || res.RowsAffected != 1 || len(res.IDs) != 1 || res.IDs[0] != 6 ||
+		len(res.Results) != 1 || res.Results[0].Status != InsertCreated {
+		t.Errorf("response = %+v, want the book_id 6 inserted", res)
+	}
+	if got := w.Header().Get("Location"); got != "/v1/books/6" {
+		t.Errorf("Location = %q, want /v1/books/6", got)
+	}
+	bk := getTestBook(t, r, "/v1/books/6")
+	if bk.ISBN != "9780306406157" || bk.Published != "1998-06" || bk.PublisherID != 4 {
+		t.Errorf("inserted book = %+v, want the ISBN-13, published 1998-06 and the Harper publisher_id 4", bk)
+	}
+
+	w = serve(r, http.MethodPost, "/v1/books", "application/json", `[{"isbn":"9780451524935","title":"1984",
+		"author_name":"George","author_surname":"Orwell","published":"1949","publisher":"Penguin"}]`)
+	p := assertProblem(t, w, http.StatusConflict, CodeBatchRolledBack)
+	if len(p.Results) != 1 || p.Results[0].Error == nil || p.Results[0].Error.Code != CodeDuplicateISBN {
+		t.Errorf("results = %+v, want the %s error", p.Results, CodeDuplicateISBN)
+	}
+
+	w = serve(r, http.MethodPost, "/v1/books?mode=best_effort", "application/json", `[{"isbn":"9780451524935",
+		"title":"1984","author_name":"George","author_surname":"Orwell","published":"1949","publisher":"Penguin"},
+		{"isbn":"9781934356685","title":"Seven Languages","author_name":"Bruce","author_surname":"Tate",
+		"published":"2010","publisher":"Pragmatic Bookshelf"}]`)
+	assertStatus(t, w, http.StatusMultiStatus)
+	res = InsertBooksResponse{}
+	decodeBody(t, w, &res)
+	if res.RowsAffected != 1 || len(res.IDs) != 1 || res.IDs[0] != 7 || len(res.Results) != 2 ||
+		res.Results[0].Status != InsertFailed || res.Results[1].Status != InsertCreated {
+		t.Errorf("response = %+v, want the first book failed and the second inserted", res)
+	}
+
+	p = assertProblem(t, serve(r, http.MethodPost, "/v1/books", "application/json", `[{"isbn":"9780306406157"}]`),
+		http.StatusUnprocessableEntity, CodeValidationFailed)
+	if len(p.Errors) == 0 {
+		t.Errorf("problem = %+v, want the empty fields in errors", p)
+	}
+	assertProblem(t, serve(r, http.MethodPost, "/v1/books", "application/json", `[{"isbn":`),
+		http.StatusBadRequest, CodeInvalidJSON)
+	assertProblem(t, serve(r, http.MethodPost, "/v1/books", "text/plain", `[]`),
+		http.StatusUnsupportedMediaType, CodeUnsupportedMediaType)
+	assertMessage(t, serve(r, http.MethodPost, "/v1/books", "application/json", `[]`), config.NoDataUpdateWarningMsg, 0)
+
+	w = serve(r, http.MethodGet, "/v1/books", "", "")
+	var bks []model.Book
+	decodeBody(t, w, &bks)
+	if len(bks) != 7 {
+		t.Errorf("listed %d books after the failed inserts, want 7", len(bks))
+	}
+}
+
+func TestUpdateBooksRequest(t *testing.T) {
+	r := newTestRouter()
+	book := `{"book_id":%s,"isbn":"%s","title":"1984","author_name":"George","author_surname":"Orwell",` +
+		`"published":"1949-06-08","publisher":"Secker & Warburg"}`
+
+	w := serve(r, http.MethodPut, "/v1/books", "application/json", fmt.Sprintf(book, "1", "9780451524935"))
+	assertMessage(t, w, config.UpdateSuccessMsg, 1)
+	if got := w.Header().Get("ETag"); got != `"2"` {
+		t.Errorf("ETag = %q, want %q", got, `"2"`)
+	}
+	if bk := getTestBook(t, r, "/v1/books/1"); bk.Title != "1984" || bk.Published != "1949-06-08" || bk.Version != 2 {
+		t.Errorf("updated book = %+v, want 1984 published 1949-06-08 with version 2", bk)
+	}
+
+	assertProblem(t, serve(r, http.MethodPut, "/v1/books", "application/json", fmt.Sprintf(book, "99", "9780306406157")),
+		http.StatusNotFound, CodeNotFound)
+	p := assertProblem(t, serve(r, http.MethodPut, "/v1/books", "application/json", fmt.Sprintf(book, "1", "9780451526342")),
+		http.StatusConflict, CodeDuplicateISBN)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "isbn" {
+		t.Errorf("errors = %+v, want the isbn field", p.Errors)
+	}
+	assertProblem(t, serve(r, http.MethodPut, "/v1/books", "application/json", fmt.Sprintf(book, "1", "9780451524935"),
+		"If-Match", `"1"`), http.StatusPreconditionFailed, CodeVersionConflict)
+	assertMessage(t, serve(r, http.MethodPut, "/v1/books", "application/json", fmt.Sprintf(book, "1", "9780451524935"),
+		"If-Match", `"2+isbn+title"`), config.UpdateSuccessMsg, 1)
+	assertProblem(t, serve(r, http.MethodPut, "/v1/books", "application/json", fmt.Sprintf(book, "1", "9780451524936")),
+		http.StatusUnprocessableEntity, CodeInvalidISBN)
+	assertProblem(t, serve(r, http.MethodPut, "/v1/books", "application/json", `{"book_id":1,"title":"1984"}`),
+		http.StatusUnprocessableEntity, CodeValidationFailed)
+}
+
+func TestPatchBooksRequest(t *testing.T) {
+	r := newTestRouter()
+
+	w := serve(r, http.MethodPatch, "/v1/books", "application/json", `{"book_id":2,"title":"Animal Farm: A Fairy Story"}`)
+	assertMessage(t, w, config.UpdateSuccessMsg, 1)
+	var got struct {
+		Warning string `json:"warning"`
+	}
+	decodeBody(t, w, &got)
+	if !strings.HasPrefix(got.Warning, config.FieldsBeEmptyWarningMsg) || !strings.Contains(got.Warning, "isbn") {
+		t.Errorf("warning = %q, want the fields that were not included", got.Warning)
+	}
+	if bk := getTestBook(t, r, "/v1/books/2"); bk.Title != "Animal Farm: A Fairy Story" || bk.ISBN != "9780451526342" {
+		t.Errorf("patched book = %+v, want the new title and the same isbn", bk)
+	}
+
+	assertProblem(t, serve(r, http.MethodPatch, "/v1/books", "application/json", `{"book_id":99,"title":"x"}`),
+		http.StatusNotFound, CodeNotFound)
+	assertProblem(t, serve(r, http.MethodPatch, "/v1/books", "application/json", `{"book_id":2,"isbn":"9780451524935"}`),
+		http.StatusConflict, CodeDuplicateISBN)
+	assertProblem(t, serve(r, http.MethodPatch, "/v1/books", "application/json", `{"book_id":2}`),
+		http.StatusUnprocessableEntity, CodeValidationFailed)
+}
+
+func TestPatchBookRequest(t *testing.T) {
+	r := newTestRouter()
+
+	w := serve(r, http.MethodPatch, "/v1/books/1", "application/merge-patch+json", `{"title":"1984","publisher":null}`)
+	assertStatus(t, w, http.StatusOK)
+	var bk model.Book
+	decodeBody(t, w, &bk)
+	if bk.ID != 1 || bk.Title != "1984" || bk.Publisher != "" || bk.PublisherID != 0 || bk.Version != 2 {
+		t.Errorf("patched book = %+v, want 1984 without a publisher with version 2", bk)
+	}
+	if got := w.Header().Get("ETag"); got != `"2"` {
+		t.Errorf("ETag = %q, want %q", got, `"2"`)
+	}
+
+	w = serve(r, http.MethodPatch, "/v1/books/1", "application/json-patch+json",
+		`[{"op":"test","path":"/version","value":2},{"op":"replace","path":"/published","value":"1949-06"}]`, "If-Match", `"2"`)
+	assertStatus(t, w, http.StatusOK)
+	if bk = getTestBook(t, r, "/v1/books/1"); bk.Published != "1949-06" || bk.Version != 3 {
+		t.Errorf("patched book = %+v, want published 1949-06 with version 3", bk)
+	}
+
+	w = serve(r, http.MethodGet, "/v1/books/1/history", "", "")
+	assertStatus(t, w, http.StatusOK)
+	var hs []model.BookHistory
+	decodeBody(t, w, &hs)
+	if len(hs) != 3 || hs[0].Operation != model.HistoryPatch || hs[1].Operation != model.HistoryPatch ||
+		hs[2].Operation != model.HistoryInsert {
+		t.Errorf("history = %+v, want patch, patch and insert", hs)
+	}
+
+	assertProblem(t, serve(r, http.MethodPatch, "/v1/books/99", "application/merge-patch+json", `{"title":"x"}`),
+		http.StatusNotFound, CodeNotFound)
+	assertProblem(t, serve(r, http.MethodPatch, "/v1/books/1", "application/json-patch+json",
+		`[{"op":"test","path":"/title","value":"Animal Farm"}]`), http.StatusConflict, CodePatchTestFailed)
+	assertProblem(t, serve(r, http.MethodPatch, "/v1/books/1", "application/merge-patch+json", `{"isbn":"9780451526342"}`),
+		http.StatusConflict, CodeDuplicateISBN)
+	assertProblem(t, serve(r, http.MethodPatch, "/v1/books/1", "application/merge-patch+json", `{"title":"x"}`,
+		"If-Match", `"1"`), http.StatusPreconditionFailed, CodeVersionConflict)
+	assertProblem(t, serve(r, http.MethodPatch, "/v1/books/1", "application/json", `{"title":"x"}`),
+		http.StatusUnsupportedMediaType, CodeUnsupportedMediaType)
+	if bk = getTestBook(t, r, "/v1/books/1"); bk.Title != "1984" || bk.Version != 3 {
+		t.Errorf("book = %+v after the failed patches, want 1984 with version 3", bk)
+	}
+}
+
+func TestDeleteBooksRequest(t *testing.T) {
+	r := newTestRouter()
+
+	assertMessage(t, serve(r, http.MethodDelete, "/v1/books/5", "", ""), config.DeleteSuccessMsg, 1)
+	assertProblem(t, serve(r, http.MethodGet, "/v1/books/5", "", ""), http.StatusNotFound, CodeNotFound)
+	assertProblem(t, serve(r, http.MethodDelete, "/v1/books/5", "", ""), http.StatusNotFound, CodeNotFound)
+	assertProblem(t, serve(r, http.MethodDelete, "/v1/books/99", "", ""), http.StatusNotFound, CodeNotFound)
+	assertProblem(t, serve(r, http.MethodDelete, "/v1/books/0", "", ""), http.StatusBadRequest, CodeBadRequest)
+
+	w := serve(r, http.MethodGet, "/v1/books", "", "")
+	assertStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("X-Total-Count"); got != "4" {
+		t.Errorf("X-Total-Count = %q after the delete, want 4", got)
+	}
+}
+
+// newSlowSqliteRouter will return the routes of a server with a sqlite storage of the dbtest books,
+// the update of a book runs a trigger that count for a long time so the query is interrupted by the context
+func newSlowSqliteRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "book.db")
+	s, err := db.OpenSqliteStorage(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.CloseDB)
+	if err = s.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.InsertBooks(context.Background(), dbtest.Books, db.AllOrNothing); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := sqlx.Connect("sqlite", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err = conn.Exec(`CREATE TRIGGER book_slow_update BEFORE UPDATE ON book BEGIN
+		SELECT count(*) FROM (WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c LIMIT 1000000000) SELECT x FROM c);
+		END`); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	return GetServer(":0", gin.New(), s).SetupRoutes()
+}
+
+func TestQueryTimeout(t *testing.T) {
+	r := newSlowSqliteRouter(t)
+	defer func(d time.Duration) { config.QueryTimeout = d }(config.QueryTimeout)
+	patch := `{"book_id":2,"title":"Animal Farm: A Fairy Story"}`
+
+	config.QueryTimeout = 100 * time.Millisecond
+	start := time.Now()
+	assertProblem(t, serve(r, http.MethodPatch, "/v1/books", "application/json", patch), http.StatusGatewayTimeout, CodeQueryTimeout)
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("the timed out request took %s", d)
+	}
+
+	config.QueryTimeout = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	defer time.AfterFunc(100*time.Millisecond, cancel).Stop()
+	req := httptest.NewRequest(http.MethodPatch, "/v1/books", strings.NewReader(patch)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assertProblem(t, w, http.StatusServiceUnavailable, CodeRequestCanceled)
+}
+
+func TestAdminRequests(t *testing.T) {
+	r := newTestRouter()
+	defer func(token string) { config.AdminToken = token }(config.AdminToken)
+	config.AdminToken = "secret"
+	admin := []string{"Authorization", "Bearer secret"}
+	assertMessage(t, serve(r, http.MethodDelete, "/v1/books/5", "", ""), config.DeleteSuccessMsg, 1)
+
+	for _, path := range []string{"/v1/books?include_deleted=true", "/v1/books/5?include_deleted=true",
+		"/v1/books/search?q=gatsby&include_deleted=true"} {
+		assertProblem(t, serve(r, http.MethodGet, path, "", ""), http.StatusForbidden, CodeForbidden)
+		assertProblem(t, serve(r, http.MethodGet, path, "", "", "Authorization", "Bearer other"),
+			http.StatusForbidden, CodeForbidden)
+		assertProblem(t, serve(r, http.MethodGet, path, "", "", "Authorization", "secret"),
+			http.StatusForbidden, CodeForbidden)
+		assertStatus(t, serve(r, http.MethodGet, path, "", "", admin...), http.StatusOK)
+	}
+	if w := serve(r, http.MethodGet, "/v1/books?include_deleted=true", "", "", admin...); w.Header().Get("X-Total-Count") != "5" {
+		t.Errorf("X-Total-Count = %q with the deleted books, want 5", w.Header().Get("X-Total-Count"))
+	}
+
+	assertProblem(t, serve(r, http.MethodPost, "/v1/books/purge?older_than=0s", "", ""), http.StatusForbidden, CodeForbidden)
+	assertStatus(t, serve(r, http.MethodPost, "/v1/books/purge?older_than=0s", "", "", admin...), http.StatusOK)
+
+	config.AdminToken = ""
+	assertProblem(t, serve(r, http.MethodPost, "/v1/books/purge", "", "", "Authorization", "Bearer "),
+		http.StatusForbidden, CodeForbidden)
+}
+
+func TestConditionalGetRequest(t *testing.T) {
+	r := newTestRouter()
+
+	w := serve(r, http.MethodGet, "/v1/books/1", "", "")
+	assertStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("Vary"); got != "Authorization" {
+		t.Errorf("Vary = %q, want Authorization", got)
+	}
+	assertStatus(t, serve(r, http.MethodGet, "/v1/books/1", "", "", "If-None-Match", `"1"`), http.StatusNotModified)
+
+	// the sparse fieldset is another representation that the tag of the whole book does not match
+	w = serve(r, http.MethodGet, "/v1/books/1?fields=title", "", "", "If-None-Match", `"1"`)
+	assertStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("ETag"); got != `"1+title"` {
+		t.Errorf("ETag = %q with fields, want %q", got, `"1+title"`)
+	}
+	assertStatus(t, serve(r, http.MethodGet, "/v1/books/1?fields=title", "", "", "If-None-Match", `"1+title"`),
+		http.StatusNotModified)
+	assertStatus(t, serve(r, http.MethodGet, "/v1/books/1", "", "", "If-None-Match", `"1+title"`), http.StatusOK)
+
+	w = serve(r, http.MethodGet, "/v1/books", "", "")
+	assertStatus(t, w, http.StatusOK)
+	etag := w.Header().Get("ETag")
+	assertStatus(t, serve(r, http.MethodGet, "/v1/books", "", "", "If-None-Match", etag), http.StatusNotModified)
+	assertStatus(t, serve(r, http.MethodGet, "/v1/books?fields=title", "", "", "If-None-Match", etag), http.StatusOK)
+}
+
+func TestStopLogging(t *testing.T) {
+	logFile, defaultWriter, stdout := config.LogFile, gin.DefaultWriter, os.Stdout
+	defer func() { config.LogFile, gin.DefaultWriter, os.Stdout = logFile, defaultWriter, stdout }()
+	config.LogFile = filepath.Join(t.TempDir(), "api.log")
+
+	debug := false
+	s := GetServer(":0", gin.New(), db.NewMemoryStorage(append([]model.Book(nil), dbtest.Books...)...))
+	s.StartLogging(&debug)
+	r := s.SetupRoutes()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/books", nil))
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = pw
+	s.StopLogging()
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/books/1", nil))
+	pw.Close()
+	out, err := io.ReadAll(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "GET /v1/books/1") {
+		t.Errorf("stdout after StopLogging = %q, want the request logged", out)
+	}
+
+	f, err := os.ReadFile(config.LogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(f), "GET /v1/books ") || strings.Contains(string(f), "/v1/books/1") {
+		t.Errorf("log file = %q, want only the request before StopLogging", f)
+	}
+}
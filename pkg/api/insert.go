@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"goapp/config"
+	"goapp/pkg/db"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Outcome status of a single book insert
+const (
+	InsertCreated    = "created"
+	InsertFailed     = "failed"
+	InsertRolledBack = "rolled_back"
+)
+
+// InsertBookResult is the outcome of a single book of the insert request, Index is the position in the request
+type InsertBookResult struct {
+	Index  int          `json:"index"`
+	Status string       `json:"status"`
+	ID     int          `json:"book_id,omitempty"`
+	Error  *ResultError `json:"error,omitempty"`
+}
+
+// ResultError is the reason a single book could not be inserted
+type ResultError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// InsertBooksResponse is the response of the insert request
+type InsertBooksResponse struct {
+	Message      string             `json:"message"`
+	RowsAffected int                `json:"rows_affected"`
+	IDs          []int              `json:"book_ids"`
+	Results      []InsertBookResult `json:"results"`
+}
+
+// fail will set the book as failed with the reason
+func (r *InsertBookResult) fail(code string, msg string, errs ...FieldError) {
+	r.Status = InsertFailed
+	r.ID = 0
+	r.Error = &ResultError{Code: code, Message: msg, Errors: errs}
+}
+
+// setOutcome will set the status of the book from the storage insert result
+func (r *InsertBookResult) setOutcome(res db.InsertResult) {
+	switch {
+	case errors.Is(res.Err, db.ErrDuplicateISBN):
+		r.fail(CodeDuplicateISBN, config.DuplicateISBNErrMsg,
+			FieldError{Field: "isbn", Code: "unique", Message: config.DuplicateISBNErrMsg})
+	case errors.Is(res.Err, db.ErrDuplicatePublisher):
+		r.fail(CodeDuplicatePublisher, config.DuplicatePublisherErrMsg,
+			FieldError{Field: "publisher", Code: "unique", Message: res.Err.Error()})
+	case errors.Is(res.Err, db.ErrUnknownPublisher):
+		r.fail(CodeValidationFailed, config.UnknownPublisherErrMsg,
+			FieldError{Field: "publisher_id", Code: "exists", Message: res.Err.Error()})
+	case res.Err != nil:
+		r.fail(CodeInternal, config.DBOperationErrMsg)
+	case res.ID == 0:
+		r.Status = InsertRolledBack
+	default:
+		r.Status = InsertCreated
+		r.ID = res.ID
+	}
+}
+
+// ValidateInsertResults will response with 201 if every book is inserted, otherwise 207 with the outcome
+// of every book. The Location header is set when a single book is inserted.
+func ValidateInsertResults(c *gin.Context, results []InsertBookResult) {
+	resp := InsertBooksResponse{Message: config.AddSuccessMsg, IDs: []int{}, Results: results}
+	for _, r := range results {
+		if r.Status == InsertCreated {
+			resp.IDs = append(resp.IDs, r.ID)
+		}
+	}
+	resp.RowsAffected = len(resp.IDs)
+
+	if resp.RowsAffected < len(results) {
+		resp.Message = config.AddPartialMsg
+		c.JSON(http.StatusMultiStatus, resp)
+		return
+	}
+	if len(resp.IDs) == 1 {
+		c.Header("Location", bookLocation(resp.IDs[0]))
+	}
+	c.JSON(http.StatusCreated, resp)
+}
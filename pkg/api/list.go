@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goapp/config"
+	"goapp/pkg/db"
+	"goapp/pkg/model"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListBooksResponse is the list response with the page metadata.
+// Page and TotalPages are set with page pagination, the cursors with cursor pagination.
+// The links are the request url of the other pages, they are empty when there is no such page.
+// Books are the books of the page with only the requested fields if the fields parameter is set.
+type ListBooksResponse struct {
+	Books      interface{} `json:"books" swaggertype:"array,object"`
+	Total      int         `json:"total"`
+	Page       int         `json:"page,omitempty"`
+	PageSize   int         `json:"page_size"`
+	TotalPages int         `json:"total_pages,omitempty"`
+	First      string      `json:"first,omitempty"`
+	Prev       string      `json:"prev,omitempty"`
+	Next       string      `json:"next,omitempty"`
+	Last       string      `json:"last,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+}
+
+// setLinks will set the page links of the offset page
+func (r *ListBooksResponse) setLinks(c *gin.Context) {
+	r.TotalPages = (r.Total + r.PageSize - 1) / r.PageSize
+	last := r.TotalPages
+	if last < 1 {
+		last = 1
+	}
+	page := func(id int) string { return pageLink(c, map[string]string{"page_id": strconv.Itoa(id)}) }
+	r.First, r.Last = page(1), page(last)
+	if r.Page > 1 {
+		r.Prev = page(r.Page - 1)
+		if r.Page > last {
+			r.Prev = r.Last
+		}
+	}
+	if r.Page < last {
+		r.Next = page(r.Page + 1)
+	}
+}
+
+// setCursorLinks will set the page links of the cursor page, there is no last page link
+func (r *ListBooksResponse) setCursorLinks(c *gin.Context) {
+	r.First = pageLink(c, map[string]string{"pagination": model.PaginationCursor, "cursor": "", "page_id": ""})
+	if r.NextCursor != "" {
+		r.Next = pageLink(c, map[string]string{"cursor": r.NextCursor, "page_id": ""})
+	}
+	if r.PrevCursor != "" {
+		r.Prev = pageLink(c, map[string]string{"cursor": r.PrevCursor, "page_id": ""})
+	}
+}
+
+// SetHeaders will set the X-Total-Count header and the RFC 8288 Link header of the page links
+func (r *ListBooksResponse) SetHeaders(c *gin.Context) {
+	c.Header("X-Total-Count", strconv.Itoa(r.Total))
+	var links []string
+	for _, l := range []struct{ rel, url string }{{"first", r.First}, {"prev", r.Prev}, {"next", r.Next}, {"last", r.Last}} {
+		if l.url != "" {
+			links = append(links, fmt.Sprintf("<%s>; rel=%q", l.url, l.rel))
+		}
+	}
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+}
+
+// pageLink will return the request url with the query parameters replaced, an empty value will remove the parameter
+func pageLink(c *gin.Context, params map[string]string) string {
+	u := *c.Request.URL
+	q := u.Query()
+	for k, v := range params {
+		if v == "" {
+			q.Del(k)
+		} else {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
+
+// cursor is the keyset position with the order it belong to, that is encoded into the opaque cursor string
+type cursor struct {
+	OrderBy string   `json:"o"`
+	Values  []string `json:"v,omitempty"`
+	ID      int      `json:"id"`
+	Before  bool     `json:"b,omitempty"`
+}
+
+// encodeCursor will encode the keyset position of the list order into the url safe cursor string
+func encodeCursor(order []db.SortField, k *db.Keyset) string {
+	b, _ := json.Marshal(cursor{OrderBy: db.FormatOrderBy(order), Values: k.Values, ID: k.ID, Before: k.Before})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor will decode the cursor string into the keyset position,
+// the cursor has to belong to the same list order
+func decodeCursor(s string, order []db.SortField) (*db.Keyset, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New(config.InvalidCursorErrMsg)
+	}
+	var cur cursor
+	if err = json.Unmarshal(b, &cur); err != nil || cur.ID < 0 {
+		return nil, errors.New(config.InvalidCursorErrMsg)
+	}
+	if orderBy := db.FormatOrderBy(order); cur.OrderBy != orderBy {
+		return nil, fmt.Errorf("cursor belong to order_by %s, not %s", cur.OrderBy, orderBy)
+	}
+	// every order by column except book_id has a value
+	if len(cur.Values) != len(order)-1 {
+		return nil, errors.New(config.InvalidCursorErrMsg)
+	}
+	return &db.Keyset{Values: cur.Values, ID: cur.ID, Before: cur.Before}, nil
+}
+
+// cursorPage will trim the extra book that was selected to look ahead of the page,
+// set the cursors of the pages that exist next to it and shape the books to the requested fields
+func cursorPage(bks []model.Book, p *db.PageList, pageSize int, fields []string) *ListBooksResponse {
+	before := p.Keyset != nil && p.Keyset.Before
+	more := len(bks) > pageSize
+	if more && before {
+		bks = bks[1:]
+	} else if more {
+		bks = bks[:pageSize]
+	}
+	// the page that the cursor came from is next to this page
+	hasNext, hasPrev := more, p.Keyset != nil
+	if before {
+		hasNext, hasPrev = true, more
+	}
+
+	// an empty page does not have a book to continue from, it only happen when the books are deleted
+	resp := &ListBooksResponse{Books: shapeBooks(bks, fields), PageSize: pageSize}
+	if len(bks) == 0 {
+		return resp
+	}
+	if hasNext {
+		resp.NextCursor = encodeCursor(p.OrderBy, db.KeysetOf(&bks[len(bks)-1], p.OrderBy, false))
+	}
+	if hasPrev {
+		resp.PrevCursor = encodeCursor(p.OrderBy, db.KeysetOf(&bks[0], p.OrderBy, true))
+	}
+	return resp
+}
@@ -8,9 +8,31 @@ import (
 	"io"
 	"log"
 	"os"
+	"sync"
 	"time"
 )
 
+// switchWriter is the writer of the api request log, it could be switched to another writer
+// while the requests are still logged
+type switchWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write will write the log line to the current writer
+func (sw *switchWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Write(p)
+}
+
+// set will switch the writer, the log line that is being written is completed first
+func (sw *switchWriter) set(w io.Writer) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.w = w
+}
+
 // StartLogging is for initialize the zerolog that will log into the local log file path
 // By default will only log on error level if you want debug level then debug flag need to be true
 func (s *Server) StartLogging(debug *bool) {
@@ -22,8 +44,10 @@ func (s *Server) StartLogging(debug *bool) {
 	if err != nil {
 		log.Fatalf("%s %s", config.FailToSaveLogErrMsg, err)
 	}
-	gin.DefaultWriter = io.MultiWriter(f, os.Stdout)
-	s.router.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+	s.logFile = f
+	s.logWriter = &switchWriter{w: io.MultiWriter(f, os.Stdout)}
+	gin.DefaultWriter = s.logWriter
+	s.router.Use(gin.LoggerWithConfig(gin.LoggerConfig{Output: s.logWriter, Formatter: func(param gin.LogFormatterParams) string {
 		return fmt.Sprintf("[%s] - %s \"%s %s %s %d %s \"%s\" %s\"\n",
 			param.TimeStamp.Format(time.RFC1123),
 			param.ClientIP,
@@ -34,5 +58,21 @@ func (s *Server) StartLogging(debug *bool) {
 			param.Latency,
 			param.Request.UserAgent(),
 			param.ErrorMessage)
-	}))
+	}}))
+}
+
+// StopLogging will flush and close the local log file, the api requests will still be log to stdout
+// since the request logger is switched to stdout before the file is closed
+func (s *Server) StopLogging() {
+	if s.logFile == nil {
+		return
+	}
+	s.logWriter.set(os.Stdout)
+	if err := s.logFile.Sync(); err != nil {
+		log.Printf("%s %s", config.FailToSaveLogErrMsg, err)
+	}
+	if err := s.logFile.Close(); err != nil {
+		log.Printf("%s %s", config.FailToSaveLogErrMsg, err)
+	}
+	s.logFile = nil
 }
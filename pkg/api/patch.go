@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goapp/config"
+	"goapp/pkg/db"
+	"goapp/pkg/isbn"
+	"goapp/pkg/jsonpatch"
+	"goapp/pkg/model"
+	"goapp/pkg/pubdate"
+	"net/http"
+	"sort"
+)
+
+// bookPatchFields are the book fields that a patch can change, the other book fields are read-only.
+// A patch that remove a field or set it to null will clear it.
+// The publisher_id is changeable too, a patch that remove it or set it to null will keep the publisher.
+var bookPatchFields = []string{"isbn", "title", "author_name", "author_surname", "published", "publisher"}
+
+// requiredPatchFields are the book fields that could not be cleared by a patch
+var requiredPatchFields = map[string]bool{"isbn": true, "title": true}
+
+// applyBookPatch will apply the merge patch or JSON patch of the content type to the book document
+// and return the patched book, or the problem if the patch could not be applied or the patched book is not valid
+func applyBookPatch(bk model.Book, contentType string, patch []byte) (model.Book, *Problem) {
+	doc, err := json.Marshal(bk)
+	if err != nil {
+		return bk, NewProblem(http.StatusInternalServerError, CodeInternal, config.InternalErrMsg)
+	}
+	var patched []byte
+	if contentType == jsonpatch.MergePatchType {
+		patched, err = jsonpatch.MergePatch(doc, patch)
+	} else {
+		var p jsonpatch.Patch
+		if p, err = jsonpatch.DecodePatch(patch); err == nil {
+			patched, err = p.Apply(doc)
+		}
+	}
+	switch {
+	case errors.Is(err, jsonpatch.ErrInvalidPatch):
+		return bk, NewProblem(http.StatusBadRequest, CodeInvalidPatch, fmt.Sprintf("%s %s", config.InvalidPatchErrMsg, err.Error()))
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		return bk, NewProblem(http.StatusConflict, CodePatchTestFailed, fmt.Sprintf("%s: %s", config.PatchTestFailedErrMsg, err.Error()))
+	case err != nil:
+		return bk, NewProblem(http.StatusUnprocessableEntity, CodeInvalidPatch, fmt.Sprintf("%s %s", config.InvalidPatchErrMsg, err.Error()))
+	}
+	return patchedBook(bk, doc, patched)
+}
+
+// patchedBook will validate the patched book document against the original document and return the patched book.
+// The read-only fields have to keep their value, the changeable fields have to be a string or null
+// and the required fields could not be empty. A changed publisher_id has to be a positive integer.
+func patchedBook(bk model.Book, doc []byte, patched []byte) (model.Book, *Problem) {
+	var orig, m map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &m); err != nil || m == nil {
+		return bk, NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, config.PatchedBookNotObjectErrMsg)
+	}
+	_ = json.Unmarshal(doc, &orig)
+
+	changeable := map[string]bool{}
+	for _, f := range bookPatchFields {
+		changeable[f] = true
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs []FieldError
+	for _, k := range keys {
+		if changeable[k] || k == "publisher_id" {
+			continue
+		}
+		if !db.IsBookField(k) {
+			errs = append(errs, FieldError{Field: k, Code: "unknown_field", Message: config.UnknownFieldErrMsg})
+		} else if !bytes.Equal(orig[k], m[k]) {
+			errs = append(errs, FieldError{Field: k, Code: "read_only", Message: config.ReadOnlyFieldErrMsg})
+		}
+	}
+	values := map[string]string{}
+	for _, f := range bookPatchFields {
+		var v string
+		if raw, ok := m[f]; ok && string(raw) != "null" {
+			if err := json.Unmarshal(raw, &v); err != nil {
+				errs = append(errs, FieldError{Field: f, Code: "invalid_type", Message: config.StringFieldErrMsg})
+				continue
+			}
+		}
+		if v == "" && requiredPatchFields[f] {
+			errs = append(errs, FieldError{Field: f, Code: "required", Message: config.DataCouldNotBeEmptyErrMsg})
+		}
+		// the isbn and published of the books from before their validation are kept until they are changed
+		if f == "isbn" && v != "" && v != bk.ISBN {
+			n, err := isbn.Normalize(v)
+			if err != nil {
+				errs = append(errs, isbnFieldError(f, err))
+				continue
+			}
+			v = n
+		}
+		if f == "published" && v != "" && v != bk.Published {
+			d, err := pubdate.Normalize(v)
+			if err != nil {
+				errs = append(errs, publishedFieldError(f, err))
+				continue
+			}
+			v = d
+		}
+		values[f] = v
+	}
+	publisherID := bk.PublisherID
+	if raw, ok := m["publisher_id"]; ok && string(raw) != "null" && !bytes.Equal(orig["publisher_id"], raw) {
+		if err := json.Unmarshal(raw, &publisherID); err != nil || publisherID < 1 {
+			errs = append(errs, FieldError{Field: "publisher_id", Code: "invalid_type", Message: config.InvalidPublisherIDErrMsg})
+		}
+	}
+	if len(errs) > 0 {
+		return bk, NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, config.InvalidPatchedBookErrMsg, errs...)
+	}
+
+	// only the changeable fields are decoded so the read-only fields keep their value
+	b, _ := json.Marshal(values)
+	if err := json.Unmarshal(b, &bk); err != nil {
+		return bk, NewProblem(http.StatusInternalServerError, CodeInternal, config.InternalErrMsg)
+	}
+	bk.PublisherID = publisherID
+	return bk, nil
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goapp/config"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// ProblemContentType is the media type of the error responses
+const ProblemContentType = "application/problem+json"
+
+// Machine-readable problem codes that the clients can rely on
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidJSON          = "invalid_json"
+	CodeInvalidQuery         = "invalid_query"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeDuplicateISBN        = "duplicate_isbn"
+	CodeInvalidISBN          = "invalid_isbn"
+	CodeInvalidPublished     = "invalid_published"
+	CodeAuthorHasBooks       = "author_has_books"
+	CodePublisherHasBooks    = "publisher_has_books"
+	CodeDuplicatePublisher   = "duplicate_publisher"
+	CodeBatchRolledBack      = "batch_rolled_back"
+	CodeEmptyFilter          = "empty_filter"
+	CodeVersionConflict      = "version_conflict"
+	CodePreconditionRequired = "precondition_required"
+	CodeInvalidPatch         = "invalid_patch"
+	CodePatchTestFailed      = "patch_test_failed"
+	CodeQueryTimeout         = "query_timeout"
+	CodeRequestCanceled      = "request_canceled"
+	CodeInternal             = "internal_error"
+)
+
+// Problem is the RFC 7807 problem details error response.
+// Code is the machine-readable error code, Errors the field level details and Results
+// the outcome of every item of a batch request if any.
+type Problem struct {
+	Type     string             `json:"type"`
+	Title    string             `json:"title"`
+	Status   int                `json:"status"`
+	Code     string             `json:"code"`
+	Detail   string             `json:"detail,omitempty"`
+	Instance string             `json:"instance,omitempty"`
+	Errors   []FieldError       `json:"errors,omitempty"`
+	Results  []InsertBookResult `json:"results,omitempty"`
+}
+
+// FieldError is the problem detail of a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewProblem to initialize the problem with the status title
+func NewProblem(status int, code string, detail string, errs ...FieldError) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+		Errors: errs,
+	}
+}
+
+// Error to use the problem as error
+func (p *Problem) Error() string {
+	return fmt.Sprintf("%d %s: %s", p.Status, p.Code, p.Detail)
+}
+
+// AbortWithProblem will response with the problem as application/problem+json and stop the handler chain
+func AbortWithProblem(c *gin.Context, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// BindingProblem will convert the request binding error into a problem.
+// Malformed body will return 400, validation errors will return the status that passing through.
+func BindingProblem(err error, obj interface{}, status int) *Problem {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var validationErrs validator.ValidationErrors
+	code := CodeValidationFailed
+	if status == http.StatusBadRequest {
+		code = CodeInvalidQuery
+	}
+	switch {
+	case errors.Is(err, io.EOF):
+		return NewProblem(http.StatusBadRequest, CodeInvalidJSON, "request body is empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return NewProblem(http.StatusBadRequest, CodeInvalidJSON, fmt.Sprintf("%s %s", config.InvalidDataErrMsg, err.Error()))
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return NewProblem(http.StatusBadRequest, CodeInvalidJSON, config.InvalidDataErrMsg, FieldError{
+			Field:   field,
+			Code:    "invalid_type",
+			Message: fmt.Sprintf("must be %s, got %s", jsonTypeName(typeErr.Type), typeErr.Value),
+		})
+	case errors.As(err, &validationErrs):
+		var errs []FieldError
+		for _, fe := range validationErrs {
+			errs = append(errs, FieldError{
+				Field:   fieldName(obj, fe.StructField()),
+				Code:    fe.Tag(),
+				Message: validationMessage(fe),
+			})
+		}
+		return NewProblem(status, code, config.InvalidDataErrMsg, errs...)
+	}
+	if code == CodeValidationFailed {
+		code = CodeBadRequest
+	}
+	return NewProblem(http.StatusBadRequest, code, fmt.Sprintf("%s %s", config.InvalidDataErrMsg, err.Error()))
+}
+
+// RequiredFieldsProblem will return the 422 problem listing the empty fields, or nil if there is none
+func RequiredFieldsProblem(fields []string) *Problem {
+	if len(fields) == 0 {
+		return nil
+	}
+	errs := make([]FieldError, len(fields))
+	for i, f := range fields {
+		errs[i] = FieldError{Field: f, Code: "required", Message: config.DataCouldNotBeEmptyErrMsg}
+	}
+	return NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, config.DataCouldNotBeEmptyErrMsg, errs...)
+}
+
+// RecoveryHandler will response with the 500 problem when a handler panic
+func RecoveryHandler(c *gin.Context, err interface{}) {
+	log.Error().Msgf("panic recovered: %v", err)
+	AbortWithProblem(c, NewProblem(http.StatusInternalServerError, CodeInternal, config.InternalErrMsg))
+}
+
+// fieldName will return the json or form tag name of the struct field, the struct field name if there is no tag
+func fieldName(obj interface{}, structField string) string {
+	t := reflect.TypeOf(obj)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return structField
+	}
+	f, ok := t.FieldByName(structField)
+	if !ok {
+		return structField
+	}
+	for _, tag := range []string{"json", "form"} {
+		if name := strings.Split(f.Tag.Get(tag), ",")[0]; name != "" {
+			return name
+		}
+	}
+	return structField
+}
+
+// jsonTypeName will return the json type name of the go type
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map, reflect.Ptr:
+		return "object"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	}
+	return "number"
+}
+
+// validationMessage will describe the failed validation rule
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return config.DataCouldNotBeEmptyErrMsg
+	case "min":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	}
+	return fmt.Sprintf("failed on the %s rule", fe.Tag())
+}
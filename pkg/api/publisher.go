@@ -0,0 +1,322 @@
+package api
+
+import (
+	"fmt"
+	"goapp/config"
+	"goapp/pkg/db"
+	"goapp/pkg/model"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// publisherLocation will return the path of the single publisher resource
+func publisherLocation(id int) string {
+	return fmt.Sprintf("/v1/publishers/%d", id)
+}
+
+// bindPublisher will bind the publisher of the request body, response with the problem and return false if it is not valid.
+// The name could not be only spaces.
+func bindPublisher(c *gin.Context, p *model.Publisher) bool {
+	if err := c.ShouldBindJSON(p); !ValidateBinding(c, err, p, http.StatusUnprocessableEntity) {
+		return false
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		AbortWithProblem(c, RequiredFieldsProblem([]string{"name"}))
+		return false
+	}
+	return true
+}
+
+// listPublishersRequest godoc
+//
+//	@Summary		List Publishers
+//	@Description	For listing publishers per page, ordered by name.
+//	@Description	With q only the publishers that have it in their name or aliases are listed, case-insensitive,
+//	@Description	and with parent_id only the imprints of the parent publisher.
+//	@Tags			publishers
+//	@Produce		json
+//	@Param			q			query	string	false	"Text in the publisher name or aliases."
+//	@Param			parent_id	query	int		false	"The publisher_id of the parent publisher."
+//	@Param			page_id		query	int		false	"Page of the publishers, default 1."
+//	@Param			page_size	query	int		false	"Number of publishers in a page, default 25."
+//	@Success		200	{array}		model.Publisher
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/publishers [get]
+func (s *Server) listPublishersRequest(c *gin.Context) {
+	var req model.ListPublisherRequest
+	if err := c.ShouldBindQuery(&req); !ValidateBinding(c, err, &req, http.StatusBadRequest) {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	ps, err := s.db.ListPublishers(ctx, &db.PublisherQuery{Name: req.Q, ParentID: req.ParentID, Limit: req.PageSize,
+		OffSet: (req.PageID - 1) * req.PageSize})
+	if err != nil {
+		HandleDBError(c, "listPublishersRequest", err)
+	} else {
+		c.JSON(http.StatusOK, ps)
+	}
+}
+
+// getPublisherRequest godoc
+//
+//	@Summary		Get Publisher by publisher_id
+//	@Description	For getting a publisher by id with its aliases.
+//	@Tags			publishers
+//	@Produce		json
+//	@Param			id	path	int	true	"The publisher_id to be found."
+//	@Success		200	{object}	model.Publisher
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/publishers/{id} [get]
+func (s *Server) getPublisherRequest(c *gin.Context) {
+	id, ok := ValidatePublisherID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	p, err := s.db.GetPublisher(ctx, id)
+	if err != nil {
+		HandleDBError(c, "getPublisherRequest", err)
+	} else {
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+// insertPublisherRequest godoc
+//
+//	@Summary		Insert Publisher
+//	@Description	For inserting a publisher, an imprint of another publisher when parent_id is set.
+//	@Description	The books with the name or any of the aliases as publisher are published by the publisher from then on.
+//	@Description	Will return 201 with the new publisher and the Location header that point to it,
+//	@Description	409 if the name or an alias is already used by another publisher.
+//	@Tags			publishers
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body	model.Publisher	true	"Fields Required: name. publisher_id and updated_at will be ignored. The names are the same without the case and punctuation."
+//	@Success		201	{object}	model.Publisher
+//	@Header			201	{string}	Location	"/v1/publishers/{id} of the inserted publisher"
+//	@Failure		400	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/publishers [post]
+func (s *Server) insertPublisherRequest(c *gin.Context) {
+	if !ValidateContentType(c) {
+		return
+	}
+	var p model.Publisher
+	if !bindPublisher(c, &p) {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	if err := s.db.InsertPublisher(ctx, &p); err != nil {
+		HandleDBError(c, "insertPublisherRequest", err)
+		return
+	}
+	c.Header("Location", publisherLocation(p.ID))
+	c.JSON(http.StatusCreated, p)
+}
+
+// updatePublisherRequest godoc
+//
+//	@Summary		Update Publisher by publisher_id
+//	@Description	For updating the name, parent and aliases of a publisher by id, the aliases are replaced.
+//	@Description	The books of the publisher get the new name as publisher with a new version,
+//	@Description	the change is recorded in their history with the publisher operation.
+//	@Description	Will return the updated publisher, 409 if the name or an alias is already used by another publisher
+//	@Description	and 422 if the parent does not exist or is an imprint of the publisher.
+//	@Tags			publishers
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int				true	"The publisher_id to be updated."
+//	@Param			body	body	model.Publisher	true	"Fields Required: name. publisher_id and updated_at will be ignored."
+//	@Success		200	{object}	model.Publisher
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/publishers/{id} [put]
+func (s *Server) updatePublisherRequest(c *gin.Context) {
+	if !ValidateContentType(c) {
+		return
+	}
+	id, ok := ValidatePublisherID(c)
+	if !ok {
+		return
+	}
+	var p model.Publisher
+	if !bindPublisher(c, &p) {
+		return
+	}
+	p.ID = id
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	if err := s.db.UpdatePublisher(ctx, &p); err != nil {
+		HandleDBError(c, "updatePublisherRequest", err)
+		return
+	}
+	p, err := s.db.GetPublisher(ctx, id)
+	if err != nil {
+		HandleDBError(c, "updatePublisherRequest", err)
+	} else {
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+// deletePublisherRequest godoc
+//
+//	@Summary		Delete Publisher
+//	@Description	For deleting a publisher by id, its imprints get its parent as their parent.
+//	@Description	Will return 409 if any book is still published by the publisher, the deleted books too.
+//	@Tags			publishers
+//	@Produce		json
+//	@Param			id	path	int	true	"The publisher_id to be deleted."
+//	@Success		200
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/publishers/{id} [delete]
+func (s *Server) deletePublisherRequest(c *gin.Context) {
+	id, ok := ValidatePublisherID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	if err := s.db.DeletePublisher(ctx, id); err != nil {
+		HandleDBError(c, "deletePublisherRequest", err)
+	} else {
+		ValidateRowsAffected(c, 1, config.DeleteSuccessMsg)
+	}
+}
+
+// publisherBooksRequest godoc
+//
+//	@Summary		Publisher Books
+//	@Description	For listing the books of a publisher, ordered by published date.
+//	@Description	With include_imprints the books of its imprints are listed too, and of their imprints.
+//	@Description	The deleted books are left out, will return 404 if there is no such publisher.
+//	@Tags			publishers
+//	@Produce		json
+//	@Param			id					path	int		true	"The publisher_id of the books."
+//	@Param			include_imprints	query	bool	false	"List the books of the imprints too."
+//	@Param			page_id				query	int		false	"Page of the books, default 1."
+//	@Param			page_size			query	int		false	"Number of books in a page, default 25."
+//	@Success		200	{array}		model.Book
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/publishers/{id}/books [get]
+func (s *Server) publisherBooksRequest(c *gin.Context) {
+	id, ok := ValidatePublisherID(c)
+	if !ok {
+		return
+	}
+	var req model.PublisherBooksRequest
+	if err := c.ShouldBindQuery(&req); !ValidateBinding(c, err, &req, http.StatusBadRequest) {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	bks, err := s.db.ListPublisherBooks(ctx, &db.PublisherBooksQuery{PublisherID: id, IncludeImprints: req.IncludeImprints,
+		Limit: req.PageSize, OffSet: (req.PageID - 1) * req.PageSize})
+	if err != nil {
+		HandleDBError(c, "publisherBooksRequest", err)
+	} else {
+		c.JSON(http.StatusOK, bks)
+	}
+}
+
+// publisherDuplicatesRequest godoc
+//
+//	@Summary		Publisher Duplicates
+//	@Description	For listing the groups of publishers with names or aliases that look alike, like Penguin and Penguin Books,
+//	@Description	to review them and merge the ones that are the same publisher.
+//	@Description	The names are compared without the case, punctuation and words like books, press or publishing.
+//	@Tags			publishers
+//	@Produce		json
+//	@Success		200	{array}		model.PublisherCluster
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/publishers/duplicates [get]
+func (s *Server) publisherDuplicatesRequest(c *gin.Context) {
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	pcs, err := s.db.ListPublisherDuplicates(ctx)
+	if err != nil {
+		HandleDBError(c, "publisherDuplicatesRequest", err)
+	} else {
+		c.JSON(http.StatusOK, pcs)
+	}
+}
+
+// mergePublishersRequest godoc
+//
+//	@Summary		Merge Publishers
+//	@Description	For merging the publishers of the body into the publisher of the id, the merged publishers are deleted.
+//	@Description	Their names and aliases become aliases of the publisher, their imprints become its imprints,
+//	@Description	and their books get the publisher with a new version, recorded in their history with the publisher operation.
+//	@Description	Will return the merged publisher, 404 if there is no such publisher
+//	@Description	and 422 if a merged publisher does not exist or is the publisher itself.
+//	@Tags			publishers
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int							true	"The publisher_id that the publishers are merged into."
+//	@Param			body	body	model.MergePublisherRequest	true	"Fields Required: publisher_ids."
+//	@Success		200	{object}	model.Publisher
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		415	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Failure		504	{object}	Problem
+//	@Router			/publishers/{id}/merge [post]
+func (s *Server) mergePublishersRequest(c *gin.Context) {
+	if !ValidateContentType(c) {
+		return
+	}
+	id, ok := ValidatePublisherID(c)
+	if !ok {
+		return
+	}
+	var req model.MergePublisherRequest
+	if err := c.ShouldBindJSON(&req); !ValidateBinding(c, err, &req, http.StatusUnprocessableEntity) {
+		return
+	}
+
+	ctx, cancel := s.queryContext(c)
+	defer cancel()
+	if err := s.db.MergePublishers(ctx, id, req.PublisherIDs); err != nil {
+		HandleDBError(c, "mergePublishersRequest", err)
+		return
+	}
+	p, err := s.db.GetPublisher(ctx, id)
+	if err != nil {
+		HandleDBError(c, "mergePublishersRequest", err)
+	} else {
+		c.JSON(http.StatusOK, p)
+	}
+}
@@ -1,25 +1,220 @@
 package api
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"goapp/config"
+	"goapp/pkg/db"
+	"goapp/pkg/isbn"
+	"goapp/pkg/model"
+	"goapp/pkg/pubdate"
 	"net/http"
+	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 )
 
-// ValidateContentType will check for required content-type in header and return false if not exist
-func ValidateContentType(c *gin.Context) bool {
-	if ct := c.Request.Header.Get("Content-Type"); ct != "application/json" {
-		log.Error().Msgf("%s: %s", config.UnsupportedContentType, ct)
-		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": config.UnsupportedContentType})
+// ValidateContentType will check for required content-type in header and return false if not exist.
+// The content-type has to be one of the types, application/json when there is none.
+func ValidateContentType(c *gin.Context, types ...string) bool {
+	if len(types) == 0 {
+		types = []string{"application/json"}
+	}
+	ct := c.ContentType()
+	for _, t := range types {
+		if ct == t {
+			return true
+		}
+	}
+	log.Error().Msgf("%s: %s", config.UnsupportedContentType, ct)
+	AbortWithProblem(c, NewProblem(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+		fmt.Sprintf("%s Expected %s, got %q", config.UnsupportedContentType, strings.Join(types, " or "), ct)))
+	return false
+}
+
+// ValidateBinding will response with the binding problem and return false if the request could not be bound.
+// The status is used for validation errors, malformed data will always return 400.
+func ValidateBinding(c *gin.Context, err error, obj interface{}, status int) bool {
+	if err != nil {
+		log.Error().Msgf("%s: %s", config.InvalidDataErrMsg, err.Error())
+		AbortWithProblem(c, BindingProblem(err, obj, status))
+		return false
+	}
+	return true
+}
+
+// ValidateAdmin will response with 403 and return false if the request does not have the configured admin token
+// as the Authorization bearer token, every request is rejected when there is no admin token configured
+func ValidateAdmin(c *gin.Context) bool {
+	auth := c.GetHeader("Authorization")
+	if config.AdminToken == "" || !strings.HasPrefix(auth, "Bearer ") ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(config.AdminToken)) != 1 {
+		log.Warn().Msgf("%s: %s %s", config.AdminRequiredErrMsg, c.Request.Method, c.Request.URL.Path)
+		AbortWithProblem(c, NewProblem(http.StatusForbidden, CodeForbidden, config.AdminRequiredErrMsg))
+		return false
+	}
+	return true
+}
+
+// ValidateBookID will parse the book id path parameter, response with 400 and return false if it is not valid
+func ValidateBookID(c *gin.Context) (int, bool) {
+	return validateID(c, config.InvalidIDErrMsg)
+}
+
+// ValidateAuthorID will parse the author id path parameter, response with 400 and return false if it is not valid
+func ValidateAuthorID(c *gin.Context) (int, bool) {
+	return validateID(c, config.InvalidAuthorIDErrMsg)
+}
+
+// ValidatePublisherID will parse the publisher id path parameter, response with 400 and return false if it is not valid
+func ValidatePublisherID(c *gin.Context) (int, bool) {
+	return validateID(c, config.InvalidPublisherIDErrMsg)
+}
+
+// validateID will parse the id path parameter, response with 400 and the message and return false if it is not valid
+func validateID(c *gin.Context, msg string) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		log.Error().Msgf("%s: %s", config.InvalidDataErrMsg, c.Param("id"))
+		AbortWithProblem(c, NewProblem(http.StatusBadRequest, CodeBadRequest, msg,
+			FieldError{Field: "id", Code: "invalid_id", Message: msg}))
+		return 0, false
+	}
+	return id, true
+}
+
+// ValidateRequiredFields will response with 422 and return false if any of the string fields
+// (and int fields when withInt is true) are empty, the omitempty fields are optional
+func ValidateRequiredFields(c *gin.Context, obj interface{}, withInt bool) bool {
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	t := v.Type()
+	var empty []string
+	for j := 0; j < v.NumField(); j++ {
+		if strings.HasSuffix(t.Field(j).Tag.Get("json"), ",omitempty") {
+			continue
+		}
+		if (t.Field(j).Type.String() == "string" && v.Field(j).Interface() == "") ||
+			(withInt && t.Field(j).Type.String() == "int" && v.Field(j).Interface() == 0) {
+			empty = append(empty, t.Field(j).Tag.Get("json"))
+		}
+	}
+	if p := RequiredFieldsProblem(empty); p != nil {
+		log.Error().Msgf("%s %s", strings.Join(empty, ", "), config.DataCouldNotBeEmptyErrMsg)
+		AbortWithProblem(c, p)
 		return false
 	}
 	return true
 }
 
+// ValidateBookFields will set the isbn to the canonical ISBN-13 digits and the published to the ISO 8601 date,
+// the nil fields are skipped. It will response with 422 and return false if any of them is not valid.
+func ValidateBookFields(c *gin.Context, isbnValue, published *string) bool {
+	if p := normalizeBookFields(isbnValue, published); p != nil {
+		log.Error().Msgf("%s: %s", config.InvalidDataErrMsg, p.Detail)
+		AbortWithProblem(c, p)
+		return false
+	}
+	return true
+}
+
+// normalizeBookFields will set the isbn and published to their normalized form, the nil fields are skipped.
+// It will return the problem with the errors of the fields that are not valid, or nil.
+func normalizeBookFields(isbnValue, published *string) *Problem {
+	var p *Problem
+	fail := func(code, detail string, fe FieldError) {
+		if p == nil {
+			p = NewProblem(http.StatusUnprocessableEntity, code, detail)
+		}
+		p.Errors = append(p.Errors, fe)
+	}
+	if isbnValue != nil {
+		if n, err := isbn.Normalize(*isbnValue); err != nil {
+			fail(CodeInvalidISBN, config.InvalidISBNErrMsg, isbnFieldError("isbn", err))
+		} else {
+			*isbnValue = n
+		}
+	}
+	if published != nil {
+		if d, err := pubdate.Normalize(*published); err != nil {
+			fail(CodeInvalidPublished, config.InvalidPublishedErrMsg, publishedFieldError("published", err))
+		} else {
+			*published = d
+		}
+	}
+	return p
+}
+
+// normalizeFilterISBN will set the isbn of a filter to its ISBN-13 when it is a valid isbn,
+// so the books are found by every form of their isbn. A partial or invalid isbn is matched as it is.
+func normalizeFilterISBN(isbnValue *string) {
+	if n, err := isbn.Normalize(*isbnValue); err == nil {
+		*isbnValue = n
+	}
+}
+
+// isbnFieldError will return the field error of the isbn validation error
+func isbnFieldError(field string, err error) FieldError {
+	switch {
+	case errors.Is(err, isbn.ErrLength):
+		return FieldError{Field: field, Code: "isbn_length", Message: config.ISBNLengthErrMsg}
+	case errors.Is(err, isbn.ErrCharacter):
+		return FieldError{Field: field, Code: "isbn_character", Message: config.ISBNCharacterErrMsg}
+	case errors.Is(err, isbn.ErrPrefix):
+		return FieldError{Field: field, Code: "isbn_prefix", Message: config.ISBNPrefixErrMsg}
+	}
+	return FieldError{Field: field, Code: "isbn_checksum", Message: config.ISBNChecksumErrMsg}
+}
+
+// ValidateBookAuthors will response with 422 and return false if there is no author,
+// or any author does not have a positive author_id or a known role. The empty role is author.
+func ValidateBookAuthors(c *gin.Context, bas []model.BookAuthor) bool {
+	if len(bas) == 0 {
+		log.Error().Msg(config.BookAuthorsRequiredErrMsg)
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, config.BookAuthorsRequiredErrMsg))
+		return false
+	}
+	var errs []FieldError
+	for i, ba := range bas {
+		if ba.AuthorID < 1 {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("[%d].author_id", i), Code: "min",
+				Message: config.InvalidAuthorIDErrMsg})
+		}
+		if ba.Role != "" && !isRole(ba.Role) {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("[%d].role", i), Code: "oneof",
+				Message: fmt.Sprintf("%s %s", config.AuthorRoleErrMsg, strings.Join(model.Roles, ", "))})
+		}
+	}
+	if len(errs) > 0 {
+		log.Error().Msgf("%s: %v", config.InvalidBookAuthorsErrMsg, errs)
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, config.InvalidBookAuthorsErrMsg, errs...))
+		return false
+	}
+	return true
+}
+
+// isRole will check if the role is one of the author roles
+func isRole(role string) bool {
+	for _, r := range model.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// publishedFieldError will return the field error of the published date validation error
+func publishedFieldError(field string, err error) FieldError {
+	if errors.Is(err, pubdate.ErrRange) {
+		return FieldError{Field: field, Code: "date_range", Message: config.PublishedRangeErrMsg}
+	}
+	return FieldError{Field: field, Code: "date_format", Message: config.PublishedFormatErrMsg}
+}
+
 // WarnFieldsCannotBeEmpty will display warning for fields that did not get updates
 func WarnFieldsCannotBeEmpty(f []string) string {
 	if len(f) > 0 {
@@ -41,12 +236,76 @@ func ValidateRowsAffected(c *gin.Context, rowsAffected int64, msg string) {
 	}
 }
 
-// WarnEmptyData will display warning for no data to pass in query
+// ValidateBookFound will response with 404 and return false if no book was affected by the request
+func ValidateBookFound(c *gin.Context, rowsAffected int64) bool {
+	if rowsAffected == 0 {
+		AbortWithProblem(c, NewProblem(http.StatusNotFound, CodeNotFound, config.BookNotFoundErrMsg))
+		return false
+	}
+	return true
+}
+
+// WarnEmptyData will response with 422 for no data to pass in query
 func WarnEmptyData(c *gin.Context, f []string) bool {
 	if len(f) == 0 {
-		log.Warn().Msgf("%s %s", config.NoQueryDataPassedWarningMsg)
-		c.JSON(http.StatusOK, gin.H{"message": config.NoQueryDataPassedWarningMsg})
+		log.Warn().Msg(config.NoQueryDataPassedWarningMsg)
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, CodeEmptyFilter, config.NoQueryDataPassedWarningMsg))
 		return false
 	}
 	return true
 }
+
+// HandleDBError will response with the problem that match the storage error.
+// Missing book or author will return 404, duplicate isbn and deleting an author with books will return 409, query timeout will return 504 and canceled request will return 503,
+// not valid isbn or published of a reverted book will return 422,
+// others will return 500.
+// The driver error of an interrupted query like sqlite "interrupted (9)" is not a context error,
+// so the timeout and the cancel are also told by the error of the request context.
+func HandleDBError(c *gin.Context, op string, err error) {
+	log.Error().Msgf("%s failed: %s", op, err.Error())
+	ctxErr := c.Request.Context().Err()
+	switch {
+	case errors.Is(err, db.ErrDuplicateISBN):
+		AbortWithProblem(c, NewProblem(http.StatusConflict, CodeDuplicateISBN, config.DuplicateISBNErrMsg,
+			FieldError{Field: "isbn", Code: "unique", Message: config.DuplicateISBNErrMsg}))
+	case errors.Is(err, db.ErrVersionConflict):
+		AbortWithProblem(c, NewProblem(http.StatusPreconditionFailed, CodeVersionConflict, config.VersionConflictErrMsg))
+	case errors.Is(err, db.ErrBookNotFound):
+		AbortWithProblem(c, NewProblem(http.StatusNotFound, CodeNotFound, config.BookNotFoundErrMsg))
+	case errors.Is(err, db.ErrHistoryNotFound):
+		AbortWithProblem(c, NewProblem(http.StatusNotFound, CodeNotFound, config.HistoryNotFoundErrMsg))
+	case errors.Is(err, db.ErrInvalidISBN):
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, CodeInvalidISBN, config.InvalidISBNErrMsg,
+			FieldError{Field: "isbn", Code: "invalid_isbn", Message: err.Error()}))
+	case errors.Is(err, db.ErrInvalidPublished):
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, CodeInvalidPublished, config.InvalidPublishedErrMsg,
+			FieldError{Field: "published", Code: "invalid_published", Message: err.Error()}))
+	case errors.Is(err, db.ErrAuthorNotFound):
+		AbortWithProblem(c, NewProblem(http.StatusNotFound, CodeNotFound, config.AuthorNotFoundErrMsg))
+	case errors.Is(err, db.ErrAuthorHasBooks):
+		AbortWithProblem(c, NewProblem(http.StatusConflict, CodeAuthorHasBooks, config.AuthorHasBooksErrMsg))
+	case errors.Is(err, db.ErrDuplicateBookAuthor):
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, err.Error()))
+	case errors.Is(err, db.ErrPublisherNotFound):
+		AbortWithProblem(c, NewProblem(http.StatusNotFound, CodeNotFound, config.PublisherNotFoundErrMsg))
+	case errors.Is(err, db.ErrPublisherHasBooks):
+		AbortWithProblem(c, NewProblem(http.StatusConflict, CodePublisherHasBooks, config.PublisherHasBooksErrMsg))
+	case errors.Is(err, db.ErrDuplicatePublisher):
+		AbortWithProblem(c, NewProblem(http.StatusConflict, CodeDuplicatePublisher, err.Error()))
+	case errors.Is(err, db.ErrUnknownPublisher):
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed,
+			fmt.Sprintf("%s. %s", err.Error(), config.UnknownPublisherErrMsg)))
+	case errors.Is(err, db.ErrPublisherCycle), errors.Is(err, db.ErrSelfMerge):
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, err.Error()))
+	case errors.Is(err, db.ErrInvalidOrderBy), errors.Is(err, db.ErrInvalidFilter), errors.Is(err, db.ErrUnknownColumn):
+		AbortWithProblem(c, NewProblem(http.StatusBadRequest, CodeInvalidQuery, err.Error()))
+	case errors.Is(err, db.ErrEmptyFilter):
+		AbortWithProblem(c, NewProblem(http.StatusUnprocessableEntity, CodeEmptyFilter, config.NoQueryDataPassedWarningMsg))
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctxErr, context.DeadlineExceeded):
+		AbortWithProblem(c, NewProblem(http.StatusGatewayTimeout, CodeQueryTimeout, config.QueryTimeoutErrMsg))
+	case errors.Is(err, context.Canceled), errors.Is(ctxErr, context.Canceled):
+		AbortWithProblem(c, NewProblem(http.StatusServiceUnavailable, CodeRequestCanceled, config.QueryCanceledErrMsg))
+	default:
+		AbortWithProblem(c, NewProblem(http.StatusInternalServerError, CodeInternal, config.DBOperationErrMsg))
+	}
+}
@@ -0,0 +1,279 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goapp/pkg/model"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrAuthorNotFound is returned when there is no author with the author_id
+	ErrAuthorNotFound = errors.New("author not found")
+	// ErrAuthorHasBooks is returned when an author that is still credited for books is deleted
+	ErrAuthorHasBooks = errors.New("author has books")
+	// ErrDuplicateBookAuthor is returned when the book credits the same author with the same role more than once
+	ErrDuplicateBookAuthor = errors.New("book author is listed more than once with the same role")
+)
+
+// AuthorQuery to define the page of the authors, ordered by surname, name and author_id.
+// Only the authors with Name in their name or surname are listed when it is set.
+type AuthorQuery struct {
+	Name   string
+	Limit  int
+	OffSet int
+}
+
+// AuthorBooksQuery to define the author and the page of its books that are not deleted,
+// ordered by published date and book_id
+type AuthorBooksQuery struct {
+	AuthorID int
+	Limit    int
+	OffSet   int
+}
+
+// bookAuthors will return the book authors with the author role when there is none and the position in the list order,
+// or ErrDuplicateBookAuthor if an author is listed with the same role more than once
+func bookAuthors(authors []model.BookAuthor) ([]model.BookAuthor, error) {
+	credited := map[model.BookAuthor]bool{}
+	bas := make([]model.BookAuthor, len(authors))
+	for i, ba := range authors {
+		if ba.Role == "" {
+			ba.Role = model.RoleAuthor
+		}
+		key := model.BookAuthor{AuthorID: ba.AuthorID, Role: ba.Role}
+		if credited[key] {
+			return nil, fmt.Errorf("%w: author %d as %s", ErrDuplicateBookAuthor, ba.AuthorID, ba.Role)
+		}
+		credited[key] = true
+		ba.Position = i + 1
+		bas[i] = ba
+	}
+	return bas, nil
+}
+
+// ListAuthors will return the page of the authors, ordered by surname, name and author_id
+func (s sqlStorage) ListAuthors(ctx context.Context, q *AuthorQuery) ([]model.Author, error) {
+	query := "SELECT * FROM author"
+	var args []interface{}
+	if q.Name != "" {
+		query += fmt.Sprintf(` WHERE name %s ? ESCAPE '\' OR surname %s ? ESCAPE '\'`, s.like, s.like)
+		pattern := "%" + escapeLike(q.Name) + "%"
+		args = append(args, pattern, pattern)
+	}
+	query += " ORDER BY surname, name, author_id LIMIT ? OFFSET ?"
+	args = append(args, q.Limit, q.OffSet)
+	log.Debug().Msgf("ListAuthors: %s %v", query, args)
+	as := []model.Author{}
+	err := s.db.SelectContext(ctx, &as, s.db.Rebind(query), args...)
+	return as, err
+}
+
+// GetAuthor will return the author with the author_id or ErrAuthorNotFound if there is none
+func (s sqlStorage) GetAuthor(ctx context.Context, id int) (model.Author, error) {
+	var a model.Author
+	err := s.db.GetContext(ctx, &a, s.db.Rebind("SELECT * FROM author WHERE author_id = ?"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, ErrAuthorNotFound
+	}
+	return a, err
+}
+
+// InsertAuthor will insert the author and set its new author_id and updated_at
+func (s sqlStorage) InsertAuthor(ctx context.Context, a *model.Author) error {
+	query := "INSERT INTO author (name, surname, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP) RETURNING author_id, updated_at"
+	log.Debug().Msgf("InsertAuthor: %s %v", query, a)
+	return s.db.QueryRowxContext(ctx, s.db.Rebind(query), a.Name, a.Surname).Scan(&a.ID, &a.UpdatedAt)
+}
+
+// UpdateAuthor will set the name and surname of the author and the author_name and author_surname
+// of the books that credit it first, the books are changed with a new version and recorded in the book history.
+// It will return ErrAuthorNotFound if there is no author with the author_id.
+func (s sqlStorage) UpdateAuthor(ctx context.Context, a *model.Author) error {
+	query := "UPDATE author SET name = ?, surname = ?, updated_at = CURRENT_TIMESTAMP WHERE author_id = ?"
+	log.Debug().Msgf("UpdateAuthor: %s %v", query, a)
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, tx.Rebind(query), a.Name, a.Surname, a.ID)
+		n, err := rowsAffected(result, err)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrAuthorNotFound
+		}
+		var ids []int
+		query := "SELECT book_id FROM book_author WHERE author_id = ? AND position = 1 ORDER BY book_id"
+		if err = tx.SelectContext(ctx, &ids, tx.Rebind(query), a.ID); err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err = s.syncBookAuthor(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteAuthor will remove the author, it will return ErrAuthorNotFound if there is no author with the author_id
+// and ErrAuthorHasBooks if any book credit the author, the deleted books too
+func (s sqlStorage) DeleteAuthor(ctx context.Context, id int) error {
+	log.Debug().Msgf("DeleteAuthor: %d", id)
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		var n int
+		if err := tx.GetContext(ctx, &n, tx.Rebind("SELECT COUNT(*) FROM book_author WHERE author_id = ?"), id); err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrAuthorHasBooks
+		}
+		result, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM author WHERE author_id = ?"), id)
+		deleted, err := rowsAffected(result, err)
+		if err == nil && deleted == 0 {
+			err = ErrAuthorNotFound
+		}
+		return err
+	})
+}
+
+// GetBookAuthors will return the authors that the book credit in the credit order,
+// or ErrBookNotFound if there is no book with the book_id or it is deleted
+func (s sqlStorage) GetBookAuthors(ctx context.Context, bookID int) ([]model.BookAuthor, error) {
+	if _, err := s.GetBook(ctx, bookID, "book_id"); err != nil {
+		return nil, err
+	}
+	query := "SELECT ba.author_id, a.name, a.surname, ba.role, ba.position FROM book_author ba " +
+		"JOIN author a ON a.author_id = ba.author_id WHERE ba.book_id = ? ORDER BY ba.position, ba.role"
+	log.Debug().Msgf("GetBookAuthors: %s %d", query, bookID)
+	bas := []model.BookAuthor{}
+	err := s.db.SelectContext(ctx, &bas, s.db.Rebind(query), bookID)
+	return bas, err
+}
+
+// SetBookAuthors will replace the authors of the book with the authors in the credit order,
+// the author_name and author_surname of the book are set to the first author.
+// It will return ErrBookNotFound if there is no book with the book_id or it is deleted,
+// ErrAuthorNotFound if any of the authors is not there and ErrDuplicateBookAuthor if an author is listed twice with the same role.
+func (s sqlStorage) SetBookAuthors(ctx context.Context, bookID int, authors []model.BookAuthor) error {
+	bas, err := bookAuthors(authors)
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("SetBookAuthors: %d %v", bookID, bas)
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		bk, err := s.snapshotBook(ctx, tx, bookID)
+		if err != nil {
+			return err
+		}
+		if bk == nil || bk.DeletedAt != nil {
+			return ErrBookNotFound
+		}
+		for _, ba := range bas {
+			var n int
+			if err = tx.GetContext(ctx, &n, tx.Rebind("SELECT COUNT(*) FROM author WHERE author_id = ?"), ba.AuthorID); err != nil {
+				return err
+			}
+			if n == 0 {
+				return fmt.Errorf("%w: author_id %d", ErrAuthorNotFound, ba.AuthorID)
+			}
+		}
+		if _, err = tx.ExecContext(ctx, tx.Rebind("DELETE FROM book_author WHERE book_id = ?"), bookID); err != nil {
+			return err
+		}
+		query := "INSERT INTO book_author (book_id, author_id, role, position) VALUES (?, ?, ?, ?)"
+		for _, ba := range bas {
+			if _, err = tx.ExecContext(ctx, tx.Rebind(query), bookID, ba.AuthorID, ba.Role, ba.Position); err != nil {
+				return err
+			}
+		}
+		return s.syncBookAuthor(ctx, tx, bookID)
+	})
+}
+
+// ListAuthorBooks will return the page of the books that credit the author and are not deleted,
+// ordered by published date and book_id. It will return ErrAuthorNotFound if there is no author with the author_id.
+func (s sqlStorage) ListAuthorBooks(ctx context.Context, q *AuthorBooksQuery) ([]model.AuthorBook, error) {
+	if _, err := s.GetAuthor(ctx, q.AuthorID); err != nil {
+		return nil, err
+	}
+	query := "SELECT b.*, ba.role, ba.position FROM book_author ba JOIN book b ON b.book_id = ba.book_id " +
+		"WHERE ba.author_id = ? AND b." + notDeleted + " ORDER BY b.published, b.book_id, ba.role LIMIT ? OFFSET ?"
+	log.Debug().Msgf("ListAuthorBooks: %s %v", query, q)
+	abs := []model.AuthorBook{}
+	err := s.db.SelectContext(ctx, &abs, s.db.Rebind(query), q.AuthorID, q.Limit, q.OffSet)
+	return abs, err
+}
+
+// syncBookAuthor will set the author_name and author_surname of the book to its first author
+// with a new version that is recorded in the book history, the book is left as it is when it has no author
+func (s sqlStorage) syncBookAuthor(ctx context.Context, tx *sqlx.Tx, bookID int) error {
+	before, err := s.snapshotBook(ctx, tx, bookID)
+	if err != nil || before == nil {
+		return err
+	}
+	var a model.Author
+	query := "SELECT a.* FROM author a JOIN book_author ba ON ba.author_id = a.author_id " +
+		"WHERE ba.book_id = ? ORDER BY ba.position, ba.role LIMIT 1"
+	err = tx.GetContext(ctx, &a, tx.Rebind(query), bookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if before.AuthorName == a.Name && before.AuthorSurname == a.Surname {
+		return nil
+	}
+	query = "UPDATE book SET author_name = ?, author_surname = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP " +
+		"WHERE book_id = ?"
+	if _, err = tx.ExecContext(ctx, tx.Rebind(query), a.Name, a.Surname, bookID); err != nil {
+		return err
+	}
+	after, err := s.snapshotBook(ctx, tx, bookID)
+	if err != nil {
+		return err
+	}
+	return recordHistory(ctx, tx, model.HistoryAuthors, before, after)
+}
+
+// linkBookAuthor will credit the author of the book author_name and author_surname first with the role
+// of the author it replace, the author is created when there is no author with the same name and surname
+func (s sqlStorage) linkBookAuthor(ctx context.Context, tx *sqlx.Tx, bk *model.Book) error {
+	var authorID int
+	query := "SELECT author_id FROM author WHERE name = ? AND surname = ? ORDER BY author_id LIMIT 1"
+	err := tx.GetContext(ctx, &authorID, tx.Rebind(query), bk.AuthorName, bk.AuthorSurname)
+	if errors.Is(err, sql.ErrNoRows) {
+		query = "INSERT INTO author (name, surname, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP) RETURNING author_id"
+		err = tx.QueryRowxContext(ctx, tx.Rebind(query), bk.AuthorName, bk.AuthorSurname).Scan(&authorID)
+	}
+	if err != nil {
+		return err
+	}
+
+	first := model.BookAuthor{Role: model.RoleAuthor}
+	query = "SELECT author_id, role FROM book_author WHERE book_id = ? AND position = 1 ORDER BY role LIMIT 1"
+	err = tx.QueryRowxContext(ctx, tx.Rebind(query), bk.ID).Scan(&first.AuthorID, &first.Role)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if first.AuthorID == authorID {
+		return nil
+	}
+	query = "DELETE FROM book_author WHERE book_id = ? AND (position = 1 OR (author_id = ? AND role = ?))"
+	if _, err = tx.ExecContext(ctx, tx.Rebind(query), bk.ID, authorID, first.Role); err != nil {
+		return err
+	}
+	query = "INSERT INTO book_author (book_id, author_id, role, position) VALUES (?, ?, ?, 1)"
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), bk.ID, authorID, first.Role)
+	return err
+}
+
+// authorChanged will check if the author_name or author_surname of the book is changed
+func authorChanged(before, after *model.Book) bool {
+	return before != nil && after != nil &&
+		(before.AuthorName != after.AuthorName || before.AuthorSurname != after.AuthorSurname)
+}
@@ -0,0 +1,1497 @@
+// Package dbtest provide the conformance suite that every db.Storage implementation need to pass,
+// so the storage backends keep the same behaviour.
+//
+// A backend test only need to provide a factory that return a new empty storage:
+//
+//	func TestSqliteStorage(t *testing.T) {
+//		dbtest.RunStorageSuite(t, func(t *testing.T) db.Storage {
+//			s, err := db.OpenSqliteStorage(filepath.Join(t.TempDir(), "book.db"))
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			t.Cleanup(s.CloseDB)
+//			if err = s.MigrateUp(); err != nil {
+//				t.Fatal(err)
+//			}
+//			return s
+//		})
+//	}
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapp/pkg/db"
+	"goapp/pkg/model"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Factory will return a new empty storage for every test case
+type Factory func(t *testing.T) db.Storage
+
+// Books is the data set that every test case start with, the book_id will be 1 to 5 in the same order
+// and the publisher_id of their publishers 1 to 4 in the order that the publishers first appear
+var Books = []model.Book{
+	{ISBN: "9780451524935", Title: "Nineteen Eighty-Four", AuthorName: "George", AuthorSurname: "Orwell",
+		Published: "1949", Publisher: "Secker & Warburg"},
+	{ISBN: "9780451526342", Title: "Animal Farm", AuthorName: "George", AuthorSurname: "Orwell",
+		Published: "1945", Publisher: "Secker & Warburg"},
+	{ISBN: "9780743273565", Title: "The Great Gatsby", AuthorName: "Francis Scott", AuthorSurname: "Fitzgerald",
+		Published: "1925", Publisher: "Charles Scribner's Sons"},
+	{ISBN: "9780140714715", Title: "The Winter's Tale", AuthorName: "William", AuthorSurname: "Shakespeare",
+		Published: "1623", Publisher: "Penguin Classics"},
+	{ISBN: "9780062316097", Title: "Sapiens", AuthorName: "Yuval Noah", AuthorSurname: "Harari",
+		Published: "2011", Publisher: "Harper"},
+}
+
+// RunStorageSuite will run every contract test case against a new storage from the factory
+func RunStorageSuite(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(ctx context.Context, t *testing.T, s db.Storage)
+	}{
+		{"ListBooksOrder", testListBooksOrder},
+		{"ListBooksMultiOrder", testListBooksMultiOrder},
+		{"ListBooksPagination", testListBooksPagination},
+		{"ListBooksEmpty", testListBooksEmpty},
+		{"ListBooksKeyset", testListBooksKeyset},
+		{"ListBooksFilter", testListBooksFilter},
+		{"ListBooksPublished", testListBooksPublished},
+		{"CountBooks", testCountBooks},
+		{"GetBooksMatchAll", testGetBooksMatchAll},
+		{"GetBooksMatchAny", testGetBooksMatchAny},
+		{"GetBooksEmptyFilter", testGetBooksEmptyFilter},
+		{"GetBooksNoMatch", testGetBooksNoMatch},
+		{"GetBook", testGetBook},
+		{"SelectColumns", testSelectColumns},
+		{"GetBookNotFound", testGetBookNotFound},
+		{"SearchBooks", testSearchBooks},
+		{"SearchBooksBoost", testSearchBooksBoost},
+		{"SearchBooksSync", testSearchBooksSync},
+		{"InsertBooks", testInsertBooks},
+		{"InsertBooksDuplicateISBN", testInsertBooksDuplicateISBN},
+		{"UpdateBooks", testUpdateBooks},
+		{"UpdateBooksDuplicateISBN", testUpdateBooksDuplicateISBN},
+		{"PatchBooks", testPatchBooks},
+		{"PatchBooksDuplicateISBN", testPatchBooksDuplicateISBN},
+		{"UpdateBooksVersion", testUpdateBooksVersion},
+		{"PatchBooksVersion", testPatchBooksVersion},
+		{"DeleteBooks", testDeleteBooks},
+		{"DeletedBooksHidden", testDeletedBooksHidden},
+		{"RestoreBooks", testRestoreBooks},
+		{"PurgeBooks", testPurgeBooks},
+		{"BookHistory", testBookHistory},
+		{"RevertBook", testRevertBook},
+		{"Authors", testAuthors},
+		{"BookAuthors", testBookAuthors},
+		{"BookAuthorsSync", testBookAuthorsSync},
+		{"Publishers", testPublishers},
+		{"PublisherBooks", testPublisherBooks},
+		{"MergePublishers", testMergePublishers},
+		{"CanceledContext", testCanceledContext},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := factory(t)
+			seed(ctx, t, s)
+			tc.test(ctx, t, s)
+		})
+	}
+}
+
+// seed will insert the Books data set and fail the test if the storage does not accept it
+func seed(ctx context.Context, t *testing.T, s db.Storage) {
+	t.Helper()
+	ids, err := insertIDs(s.InsertBooks(ctx, Books, db.AllOrNothing))
+	if err != nil {
+		t.Fatalf("seed InsertBooks failed: %s", err)
+	}
+	if fmt.Sprint(ids) != "[1 2 3 4 5]" {
+		t.Fatalf("seed InsertBooks ids = %v, want [1 2 3 4 5]", ids)
+	}
+}
+
+func testListBooksOrder(ctx context.Context, t *testing.T, s db.Storage) {
+	bks := mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("book_id"), Limit: 25})
+	assertIDs(t, bks, 1, 2, 3, 4, 5)
+
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("title"), Limit: 25})
+	assertIDs(t, bks, 2, 1, 5, 3, 4)
+
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("published"), Limit: 25})
+	assertIDs(t, bks, 4, 3, 2, 1, 5)
+
+	want := Books[0]
+	want.ID, want.Version, want.PublisherID = 1, 1, 1
+	assertBook(t, "ListBooks", bks[3], want)
+}
+
+func testListBooksMultiOrder(ctx context.Context, t *testing.T, s db.Storage) {
+	bks := mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("author_surname,-published,title"), Limit: 25})
+	assertIDs(t, bks, 3, 5, 1, 2, 4)
+
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("-author_surname,published"), Limit: 25})
+	assertIDs(t, bks, 4, 2, 1, 5, 3)
+
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("-book_id"), Limit: 2, OffSet: 1})
+	assertIDs(t, bks, 4, 3)
+
+	// keyset with mixed directions
+	order := orderBy("author_surname,-published")
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: order, Limit: 25,
+		Keyset: &db.Keyset{Values: []string{"Orwell", "1949"}, ID: 1}})
+	assertIDs(t, bks, 2, 4)
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: order, Limit: 25,
+		Keyset: &db.Keyset{Values: []string{"Orwell", "1945"}, ID: 2, Before: true}})
+	assertIDs(t, bks, 3, 5, 1)
+
+	_, err := s.ListBooks(ctx, &db.PageList{OrderBy: []db.SortField{{Column: "title; DROP TABLE book"}}, Limit: 25})
+	if !errors.Is(err, db.ErrInvalidOrderBy) {
+		t.Errorf("ListBooks unknown column error = %v, want %v", err, db.ErrInvalidOrderBy)
+	}
+	_, err = s.ListBooks(ctx, &db.PageList{OrderBy: order, Limit: 25, Keyset: &db.Keyset{Values: []string{"Orwell"}, ID: 1}})
+	if !errors.Is(err, db.ErrInvalidOrderBy) {
+		t.Errorf("ListBooks keyset without every value error = %v, want %v", err, db.ErrInvalidOrderBy)
+	}
+}
+
+func testListBooksPagination(ctx context.Context, t *testing.T, s db.Storage) {
+	bks := mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("book_id"), Limit: 2, OffSet: 0})
+	assertIDs(t, bks, 1, 2)
+
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("book_id"), Limit: 2, OffSet: 2})
+	assertIDs(t, bks, 3, 4)
+
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("book_id"), Limit: 2, OffSet: 4})
+	assertIDs(t, bks, 5)
+}
+
+func testListBooksEmpty(ctx context.Context, t *testing.T, s db.Storage) {
+	bks := mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("book_id"), Limit: 25, OffSet: 100})
+	if bks == nil || len(bks) != 0 {
+		t.Errorf("ListBooks past the last page = %#v, want empty slice", bks)
+	}
+}
+
+func testListBooksKeyset(ctx context.Context, t *testing.T, s db.Storage) {
+	// by book_id the keyset value is ignored
+	bks := mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("book_id"), Limit: 2, Keyset: &db.Keyset{ID: 3}})
+	assertIDs(t, bks, 4, 5)
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("book_id"), Limit: 2, Keyset: &db.Keyset{ID: 3, Before: true}})
+	assertIDs(t, bks, 1, 2)
+
+	// by author_surname: Fitzgerald 3, Harari 5, Orwell 1, Orwell 2, Shakespeare 4
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("author_surname"), Limit: 2,
+		Keyset: &db.Keyset{Values: []string{"Orwell"}, ID: 1}})
+	assertIDs(t, bks, 2, 4)
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("author_surname"), Limit: 25,
+		Keyset: &db.Keyset{Values: []string{"Orwell"}, ID: 2, Before: true}})
+	assertIDs(t, bks, 3, 5, 1)
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("author_surname"), Limit: 2,
+		Keyset: &db.Keyset{Values: []string{"Orwell"}, ID: 2, Before: true}})
+	assertIDs(t, bks, 5, 1)
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("author_surname"), Limit: 25,
+		Keyset: &db.Keyset{Values: []string{"Shakespeare"}, ID: 4}})
+	assertIDs(t, bks, []int{}...)
+
+	// a book inserted before the keyset does not shift the next page
+	_, err := s.InsertBooks(ctx, []model.Book{{ISBN: "9780000000006", Title: "Homage to Catalonia",
+		AuthorName: "George", AuthorSurname: "Orwell", Published: "1938", Publisher: "Secker & Warburg"}}, db.AllOrNothing)
+	if err != nil {
+		t.Fatalf("InsertBooks failed: %s", err)
+	}
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("author_surname"), Limit: 25,
+		Keyset: &db.Keyset{Values: []string{"Harari"}, ID: 5}})
+	assertIDs(t, bks, 1, 2, 6, 4)
+
+	// the keyset of a listed book continue right after it
+	k := db.KeysetOf(&bks[1], orderBy("author_surname"), false)
+	bks = mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("author_surname"), Limit: 1, Keyset: k})
+	assertIDs(t, bks, 6)
+}
+
+func testListBooksFilter(ctx context.Context, t *testing.T, s db.Storage) {
+	tests := []struct {
+		params url.Values
+		want   []int
+	}{
+		{url.Values{"author_surname": {"Orwell"}}, []int{1, 2}},
+		{url.Values{"author_surname": {"eq:Orwell"}, "published": {"gte:1946"}}, []int{1}},
+		{url.Values{"author_surname": {"ne:Orwell"}}, []int{3, 4, 5}},
+		{url.Values{"published": {"gt:1925", "lt:2000"}}, []int{1, 2}},
+		{url.Values{"book_id": {"lte:2"}}, []int{1, 2}},
+		{url.Values{"title": {"contains:WINTER"}}, []int{4}},
+		{url.Values{"publisher": {"starts:secker"}}, []int{1, 2}},
+		{url.Values{"any.title": {"contains:farm", "contains:gatsby"}}, []int{2, 3}},
+		{url.Values{"any.title": {"contains:farm"}, "any.author_surname": {"Harari"}, "published": {"gt:1946"}}, []int{5}},
+		{url.Values{"a.author_surname": {"Orwell", "Harari"}, "b.published": {"1945", "2011"}}, []int{2, 5}},
+		// the like wildcards are matched as they are
+		{url.Values{"title": {"contains:%"}}, []int{}},
+		{url.Values{"title": {"contains:_"}}, []int{}},
+		{url.Values{"title": {"Re:Zero"}}, []int{}},
+	}
+	for _, tt := range tests {
+		f, err := db.ParseFilter(tt.params)
+		if err != nil {
+			t.Fatalf("ParseFilter(%v) failed: %s", tt.params, err)
+		}
+		bks := mustList(ctx, t, s, &db.PageList{Filter: f, OrderBy: orderBy("book_id"), Limit: 25})
+		assertIDs(t, bks, tt.want...)
+		n, err := s.CountBooks(ctx, f)
+		if err != nil || n != len(tt.want) {
+			t.Errorf("CountBooks(%v) = %d, %v, want %d", tt.params, n, err, len(tt.want))
+		}
+	}
+
+	// filter with keyset and offset pages
+	f, _ := db.ParseFilter(url.Values{"author_surname": {"Orwell"}})
+	bks := mustList(ctx, t, s, &db.PageList{Filter: f, OrderBy: orderBy("book_id"), Limit: 25, Keyset: &db.Keyset{ID: 1}})
+	assertIDs(t, bks, 2)
+	bks = mustList(ctx, t, s, &db.PageList{Filter: f, OrderBy: orderBy("-book_id"), Limit: 1, OffSet: 1})
+	assertIDs(t, bks, 1)
+
+	bad := &db.Filter{Conditions: []db.Condition{{Column: "title = title OR 1", Op: db.OpEq, Value: "x"}}}
+	if _, err := s.ListBooks(ctx, &db.PageList{Filter: bad, OrderBy: orderBy("book_id"), Limit: 25}); !errors.Is(err, db.ErrInvalidFilter) {
+		t.Errorf("ListBooks unknown filter column error = %v, want %v", err, db.ErrInvalidFilter)
+	}
+	if _, err := s.CountBooks(ctx, bad); !errors.Is(err, db.ErrInvalidFilter) {
+		t.Errorf("CountBooks unknown filter column error = %v, want %v", err, db.ErrInvalidFilter)
+	}
+}
+
+func testListBooksPublished(ctx context.Context, t *testing.T, s db.Storage) {
+	_, err := s.InsertBooks(ctx, []model.Book{
+		{ISBN: "9780141036144", Title: "Nineteen Eighty-Four", AuthorName: "George", AuthorSurname: "Orwell",
+			Published: "1949-06-08", Publisher: "Secker & Warburg"},
+		{ISBN: "9780141036137", Title: "Animal Farm", AuthorName: "George", AuthorSurname: "Orwell",
+			Published: "1945-08", Publisher: "Secker & Warburg"},
+	}, db.AllOrNothing)
+	if err != nil {
+		t.Fatalf("InsertBooks failed: %s", err)
+	}
+	bks := mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("published,book_id"), Limit: 25})
+	assertIDs(t, bks, 4, 3, 2, 7, 1, 6, 5)
+
+	// the value is compared with its period, 1949 is every date of 1949
+	tests := []struct {
+		params url.Values
+		want   []int
+	}{
+		{url.Values{"published": {"1949"}}, []int{1, 6}},
+		{url.Values{"published": {"eq:June 1949"}}, []int{6}},
+		{url.Values{"published": {"ne:1945"}}, []int{1, 3, 4, 5, 6}},
+		{url.Values{"published": {"gt:1945-07"}}, []int{1, 5, 6, 7}},
+		{url.Values{"published": {"gte:1945-08-01"}}, []int{1, 5, 6}},
+		// a partial date is ordered at the start of its period so 1949 is before 1949-06
+		{url.Values{"published": {"lt:1949-06"}}, []int{1, 2, 3, 4, 7}},
+		{url.Values{"published": {"lte:1945"}}, []int{2, 3, 4, 7}},
+		{url.Values{"published": {"gte:1945", "lte:1949-06"}}, []int{1, 2, 6, 7}},
+		{url.Values{"published": {"starts:1949"}}, []int{1, 6}},
+	}
+	for _, tt := range tests {
+		f, err := db.ParseFilter(tt.params)
+		if err != nil {
+			t.Fatalf("ParseFilter(%v) failed: %s", tt.params, err)
+		}
+		assertIDs(t, mustList(ctx, t, s, &db.PageList{Filter: f, OrderBy: orderBy("book_id"), Limit: 25}), tt.want...)
+	}
+	if _, err := db.ParseFilter(url.Values{"published": {"gt:1949-13"}}); !errors.Is(err, db.ErrInvalidFilter) {
+		t.Errorf("ParseFilter invalid published error = %v, want %v", err, db.ErrInvalidFilter)
+	}
+}
+
+func testCountBooks(ctx context.Context, t *testing.T, s db.Storage) {
+	n, err := s.CountBooks(ctx, nil)
+	if err != nil || n != len(Books) {
+		t.Errorf("CountBooks = %d, %v, want %d", n, err, len(Books))
+	}
+
+	_, err = s.DeleteBooks(ctx, 1)
+	if err != nil {
+		t.Fatalf("DeleteBooks failed: %s", err)
+	}
+	n, err = s.CountBooks(ctx, nil)
+	if err != nil || n != len(Books)-1 {
+		t.Errorf("CountBooks after delete = %d, %v, want %d", n, err, len(Books)-1)
+	}
+}
+
+func testGetBooksMatchAll(ctx context.Context, t *testing.T, s db.Storage) {
+	bks := mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{AuthorSurname: "Orwell"}, Mode: db.MatchAll})
+	assertIDs(t, bks, 1, 2)
+
+	bks = mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{AuthorSurname: "Orwell", Published: "1945"}, Mode: db.MatchAll})
+	assertIDs(t, bks, 2)
+
+	bks = mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{ID: 4, Title: "The Winter's Tale"}, Mode: db.MatchAll})
+	assertIDs(t, bks, 4)
+
+	// exact match does not match part of the value
+	bks = mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{AuthorSurname: "Orw"}, Mode: db.MatchAll})
+	assertIDs(t, bks)
+}
+
+func testGetBooksMatchAny(ctx context.Context, t *testing.T, s db.Storage) {
+	bks := mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{Title: "the"}, Mode: db.MatchAny})
+	assertIDs(t, bks, 3, 4)
+
+	bks = mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{Title: "farm", Publisher: "harper"}, Mode: db.MatchAny})
+	assertIDs(t, bks, 2, 5)
+
+	bks = mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{Title: "winter's"}, Mode: db.MatchAny})
+	assertIDs(t, bks, 4)
+
+	// the LIKE wildcards are matched as they are
+	for _, title := range []string{"%", "the_great", `the\%`} {
+		bks = mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{Title: title}, Mode: db.MatchAny})
+		assertIDs(t, bks)
+	}
+}
+
+func testGetBooksEmptyFilter(ctx context.Context, t *testing.T, s db.Storage) {
+	for _, mode := range []db.FilterMode{db.MatchAll, db.MatchAny} {
+		if _, err := s.GetBooks(ctx, &db.BookFilter{Mode: mode}); !errors.Is(err, db.ErrEmptyFilter) {
+			t.Errorf("GetBooks mode %d with empty filter error = %v, want %v", mode, err, db.ErrEmptyFilter)
+		}
+	}
+}
+
+func testGetBooksNoMatch(ctx context.Context, t *testing.T, s db.Storage) {
+	for _, mode := range []db.FilterMode{db.MatchAll, db.MatchAny} {
+		bks := mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{Title: "no such title"}, Mode: mode})
+		if bks == nil || len(bks) != 0 {
+			t.Errorf("GetBooks mode %d without match = %#v, want empty slice", mode, bks)
+		}
+	}
+}
+
+func testGetBook(ctx context.Context, t *testing.T, s db.Storage) {
+	want := Books[3]
+	want.ID, want.Version, want.PublisherID = 4, 1, 3
+	bk, err := s.GetBook(ctx, 4)
+	if err != nil {
+		t.Fatalf("GetBook failed: %s", err)
+	}
+	assertBook(t, "GetBook", bk, want)
+}
+
+func testSelectColumns(ctx context.Context, t *testing.T, s db.Storage) {
+	cols := []string{"book_id", "title"}
+	want := model.Book{ID: 2, Title: Books[1].Title}
+
+	bks := mustList(ctx, t, s, &db.PageList{Columns: cols, OrderBy: orderBy("book_id"), Limit: 2, OffSet: 1})
+	if len(bks) != 2 || !reflect.DeepEqual(bks[0], want) {
+		t.Errorf("ListBooks with columns = %+v, want first book %+v", bks, want)
+	}
+	bks = mustList(ctx, t, s, &db.PageList{Columns: []string{"title", "book_id"}, OrderBy: orderBy("book_id"), Limit: 25,
+		Keyset: &db.Keyset{ID: 1}})
+	if len(bks) != 4 || !reflect.DeepEqual(bks[0], want) {
+		t.Errorf("ListBooks keyset with columns = %+v, want first book %+v", bks, want)
+	}
+
+	bks = mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{Title: "Animal Farm"}, Mode: db.MatchAll, Columns: cols})
+	if len(bks) != 1 || !reflect.DeepEqual(bks[0], want) {
+		t.Errorf("GetBooks with columns = %+v, want %+v", bks, want)
+	}
+
+	bk, err := s.GetBook(ctx, 2, cols...)
+	if err != nil || !reflect.DeepEqual(bk, want) {
+		t.Errorf("GetBook with columns = %+v, %v, want %+v", bk, err, want)
+	}
+
+	terms, _ := db.ParseSearchQuery("animal")
+	results, err := s.SearchBooks(ctx, &db.SearchQuery{Columns: cols, Terms: terms, Limit: 25})
+	if err != nil || len(results) != 1 || !reflect.DeepEqual(results[0].Book, want) || results[0].Snippet == "" {
+		t.Errorf("SearchBooks with columns = %+v, %v, want %+v with snippet", results, err, want)
+	}
+
+	bad := []string{"title", "password"}
+	errs := map[string]error{}
+	_, errs["ListBooks"] = s.ListBooks(ctx, &db.PageList{Columns: bad, OrderBy: orderBy("book_id"), Limit: 25})
+	_, errs["GetBooks"] = s.GetBooks(ctx, &db.BookFilter{Book: model.Book{ID: 1}, Mode: db.MatchAll, Columns: bad})
+	_, errs["GetBook"] = s.GetBook(ctx, 1, bad...)
+	_, errs["SearchBooks"] = s.SearchBooks(ctx, &db.SearchQuery{Columns: bad, Terms: terms, Limit: 25})
+	for op, err := range errs {
+		if !errors.Is(err, db.ErrUnknownColumn) {
+			t.Errorf("%s with unknown column error = %v, want %v", op, err, db.ErrUnknownColumn)
+		}
+	}
+}
+
+func testGetBookNotFound(ctx context.Context, t *testing.T, s db.Storage) {
+	if _, err := s.GetBook(ctx, 100); !errors.Is(err, db.ErrBookNotFound) {
+		t.Errorf("GetBook missing book error = %v, want %v", err, db.ErrBookNotFound)
+	}
+}
+
+func testSearchBooks(ctx context.Context, t *testing.T, s db.Storage) {
+	tests := []struct {
+		q    string
+		want []int
+	}{
+		{"orwell", []int{1, 2}},
+		{"George ANIMAL", []int{2}},
+		{`"animal farm"`, []int{2}},
+		{`"farm animal"`, []int{}},
+		{"shakesp*", []int{4}},
+		{"shakesp", []int{}},
+		{`"great gats"*`, []int{3}},
+		{"winter's", []int{4}},
+		{"9780062316097", []int{5}},
+	}
+	for _, tt := range tests {
+		// the rank of the equally matching books depend on the backend, so only the matched books are compared
+		results := mustSearch(ctx, t, s, tt.q, "", 25, 0)
+		sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+		assertSearchIDs(t, tt.q, results, tt.want...)
+	}
+
+	results := mustSearch(ctx, t, s, "gatsby", "", 25, 0)
+	if len(results) != 1 || results[0].Snippet != "The Great "+db.HighlightStart+"Gatsby"+db.HighlightEnd {
+		t.Errorf("SearchBooks snippet = %+v, want the highlighted title", results)
+	} else if want := Books[2]; results[0].Book.ISBN != want.ISBN || results[0].Rank <= 0 {
+		t.Errorf("SearchBooks result = %+v, want book %+v with positive rank", results[0], want)
+	}
+
+	// pages of the books that match "the"
+	page1 := mustSearch(ctx, t, s, "the", "", 1, 0)
+	page2 := mustSearch(ctx, t, s, "the", "", 1, 1)
+	page3 := mustSearch(ctx, t, s, "the", "", 1, 2)
+	if len(page1) != 1 || len(page2) != 1 || len(page3) != 0 || page1[0].ID == page2[0].ID {
+		t.Errorf("SearchBooks pages = %v, %v, %v, want two different books and an empty page", page1, page2, page3)
+	}
+
+	if _, err := s.SearchBooks(ctx, &db.SearchQuery{Limit: 25}); !errors.Is(err, db.ErrEmptySearch) {
+		t.Errorf("SearchBooks without terms error = %v, want %v", err, db.ErrEmptySearch)
+	}
+
+	// the snippet is HTML escaped so only the highlight markers are tags
+	n, err := s.PatchBooks(ctx, &model.PatchBook{ID: 3, Title: "The <b>Great</b> & Gatsby"})
+	assertRowsAffected(t, "PatchBooks", n, err, 1)
+	results = mustSearch(ctx, t, s, "gatsby", "", 25, 0)
+	want := "The &lt;b&gt;Great&lt;/b&gt; &amp; " + db.HighlightStart + "Gatsby" + db.HighlightEnd
+	if len(results) != 1 || !strings.Contains(results[0].Snippet, want) {
+		t.Errorf("SearchBooks snippet = %+v, want the escaped title %q", results, want)
+	}
+}
+
+func testSearchBooksBoost(ctx context.Context, t *testing.T, s db.Storage) {
+	// harper is the title of the new book and the publisher of Sapiens
+	ids, err := insertIDs(s.InsertBooks(ctx, []model.Book{{ISBN: "9780000000006", Title: "Harper Valley",
+		AuthorName: "Tom", AuthorSurname: "Hall", Published: "1968", Publisher: "Plantation"}}, db.AllOrNothing))
+	assertInsertedIDs(t, "InsertBooks", ids, err, 6)
+
+	results := mustSearch(ctx, t, s, "harper", "title:10,publisher:1", 25, 0)
+	assertSearchIDs(t, "harper boosted title", results, 6, 5)
+	results = mustSearch(ctx, t, s, "harper", "title:1,publisher:10", 25, 0)
+	assertSearchIDs(t, "harper boosted publisher", results, 5, 6)
+}
+
+func testSearchBooksSync(ctx context.Context, t *testing.T, s db.Storage) {
+	n, err := s.PatchBooks(ctx, &model.PatchBook{ID: 3, Title: "Trimalchio in West Egg"})
+	assertRowsAffected(t, "PatchBooks", n, err, 1)
+	assertSearchIDs(t, "old title", mustSearch(ctx, t, s, "gatsby", "", 25, 0))
+	assertSearchIDs(t, "new title", mustSearch(ctx, t, s, "trimalchio", "", 25, 0), 3)
+
+	n, err = s.DeleteBooks(ctx, 3)
+	assertRowsAffected(t, "DeleteBooks", n, err, 1)
+	assertSearchIDs(t, "deleted book", mustSearch(ctx, t, s, "trimalchio", "", 25, 0))
+	n, err = s.PurgeBooks(ctx, -time.Minute)
+	assertRowsAffected(t, "PurgeBooks", n, err, 1)
+
+	ids, err := insertIDs(s.InsertBooks(ctx, []model.Book{Books[2]}, db.AllOrNothing))
+	assertInsertedIDs(t, "InsertBooks", ids, err, 6)
+	assertSearchIDs(t, "inserted book", mustSearch(ctx, t, s, "gatsby", "", 25, 0), 6)
+}
+
+func testInsertBooks(ctx context.Context, t *testing.T, s db.Storage) {
+	bk := model.Book{ISBN: "9780141439518", Title: "Pride and Prejudice", AuthorName: "Jane",
+		AuthorSurname: "Austen", Published: "1813", Publisher: "T. Egerton"}
+	ids, err := insertIDs(s.InsertBooks(ctx, []model.Book{bk}, db.AllOrNothing))
+	assertInsertedIDs(t, "InsertBooks", ids, err, 6)
+
+	bk.ID, bk.Version, bk.PublisherID = 6, 1, 5
+	bks := mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{ISBN: bk.ISBN}, Mode: db.MatchAll})
+	if len(bks) != 1 {
+		t.Fatalf("inserted book = %+v, want %+v", bks, bk)
+	}
+	assertBook(t, "InsertBooks", bks[0], bk)
+
+	ids, err = insertIDs(s.InsertBooks(ctx, nil, db.BestEffort))
+	assertInsertedIDs(t, "InsertBooks without books", ids, err)
+}
+
+func testInsertBooksDuplicateISBN(ctx context.Context, t *testing.T, s db.Storage) {
+	bks := []model.Book{
+		{ISBN: "9780141439518", Title: "Pride and Prejudice", AuthorName: "Jane", AuthorSurname: "Austen",
+			Published: "1813", Publisher: "T. Egerton"},
+		{ISBN: Books[0].ISBN, Title: "Duplicate", AuthorName: "A", AuthorSurname: "B", Published: "2000",
+			Publisher: "C"},
+		{ISBN: "9780141439518", Title: "Duplicate in batch", AuthorName: "A", AuthorSurname: "B",
+			Published: "2000", Publisher: "C"},
+	}
+
+	// AllOrNothing report every failed book and insert none of them
+	results, err := s.InsertBooks(ctx, bks, db.AllOrNothing)
+	if !errors.Is(err, db.ErrBatchRolledBack) {
+		t.Errorf("InsertBooks AllOrNothing error = %v, want %v", err, db.ErrBatchRolledBack)
+	}
+	assertInsertResults(t, "InsertBooks AllOrNothing", results, false, false, false)
+	for i, want := range []error{nil, db.ErrDuplicateISBN, db.ErrDuplicateISBN} {
+		if !errors.Is(results[i].Err, want) {
+			t.Errorf("InsertBooks AllOrNothing result[%d] error = %v, want %v", i, results[i].Err, want)
+		}
+	}
+	got := mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{ISBN: bks[0].ISBN}, Mode: db.MatchAll})
+	assertIDs(t, got)
+
+	// BestEffort insert the books that do not fail
+	results, err = s.InsertBooks(ctx, bks, db.BestEffort)
+	if err != nil {
+		t.Fatalf("InsertBooks BestEffort failed: %s", err)
+	}
+	assertInsertResults(t, "InsertBooks BestEffort", results, true, false, false)
+	for i := 1; i < len(results); i++ {
+		if !errors.Is(results[i].Err, db.ErrDuplicateISBN) {
+			t.Errorf("InsertBooks BestEffort result[%d] error = %v, want %v", i, results[i].Err, db.ErrDuplicateISBN)
+		}
+	}
+	got = mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{ISBN: bks[0].ISBN}, Mode: db.MatchAll})
+	if len(got) != 1 || got[0].ID != results[0].ID || got[0].Title != bks[0].Title {
+		t.Errorf("InsertBooks BestEffort inserted = %+v, want book_id %d", got, results[0].ID)
+	}
+}
+
+func testUpdateBooks(ctx context.Context, t *testing.T, s db.Storage) {
+	bk := Books[1]
+	bk.ID = 2
+	bk.Title = "Animal Farm: A Fairy Story"
+	n, err := s.UpdateBooks(ctx, &bk)
+	assertRowsAffected(t, "UpdateBooks", n, err, 1)
+
+	bk.Version, bk.PublisherID = 2, 1
+	got := mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{ID: 2}, Mode: db.MatchAll})
+	if len(got) != 1 {
+		t.Fatalf("updated book = %+v, want %+v", got, bk)
+	}
+	assertBook(t, "UpdateBooks", got[0], bk)
+
+	bk.ID = 100
+	bk.ISBN = "9780000000002"
+	n, err = s.UpdateBooks(ctx, &bk)
+	assertRowsAffected(t, "UpdateBooks missing book", n, err, 0)
+}
+
+func testUpdateBooksDuplicateISBN(ctx context.Context, t *testing.T, s db.Storage) {
+	bk := Books[1]
+	bk.ID = 2
+	bk.ISBN = Books[0].ISBN
+	if _, err := s.UpdateBooks(ctx, &bk); !errors.Is(err, db.ErrDuplicateISBN) {
+		t.Errorf("UpdateBooks duplicate isbn error = %v, want %v", err, db.ErrDuplicateISBN)
+	}
+}
+
+func testPatchBooks(ctx context.Context, t *testing.T, s db.Storage) {
+	n, err := s.PatchBooks(ctx, &model.PatchBook{ID: 3, Published: "April 1925"})
+	assertRowsAffected(t, "PatchBooks", n, err, 1)
+
+	want := Books[2]
+	want.ID, want.Version, want.PublisherID = 3, 2, 2
+	want.Published = "April 1925"
+	got := mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{ID: 3}, Mode: db.MatchAll})
+	if len(got) != 1 {
+		t.Fatalf("patched book = %+v, want %+v", got, want)
+	}
+	assertBook(t, "PatchBooks", got[0], want)
+
+	n, err = s.PatchBooks(ctx, &model.PatchBook{ID: 100, Title: "Missing"})
+	assertRowsAffected(t, "PatchBooks missing book", n, err, 0)
+
+	n, err = s.PatchBooks(ctx, &model.PatchBook{ID: 3})
+	assertRowsAffected(t, "PatchBooks without fields", n, err, 0)
+}
+
+func testPatchBooksDuplicateISBN(ctx context.Context, t *testing.T, s db.Storage) {
+	if _, err := s.PatchBooks(ctx, &model.PatchBook{ID: 2, ISBN: Books[0].ISBN}); !errors.Is(err, db.ErrDuplicateISBN) {
+		t.Errorf("PatchBooks duplicate isbn error = %v, want %v", err, db.ErrDuplicateISBN)
+	}
+}
+
+func testUpdateBooksVersion(ctx context.Context, t *testing.T, s db.Storage) {
+	bk := Books[1]
+	bk.ID, bk.Version = 2, 1
+	bk.Title = "Animal Farm: A Fairy Story"
+	n, err := s.UpdateBooks(ctx, &bk)
+	assertRowsAffected(t, "UpdateBooks current version", n, err, 1)
+
+	// the book is now at version 2 so the same update is a conflict
+	if _, err = s.UpdateBooks(ctx, &bk); !errors.Is(err, db.ErrVersionConflict) {
+		t.Errorf("UpdateBooks old version error = %v, want %v", err, db.ErrVersionConflict)
+	}
+	got, err := s.GetBook(ctx, 2, "book_id", "version", "updated_at")
+	if err != nil || got.Version != 2 || got.UpdatedAt.IsZero() {
+		t.Errorf("GetBook version = %+v, %v, want version 2 with updated_at", got, err)
+	}
+
+	bk.ID = 100
+	n, err = s.UpdateBooks(ctx, &bk)
+	assertRowsAffected(t, "UpdateBooks missing book with version", n, err, 0)
+}
+
+func testPatchBooksVersion(ctx context.Context, t *testing.T, s db.Storage) {
+	n, err := s.PatchBooks(ctx, &model.PatchBook{ID: 3, Published: "April 1925", Version: 1})
+	assertRowsAffected(t, "PatchBooks current version", n, err, 1)
+	n, err = s.PatchBooks(ctx, &model.PatchBook{ID: 3, Published: "1925"})
+	assertRowsAffected(t, "PatchBooks without version", n, err, 1)
+
+	if _, err = s.PatchBooks(ctx, &model.PatchBook{ID: 3, Published: "10 April 1925", Version: 2}); !errors.Is(err, db.ErrVersionConflict) {
+		t.Errorf("PatchBooks old version error = %v, want %v", err, db.ErrVersionConflict)
+	}
+	got, err := s.GetBook(ctx, 3)
+	if err != nil || got.Version != 3 || got.Published != "1925" {
+		t.Errorf("GetBook = %+v, %v, want version 3 published 1925", got, err)
+	}
+
+	n, err = s.PatchBooks(ctx, &model.PatchBook{ID: 100, Title: "Missing", Version: 1})
+	assertRowsAffected(t, "PatchBooks missing book with version", n, err, 0)
+}
+
+func testDeleteBooks(ctx context.Context, t *testing.T, s db.Storage) {
+	n, err := s.DeleteBooks(ctx, 1)
+	assertRowsAffected(t, "DeleteBooks", n, err, 1)
+
+	bks := mustList(ctx, t, s, &db.PageList{OrderBy: orderBy("book_id"), Limit: 25})
+	assertIDs(t, bks, 2, 3, 4, 5)
+
+	n, err = s.DeleteBooks(ctx, 1)
+	assertRowsAffected(t, "DeleteBooks deleted book", n, err, 0)
+	n, err = s.DeleteBooks(ctx, 100)
+	assertRowsAffected(t, "DeleteBooks missing book", n, err, 0)
+
+	// the deleted book keep its isbn until it is purged
+	_, err = s.InsertBooks(ctx, []model.Book{Books[0]}, db.AllOrNothing)
+	if !errors.Is(err, db.ErrBatchRolledBack) {
+		t.Errorf("InsertBooks isbn of deleted book error = %v, want %v", err, db.ErrBatchRolledBack)
+	}
+}
+
+func testDeletedBooksHidden(ctx context.Context, t *testing.T, s db.Storage) {
+	n, err := s.DeleteBooks(ctx, 2)
+	assertRowsAffected(t, "DeleteBooks", n, err, 1)
+
+	if _, err = s.GetBook(ctx, 2); !errors.Is(err, db.ErrBookNotFound) {
+		t.Errorf("GetBook deleted book error = %v, want %v", err, db.ErrBookNotFound)
+	}
+	bks := mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{AuthorSurname: "Orwell"}, Mode: db.MatchAll})
+	assertIDs(t, bks, 1)
+	assertSearchIDs(t, "orwell", mustSearch(ctx, t, s, "orwell", "", 25, 0), 1)
+	if total, err := s.CountBooks(ctx, nil); err != nil || total != 4 {
+		t.Errorf("CountBooks = %d, %v, want 4", total, err)
+	}
+	n, err = s.UpdateBooks(ctx, &model.Book{ID: 2, ISBN: Books[1].ISBN, Title: "T", AuthorName: "A",
+		AuthorSurname: "B", Published: "2000", Publisher: "P", Version: 2})
+	assertRowsAffected(t, "UpdateBooks deleted book", n, err, 0)
+	n, err = s.PatchBooks(ctx, &model.PatchBook{ID: 2, Title: "T"})
+	assertRowsAffected(t, "PatchBooks deleted book", n, err, 0)
+
+	// the deleted books are only listed, counted and found when they are included
+	f := &db.Filter{IncludeDeleted: true}
+	bks = mustList(ctx, t, s, &db.PageList{Filter: f, OrderBy: orderBy("book_id"), Limit: 25})
+	assertIDs(t, bks, 1, 2, 3, 4, 5)
+	if bks[1].DeletedAt == nil || bks[1].Version != 2 {
+		t.Errorf("deleted book = %+v, want deleted_at and version 2", bks[1])
+	}
+	if total, err := s.CountBooks(ctx, f); err != nil || total != 5 {
+		t.Errorf("CountBooks with deleted = %d, %v, want 5", total, err)
+	}
+	bks = mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{ID: 2}, Mode: db.MatchAll, IncludeDeleted: true})
+	assertIDs(t, bks, 2)
+	terms, _ := db.ParseSearchQuery("orwell")
+	results, err := s.SearchBooks(ctx, &db.SearchQuery{Terms: terms, Limit: 25, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("SearchBooks with deleted failed: %s", err)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+	assertSearchIDs(t, "orwell with deleted", results, 1, 2)
+}
+
+func testRestoreBooks(ctx context.Context, t *testing.T, s db.Storage) {
+	n, err := s.RestoreBooks(ctx, 3)
+	assertRowsAffected(t, "RestoreBooks not deleted book", n, err, 0)
+
+	n, err = s.DeleteBooks(ctx, 3)
+	assertRowsAffected(t, "DeleteBooks", n, err, 1)
+	n, err = s.RestoreBooks(ctx, 3)
+	assertRowsAffected(t, "RestoreBooks", n, err, 1)
+
+	bk, err := s.GetBook(ctx, 3)
+	if err != nil {
+		t.Fatalf("GetBook restored book failed: %s", err)
+	}
+	want := Books[2]
+	want.ID, want.Version, want.PublisherID = 3, 3, 2
+	assertBook(t, "RestoreBooks", bk, want)
+
+	n, err = s.RestoreBooks(ctx, 100)
+	assertRowsAffected(t, "RestoreBooks missing book", n, err, 0)
+}
+
+func testPurgeBooks(ctx context.Context, t *testing.T, s db.Storage) {
+	n, err := s.DeleteBooks(ctx, 1)
+	assertRowsAffected(t, "DeleteBooks", n, err, 1)
+
+	n, err = s.PurgeBooks(ctx, time.Hour)
+	assertRowsAffected(t, "PurgeBooks within retention", n, err, 0)
+	// a negative retention will purge the books that are deleted until a minute from now
+	n, err = s.PurgeBooks(ctx, -time.Minute)
+	assertRowsAffected(t, "PurgeBooks", n, err, 1)
+
+	bks := mustList(ctx, t, s, &db.PageList{Filter: &db.Filter{IncludeDeleted: true}, OrderBy: orderBy("book_id"), Limit: 25})
+	assertIDs(t, bks, 2, 3, 4, 5)
+	if n, err = s.RestoreBooks(ctx, 1); err != nil || n != 0 {
+		t.Errorf("RestoreBooks purged book = %d, %v, want 0", n, err)
+	}
+
+	// book_id is not reused after purge and the isbn is free again
+	ids, err := insertIDs(s.InsertBooks(ctx, []model.Book{Books[0]}, db.AllOrNothing))
+	assertInsertedIDs(t, "InsertBooks after purge", ids, err, 6)
+	bks = mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{ISBN: Books[0].ISBN}, Mode: db.MatchAll})
+	assertIDs(t, bks, 6)
+}
+
+func testBookHistory(ctx context.Context, t *testing.T, s db.Storage) {
+	actx := db.WithActor(ctx, "alice")
+	n, err := s.PatchBooks(actx, &model.PatchBook{ID: 1, Title: "Changed Title"})
+	assertRowsAffected(t, "PatchBooks", n, err, 1)
+	n, err = s.DeleteBooks(actx, 1)
+	assertRowsAffected(t, "DeleteBooks", n, err, 1)
+	n, err = s.RestoreBooks(ctx, 1)
+	assertRowsAffected(t, "RestoreBooks", n, err, 1)
+	n, err = s.UpdateBooks(actx, &model.Book{ID: 1, Version: 1})
+	if !errors.Is(err, db.ErrVersionConflict) {
+		t.Errorf("UpdateBooks stale version = %d, %v, want %v", n, err, db.ErrVersionConflict)
+	}
+
+	hs := mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 1, Limit: 25})
+	assertHistory(t, hs, "restore:system:4", "delete:alice:3", "patch:alice:2", "insert:system:1")
+	if hs[3].Before != nil || hs[3].After == nil || hs[3].After.Title != Books[0].Title {
+		t.Errorf("insert history = %+v, %+v, want no before and the inserted book after", hs[3].Before, hs[3].After)
+	}
+	if hs[2].Before == nil || hs[2].Before.Title != Books[0].Title || hs[2].After == nil || hs[2].After.Title != "Changed Title" {
+		t.Errorf("patch history = %+v, %+v, want the title before and after", hs[2].Before, hs[2].After)
+	}
+	if hs[1].After == nil || hs[1].After.DeletedAt == nil || hs[0].After == nil || hs[0].After.DeletedAt != nil {
+		t.Errorf("delete and restore history = %+v, %+v, want the deleted_at set and cleared", hs[1].After, hs[0].After)
+	}
+
+	hs = mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 1, Limit: 2, OffSet: 1})
+	assertHistory(t, hs, "delete:alice:3", "patch:alice:2")
+	hs = mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 1, Until: hs[0].ChangedAt.Add(-time.Hour), Limit: 25})
+	assertHistory(t, hs)
+	hs = mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 2, Until: time.Now().Add(time.Hour), Limit: 25})
+	assertHistory(t, hs, "insert:system:1")
+	hs = mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 100, Limit: 25})
+	assertHistory(t, hs)
+
+	// the history is kept when the book is purged
+	n, err = s.DeleteBooks(ctx, 2)
+	assertRowsAffected(t, "DeleteBooks", n, err, 1)
+	n, err = s.PurgeBooks(ctx, -time.Minute)
+	assertRowsAffected(t, "PurgeBooks", n, err, 1)
+	hs = mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 2, Limit: 25})
+	assertHistory(t, hs, "purge:system:2", "delete:system:2", "insert:system:1")
+	if hs[0].Before == nil || hs[0].Before.ID != 2 || hs[0].After != nil {
+		t.Errorf("purge history = %+v, %+v, want the purged book before and none after", hs[0].Before, hs[0].After)
+	}
+
+	// the patched book is saved like an update but recorded as a patch
+	bk := Books[2]
+	bk.ID, bk.Published = 3, "1926"
+	n, err = s.ApplyBookPatch(actx, &bk)
+	assertRowsAffected(t, "ApplyBookPatch", n, err, 1)
+	bk.Published = "1925"
+	n, err = s.UpdateBooks(actx, &bk)
+	assertRowsAffected(t, "UpdateBooks", n, err, 1)
+	hs = mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 3, Limit: 25})
+	assertHistory(t, hs, "update:alice:3", "patch:alice:2", "insert:system:1")
+}
+
+func testRevertBook(ctx context.Context, t *testing.T, s db.Storage) {
+	n, err := s.PatchBooks(ctx, &model.PatchBook{ID: 1, Title: "Changed Title", Publisher: "Changed Publisher"})
+	assertRowsAffected(t, "PatchBooks", n, err, 1)
+	hs := mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 1, Limit: 25})
+	inserted := hs[len(hs)-1].HistoryID
+
+	if _, err = s.RevertBook(ctx, 1, inserted, 1); !errors.Is(err, db.ErrVersionConflict) {
+		t.Errorf("RevertBook stale version error = %v, want %v", err, db.ErrVersionConflict)
+	}
+	n, err = s.RevertBook(db.WithActor(ctx, "bob"), 1, inserted, 2)
+	assertRowsAffected(t, "RevertBook", n, err, 1)
+	bk, err := s.GetBook(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetBook reverted book failed: %s", err)
+	}
+	want := Books[0]
+	want.ID, want.Version, want.PublisherID = 1, 3, 1
+	assertBook(t, "RevertBook", bk, want)
+	hs = mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 1, Limit: 1})
+	assertHistory(t, hs, "revert:bob:3")
+
+	other := mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 2, Limit: 25})
+	if _, err = s.RevertBook(ctx, 1, other[0].HistoryID, 0); !errors.Is(err, db.ErrHistoryNotFound) {
+		t.Errorf("RevertBook to another book history error = %v, want %v", err, db.ErrHistoryNotFound)
+	}
+	if _, err = s.RevertBook(ctx, 100, inserted, 0); !errors.Is(err, db.ErrBookNotFound) {
+		t.Errorf("RevertBook missing book error = %v, want %v", err, db.ErrBookNotFound)
+	}
+
+	// the book versions from before the validation are validated again like an update
+	for _, tc := range []struct {
+		patch model.PatchBook
+		want  error
+	}{
+		{model.PatchBook{ID: 1, ISBN: "9780451524936"}, db.ErrInvalidISBN},
+		{model.PatchBook{ID: 1, Published: "someday"}, db.ErrInvalidPublished},
+	} {
+		n, err = s.PatchBooks(ctx, &tc.patch)
+		assertRowsAffected(t, "PatchBooks invalid", n, err, 1)
+		invalid := mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 1, Limit: 1})[0].HistoryID
+		n, err = s.RevertBook(ctx, 1, inserted, 0)
+		assertRowsAffected(t, "RevertBook valid", n, err, 1)
+		if _, err = s.RevertBook(ctx, 1, invalid, 0); !errors.Is(err, tc.want) {
+			t.Errorf("RevertBook %+v error = %v, want %v", tc.patch, err, tc.want)
+		}
+	}
+
+	// the isbn of the book version is used by another book now
+	n, err = s.PatchBooks(ctx, &model.PatchBook{ID: 1, ISBN: "9780000000099"})
+	assertRowsAffected(t, "PatchBooks isbn", n, err, 1)
+	n, err = s.PatchBooks(ctx, &model.PatchBook{ID: 2, ISBN: Books[0].ISBN})
+	assertRowsAffected(t, "PatchBooks other isbn", n, err, 1)
+	if _, err = s.RevertBook(ctx, 1, inserted, 0); !errors.Is(err, db.ErrDuplicateISBN) {
+		t.Errorf("RevertBook duplicate isbn error = %v, want %v", err, db.ErrDuplicateISBN)
+	}
+
+	n, err = s.DeleteBooks(ctx, 1)
+	assertRowsAffected(t, "DeleteBooks", n, err, 1)
+	if _, err = s.RevertBook(ctx, 1, inserted, 0); !errors.Is(err, db.ErrBookNotFound) {
+		t.Errorf("RevertBook deleted book error = %v, want %v", err, db.ErrBookNotFound)
+	}
+}
+
+func testAuthors(ctx context.Context, t *testing.T, s db.Storage) {
+	// the seed books credit their authors, Orwell once for both books
+	as := mustAuthors(ctx, t, s, &db.AuthorQuery{Limit: 25})
+	assertAuthors(t, as, "2:Fitzgerald", "4:Harari", "1:Orwell", "3:Shakespeare")
+	as = mustAuthors(ctx, t, s, &db.AuthorQuery{Name: "ORW", Limit: 25})
+	assertAuthors(t, as, "1:Orwell")
+	as = mustAuthors(ctx, t, s, &db.AuthorQuery{Name: "william", Limit: 25})
+	assertAuthors(t, as, "3:Shakespeare")
+	as = mustAuthors(ctx, t, s, &db.AuthorQuery{Limit: 2, OffSet: 1})
+	assertAuthors(t, as, "4:Harari", "1:Orwell")
+
+	a := model.Author{Name: "Constance", Surname: "Garnett"}
+	if err := s.InsertAuthor(ctx, &a); err != nil || a.ID != 5 || a.UpdatedAt.IsZero() {
+		t.Fatalf("InsertAuthor = %+v, %v, want author_id 5 with updated_at", a, err)
+	}
+	a.Name = "Constance Clara"
+	if err := s.UpdateAuthor(ctx, &a); err != nil {
+		t.Errorf("UpdateAuthor failed: %s", err)
+	}
+	if got, err := s.GetAuthor(ctx, 5); err != nil || got.Name != "Constance Clara" || got.Surname != "Garnett" {
+		t.Errorf("GetAuthor = %+v, %v, want the updated author", got, err)
+	}
+
+	if _, err := s.GetAuthor(ctx, 100); !errors.Is(err, db.ErrAuthorNotFound) {
+		t.Errorf("GetAuthor unknown author error = %v, want %v", err, db.ErrAuthorNotFound)
+	}
+	if err := s.UpdateAuthor(ctx, &model.Author{ID: 100, Surname: "Nobody"}); !errors.Is(err, db.ErrAuthorNotFound) {
+		t.Errorf("UpdateAuthor unknown author error = %v, want %v", err, db.ErrAuthorNotFound)
+	}
+	if err := s.DeleteAuthor(ctx, 1); !errors.Is(err, db.ErrAuthorHasBooks) {
+		t.Errorf("DeleteAuthor with books error = %v, want %v", err, db.ErrAuthorHasBooks)
+	}
+	if err := s.DeleteAuthor(ctx, 5); err != nil {
+		t.Errorf("DeleteAuthor failed: %s", err)
+	}
+	if err := s.DeleteAuthor(ctx, 5); !errors.Is(err, db.ErrAuthorNotFound) {
+		t.Errorf("DeleteAuthor deleted author error = %v, want %v", err, db.ErrAuthorNotFound)
+	}
+}
+
+func testBookAuthors(ctx context.Context, t *testing.T, s db.Storage) {
+	bas, err := s.GetBookAuthors(ctx, 1)
+	assertBookAuthors(t, bas, err, "1:Orwell:author:1")
+
+	a := model.Author{Name: "Constance", Surname: "Garnett"}
+	if err = s.InsertAuthor(ctx, &a); err != nil {
+		t.Fatalf("InsertAuthor failed: %s", err)
+	}
+	actx := db.WithActor(ctx, "alice")
+	// the book is left as it is while its first author is the same
+	err = s.SetBookAuthors(actx, 4, []model.BookAuthor{{AuthorID: 3}, {AuthorID: 5, Role: model.RoleEditor}})
+	bas, _ = s.GetBookAuthors(ctx, 4)
+	assertBookAuthors(t, bas, err, "3:Shakespeare:author:1", "5:Garnett:editor:2")
+	assertHistory(t, mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 4, Limit: 25}), "insert:system:1")
+
+	err = s.SetBookAuthors(actx, 4, []model.BookAuthor{{AuthorID: 5, Role: model.RoleTranslator}, {AuthorID: 3}})
+	bas, _ = s.GetBookAuthors(ctx, 4)
+	assertBookAuthors(t, bas, err, "5:Garnett:translator:1", "3:Shakespeare:author:2")
+	bk, err := s.GetBook(ctx, 4)
+	if err != nil || bk.AuthorName != "Constance" || bk.AuthorSurname != "Garnett" || bk.Version != 2 {
+		t.Errorf("GetBook = %+v, %v, want the first author with version 2", bk, err)
+	}
+	assertHistory(t, mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 4, Limit: 25}), "authors:alice:2", "insert:system:1")
+
+	err = s.SetBookAuthors(ctx, 4, []model.BookAuthor{{AuthorID: 3}, {AuthorID: 3, Role: model.RoleAuthor}})
+	if !errors.Is(err, db.ErrDuplicateBookAuthor) {
+		t.Errorf("SetBookAuthors duplicate author error = %v, want %v", err, db.ErrDuplicateBookAuthor)
+	}
+	if err = s.SetBookAuthors(ctx, 4, []model.BookAuthor{{AuthorID: 3}, {AuthorID: 100}}); !errors.Is(err, db.ErrAuthorNotFound) {
+		t.Errorf("SetBookAuthors unknown author error = %v, want %v", err, db.ErrAuthorNotFound)
+	}
+	if err = s.SetBookAuthors(ctx, 100, []model.BookAuthor{{AuthorID: 3}}); !errors.Is(err, db.ErrBookNotFound) {
+		t.Errorf("SetBookAuthors unknown book error = %v, want %v", err, db.ErrBookNotFound)
+	}
+	// the failed calls leave the authors as they are
+	bas, err = s.GetBookAuthors(ctx, 4)
+	assertBookAuthors(t, bas, err, "5:Garnett:translator:1", "3:Shakespeare:author:2")
+	if _, err = s.GetBookAuthors(ctx, 100); !errors.Is(err, db.ErrBookNotFound) {
+		t.Errorf("GetBookAuthors unknown book error = %v, want %v", err, db.ErrBookNotFound)
+	}
+
+	abs, err := s.ListAuthorBooks(ctx, &db.AuthorBooksQuery{AuthorID: 1, Limit: 25})
+	assertAuthorBooks(t, abs, err, "2:author:1", "1:author:1")
+	abs, err = s.ListAuthorBooks(ctx, &db.AuthorBooksQuery{AuthorID: 1, Limit: 1, OffSet: 1})
+	assertAuthorBooks(t, abs, err, "1:author:1")
+	n, err := s.DeleteBooks(ctx, 2)
+	assertRowsAffected(t, "DeleteBooks", n, err, 1)
+	abs, err = s.ListAuthorBooks(ctx, &db.AuthorBooksQuery{AuthorID: 1, Limit: 25})
+	assertAuthorBooks(t, abs, err, "1:author:1")
+	if _, err = s.ListAuthorBooks(ctx, &db.AuthorBooksQuery{AuthorID: 100, Limit: 25}); !errors.Is(err, db.ErrAuthorNotFound) {
+		t.Errorf("ListAuthorBooks unknown author error = %v, want %v", err, db.ErrAuthorNotFound)
+	}
+}
+
+func testBookAuthorsSync(ctx context.Context, t *testing.T, s db.Storage) {
+	// the books that credit the author first follow the author name
+	if err := s.UpdateAuthor(ctx, &model.Author{ID: 1, Name: "Eric Arthur", Surname: "Blair"}); err != nil {
+		t.Fatalf("UpdateAuthor failed: %s", err)
+	}
+	bks := mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{AuthorSurname: "Blair"}, Mode: db.MatchAll})
+	assertIDs(t, bks, 1, 2)
+	if bks[0].AuthorName != "Eric Arthur" || bks[0].Version != 2 {
+		t.Errorf("synced book = %+v, want the new author name with version 2", bks[0])
+	}
+	assertHistory(t, mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 2, Limit: 25}), "authors:system:2", "insert:system:1")
+
+	// the book credit the author with the same name first when its author is changed, a new author when there is none
+	n, err := s.PatchBooks(ctx, &model.PatchBook{ID: 3, AuthorName: "Eric Arthur", AuthorSurname: "Blair"})
+	assertRowsAffected(t, "PatchBooks", n, err, 1)
+	bas, err := s.GetBookAuthors(ctx, 3)
+	assertBookAuthors(t, bas, err, "1:Blair:author:1")
+	n, err = s.UpdateBooks(ctx, &model.Book{ID: 5, ISBN: Books[4].ISBN, Title: Books[4].Title, AuthorName: "Ann",
+		AuthorSurname: "Other", Published: Books[4].Published, Publisher: Books[4].Publisher})
+	assertRowsAffected(t, "UpdateBooks", n, err, 1)
+	bas, err = s.GetBookAuthors(ctx, 5)
+	assertBookAuthors(t, bas, err, "5:Other:author:1")
+
+	// the authors that are not credited any more could be deleted, the purged books do not credit theirs
+	if err = s.DeleteAuthor(ctx, 2); err != nil {
+		t.Errorf("DeleteAuthor uncredited author failed: %s", err)
+	}
+	n, err = s.DeleteBooks(ctx, 4)
+	assertRowsAffected(t, "DeleteBooks", n, err, 1)
+	if err = s.DeleteAuthor(ctx, 3); !errors.Is(err, db.ErrAuthorHasBooks) {
+		t.Errorf("DeleteAuthor of deleted book error = %v, want %v", err, db.ErrAuthorHasBooks)
+	}
+	n, err = s.PurgeBooks(ctx, -time.Minute)
+	assertRowsAffected(t, "PurgeBooks", n, err, 1)
+	if err = s.DeleteAuthor(ctx, 3); err != nil {
+		t.Errorf("DeleteAuthor of purged book failed: %s", err)
+	}
+}
+
+func testPublishers(ctx context.Context, t *testing.T, s db.Storage) {
+	// the seed books are published by their publishers
+	ps := mustPublishers(ctx, t, s, &db.PublisherQuery{Limit: 25})
+	assertPublishers(t, ps, "2:Charles Scribner's Sons", "4:Harper", "3:Penguin Classics", "1:Secker & Warburg")
+	ps = mustPublishers(ctx, t, s, &db.PublisherQuery{Limit: 2, OffSet: 1})
+	assertPublishers(t, ps, "4:Harper", "3:Penguin Classics")
+
+	// the aliases that are the same as the name or another alias without the case and spaces are left out
+	p := model.Publisher{Name: "Penguin  Books", Aliases: []string{"Penguin", " penguin ", "PENGUIN BOOKS"}}
+	if err := s.InsertPublisher(ctx, &p); err != nil || p.ID != 5 || p.UpdatedAt.IsZero() {
+		t.Fatalf("InsertPublisher = %+v, %v, want publisher_id 5 with updated_at", p, err)
+	}
+	if got, err := s.GetPublisher(ctx, 5); err != nil || got.Name != "Penguin Books" || fmt.Sprint(got.Aliases) != "[Penguin]" {
+		t.Errorf("GetPublisher = %+v, %v, want Penguin Books with the Penguin alias", got, err)
+	}
+	ps = mustPublishers(ctx, t, s, &db.PublisherQuery{Name: "PENGUIN", Limit: 25})
+	assertPublishers(t, ps, "5:Penguin Books", "3:Penguin Classics")
+	if err := s.InsertPublisher(ctx, &model.Publisher{Name: "penguin"}); !errors.Is(err, db.ErrDuplicatePublisher) {
+		t.Errorf("InsertPublisher alias as name error = %v, want %v", err, db.ErrDuplicatePublisher)
+	}
+	err := s.InsertPublisher(ctx, &model.Publisher{Name: "Other", Aliases: []string{"Secker and Warburg"}})
+	if !errors.Is(err, db.ErrDuplicatePublisher) {
+		t.Errorf("InsertPublisher name as alias error = %v, want %v", err, db.ErrDuplicatePublisher)
+	}
+
+	// the imprints are listed by their parent, a publisher could not be an imprint of its imprints
+	parentID := 5
+	imprint := model.Publisher{Name: "Puffin Books", ParentID: &parentID}
+	if err = s.InsertPublisher(ctx, &imprint); err != nil {
+		t.Fatalf("InsertPublisher imprint failed: %s", err)
+	}
+	ps = mustPublishers(ctx, t, s, &db.PublisherQuery{ParentID: 5, Limit: 25})
+	assertPublishers(t, ps, "6:Puffin Books")
+	p.ParentID = &imprint.ID
+	if err = s.UpdatePublisher(ctx, &p); !errors.Is(err, db.ErrPublisherCycle) {
+		t.Errorf("UpdatePublisher parent imprint error = %v, want %v", err, db.ErrPublisherCycle)
+	}
+	unknownID := 100
+	if err = s.InsertPublisher(ctx, &model.Publisher{Name: "Other", ParentID: &unknownID}); !errors.Is(err, db.ErrUnknownPublisher) {
+		t.Errorf("InsertPublisher unknown parent error = %v, want %v", err, db.ErrUnknownPublisher)
+	}
+
+	if _, err = s.GetPublisher(ctx, 100); !errors.Is(err, db.ErrPublisherNotFound) {
+		t.Errorf("GetPublisher unknown publisher error = %v, want %v", err, db.ErrPublisherNotFound)
+	}
+	if err = s.UpdatePublisher(ctx, &model.Publisher{ID: 100, Name: "Nobody"}); !errors.Is(err, db.ErrPublisherNotFound) {
+		t.Errorf("UpdatePublisher unknown publisher error = %v, want %v", err, db.ErrPublisherNotFound)
+	}
+	if err = s.DeletePublisher(ctx, 1); !errors.Is(err, db.ErrPublisherHasBooks) {
+		t.Errorf("DeletePublisher with books error = %v, want %v", err, db.ErrPublisherHasBooks)
+	}
+	// the imprints of the deleted publisher get its parent
+	if err = s.DeletePublisher(ctx, 5); err != nil {
+		t.Errorf("DeletePublisher failed: %s", err)
+	}
+	if got, err := s.GetPublisher(ctx, 6); err != nil || got.ParentID != nil {
+		t.Errorf("GetPublisher imprint of deleted publisher = %+v, %v, want no parent", got, err)
+	}
+	if err = s.DeletePublisher(ctx, 5); !errors.Is(err, db.ErrPublisherNotFound) {
+		t.Errorf("DeletePublisher deleted publisher error = %v, want %v", err, db.ErrPublisherNotFound)
+	}
+}
+
+func testPublisherBooks(ctx context.Context, t *testing.T, s db.Storage) {
+	if err := s.InsertPublisher(ctx, &model.Publisher{Name: "Penguin Books", Aliases: []string{"Penguin"}}); err != nil {
+		t.Fatalf("InsertPublisher failed: %s", err)
+	}
+	// the book get the publisher of the name or alias, a new publisher when there is none
+	bks := []model.Book{
+		{ISBN: "9780141439518", Title: "Pride and Prejudice", AuthorName: "Jane", AuthorSurname: "Austen",
+			Published: "2003", Publisher: "penguin"},
+		{ISBN: "9780141439600", Title: "A Tale of Two Cities", AuthorName: "Charles", AuthorSurname: "Dickens",
+			Published: "1859", Publisher: "Chapman & Hall"},
+	}
+	ids, err := insertIDs(s.InsertBooks(ctx, bks, db.AllOrNothing))
+	assertInsertedIDs(t, "InsertBooks", ids, err, 6, 7)
+	got := mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{ID: 6}, Mode: db.MatchAll})
+	if len(got) != 1 || got[0].Publisher != "Penguin Books" || got[0].PublisherID != 5 {
+		t.Errorf("inserted book = %+v, want the Penguin Books publisher 5", got)
+	}
+	if p, err := s.GetPublisher(ctx, 6); err != nil || p.Name != "Chapman & Hall" {
+		t.Errorf("GetPublisher = %+v, %v, want the new Chapman & Hall publisher", p, err)
+	}
+	results, err := s.InsertBooks(ctx, []model.Book{{ISBN: "9780000000001", Title: "T", AuthorName: "A",
+		AuthorSurname: "B", Published: "2000", Publisher: "P", PublisherID: 100}}, db.AllOrNothing)
+	if !errors.Is(err, db.ErrBatchRolledBack) || len(results) != 1 || !errors.Is(results[0].Err, db.ErrUnknownPublisher) {
+		t.Errorf("InsertBooks unknown publisher = %+v, %v, want %v", results, err, db.ErrUnknownPublisher)
+	}
+
+	// a changed publisher_id win over the publisher name
+	n, err := s.PatchBooks(ctx, &model.PatchBook{ID: 1, PublisherID: 3})
+	assertRowsAffected(t, "PatchBooks", n, err, 1)
+	bk, err := s.GetBook(ctx, 1)
+	if err != nil || bk.Publisher != "Penguin Classics" || bk.PublisherID != 3 || bk.Version != 2 {
+		t.Errorf("GetBook = %+v, %v, want Penguin Classics with version 2", bk, err)
+	}
+	n, err = s.PatchBooks(ctx, &model.PatchBook{ID: 1, Publisher: "HARPER"})
+	assertRowsAffected(t, "PatchBooks", n, err, 1)
+	bk, err = s.GetBook(ctx, 1)
+	if err != nil || bk.Publisher != "Harper" || bk.PublisherID != 4 {
+		t.Errorf("GetBook = %+v, %v, want the Harper publisher 4", bk, err)
+	}
+	bk.PublisherID = 100
+	if _, err = s.UpdateBooks(ctx, &bk); !errors.Is(err, db.ErrUnknownPublisher) {
+		t.Errorf("UpdateBooks unknown publisher error = %v, want %v", err, db.ErrUnknownPublisher)
+	}
+
+	// the books follow the publisher name
+	err = s.UpdatePublisher(db.WithActor(ctx, "alice"), &model.Publisher{ID: 4, Name: "HarperCollins", Aliases: []string{"Harper"}})
+	if err != nil {
+		t.Fatalf("UpdatePublisher failed: %s", err)
+	}
+	bks = mustGet(ctx, t, s, &db.BookFilter{Book: model.Book{Publisher: "HarperCollins"}, Mode: db.MatchAll})
+	assertIDs(t, bks, 1, 5)
+	assertHistory(t, mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 5, Limit: 25}), "publisher:alice:2", "insert:system:1")
+
+	// the books of the imprints are listed by published date, the deleted books are left out
+	parentID := 5
+	if err = s.UpdatePublisher(ctx, &model.Publisher{ID: 3, Name: "Penguin Classics", ParentID: &parentID}); err != nil {
+		t.Fatalf("UpdatePublisher parent failed: %s", err)
+	}
+	bks, err = s.ListPublisherBooks(ctx, &db.PublisherBooksQuery{PublisherID: 5, Limit: 25})
+	assertListed(t, "ListPublisherBooks", bks, err, 6)
+	bks, err = s.ListPublisherBooks(ctx, &db.PublisherBooksQuery{PublisherID: 5, IncludeImprints: true, Limit: 25})
+	assertListed(t, "ListPublisherBooks with imprints", bks, err, 4, 6)
+	n, err = s.DeleteBooks(ctx, 4)
+	assertRowsAffected(t, "DeleteBooks", n, err, 1)
+	bks, err = s.ListPublisherBooks(ctx, &db.PublisherBooksQuery{PublisherID: 5, IncludeImprints: true, Limit: 25})
+	assertListed(t, "ListPublisherBooks with imprints", bks, err, 6)
+	if _, err = s.ListPublisherBooks(ctx, &db.PublisherBooksQuery{PublisherID: 100, Limit: 25}); !errors.Is(err, db.ErrPublisherNotFound) {
+		t.Errorf("ListPublisherBooks unknown publisher error = %v, want %v", err, db.ErrPublisherNotFound)
+	}
+}
+
+func testMergePublishers(ctx context.Context, t *testing.T, s db.Storage) {
+	pcs, err := s.ListPublisherDuplicates(ctx)
+	if err != nil || len(pcs) != 0 {
+		t.Errorf("ListPublisherDuplicates = %+v, %v, want none", pcs, err)
+	}
+	// the names are alike without the case, punctuation and words like books or ltd
+	for _, p := range []model.Publisher{{Name: "Penguin Books", Aliases: []string{"Penguin"}}, {Name: "Secker and Warburg Ltd"}} {
+		p := p
+		if err = s.InsertPublisher(ctx, &p); err != nil {
+			t.Fatalf("InsertPublisher failed: %s", err)
+		}
+	}
+	pcs, err = s.ListPublisherDuplicates(ctx)
+	if err != nil || len(pcs) != 1 || pcs[0].Key != "secker and warburg" || len(pcs[0].Publishers) != 2 ||
+		pcs[0].Publishers[0].ID != 1 || pcs[0].Publishers[1].ID != 6 {
+		t.Errorf("ListPublisherDuplicates = %+v, %v, want publishers 1 and 6", pcs, err)
+	}
+
+	parentID := 3
+	if err = s.InsertPublisher(ctx, &model.Publisher{Name: "Puffin Books", ParentID: &parentID}); err != nil {
+		t.Fatalf("InsertPublisher imprint failed: %s", err)
+	}
+	// the merged publishers become aliases, their imprints and books move to the publisher
+	if err = s.MergePublishers(db.WithActor(ctx, "alice"), 5, []int{3, 4}); err != nil {
+		t.Fatalf("MergePublishers failed: %s", err)
+	}
+	p, err := s.GetPublisher(ctx, 5)
+	if err != nil || fmt.Sprint(p.Aliases) != "[Harper Penguin Penguin Classics]" {
+		t.Errorf("GetPublisher = %+v, %v, want the merged names as aliases", p, err)
+	}
+	if _, err = s.GetPublisher(ctx, 3); !errors.Is(err, db.ErrPublisherNotFound) {
+		t.Errorf("GetPublisher merged publisher error = %v, want %v", err, db.ErrPublisherNotFound)
+	}
+	ps := mustPublishers(ctx, t, s, &db.PublisherQuery{ParentID: 5, Limit: 25})
+	assertPublishers(t, ps, "7:Puffin Books")
+	bks, err := s.ListPublisherBooks(ctx, &db.PublisherBooksQuery{PublisherID: 5, Limit: 25})
+	assertListed(t, "ListPublisherBooks", bks, err, 4, 5)
+	if bks[0].Publisher != "Penguin Books" || bks[0].Version != 2 {
+		t.Errorf("merged book = %+v, want Penguin Books with version 2", bks[0])
+	}
+	assertHistory(t, mustHistory(ctx, t, s, &db.HistoryQuery{BookID: 4, Limit: 25}), "publisher:alice:2", "insert:system:1")
+
+	// the books with a merged name get the publisher
+	ids, err := insertIDs(s.InsertBooks(ctx, []model.Book{{ISBN: "9780141439518", Title: "Pride and Prejudice",
+		AuthorName: "Jane", AuthorSurname: "Austen", Published: "2003", Publisher: "Harper"}}, db.AllOrNothing))
+	assertInsertedIDs(t, "InsertBooks", ids, err, 6)
+	if bk, err := s.GetBook(ctx, 6); err != nil || bk.PublisherID != 5 {
+		t.Errorf("GetBook = %+v, %v, want publisher_id 5", bk, err)
+	}
+
+	if err = s.MergePublishers(ctx, 5, []int{5}); !errors.Is(err, db.ErrSelfMerge) {
+		t.Errorf("MergePublishers into itself error = %v, want %v", err, db.ErrSelfMerge)
+	}
+	if err = s.MergePublishers(ctx, 5, []int{100}); !errors.Is(err, db.ErrUnknownPublisher) {
+		t.Errorf("MergePublishers unknown publisher error = %v, want %v", err, db.ErrUnknownPublisher)
+	}
+	if err = s.MergePublishers(ctx, 100, []int{1}); !errors.Is(err, db.ErrPublisherNotFound) {
+		t.Errorf("MergePublishers into unknown publisher error = %v, want %v", err, db.ErrPublisherNotFound)
+	}
+}
+
+func testCanceledContext(ctx context.Context, t *testing.T, s db.Storage) {
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	errs := map[string]error{}
+	_, errs["ListBooks"] = s.ListBooks(ctx, &db.PageList{OrderBy: orderBy("book_id"), Limit: 25})
+	_, errs["CountBooks"] = s.CountBooks(ctx, nil)
+	_, errs["GetBooks"] = s.GetBooks(ctx, &db.BookFilter{Book: model.Book{ID: 1}, Mode: db.MatchAll})
+	_, errs["GetBook"] = s.GetBook(ctx, 1)
+	_, errs["SearchBooks"] = s.SearchBooks(ctx, &db.SearchQuery{Terms: []db.SearchTerm{{Tokens: []string{"orwell"}}}, Limit: 25})
+	_, errs["InsertBooks"] = s.InsertBooks(ctx, []model.Book{{ISBN: "9780000000001", Title: "T", AuthorName: "A",
+		AuthorSurname: "B", Published: "2000", Publisher: "P"}}, db.BestEffort)
+	_, errs["UpdateBooks"] = s.UpdateBooks(ctx, &model.Book{ID: 1, ISBN: "9780000000001", Title: "T",
+		AuthorName: "A", AuthorSurname: "B", Published: "2000", Publisher: "P"})
+	_, errs["ApplyBookPatch"] = s.ApplyBookPatch(ctx, &model.Book{ID: 1, ISBN: "9780000000001", Title: "T",
+		AuthorName: "A", AuthorSurname: "B", Published: "2000", Publisher: "P"})
+	_, errs["PatchBooks"] = s.PatchBooks(ctx, &model.PatchBook{ID: 1, Title: "T"})
+	_, errs["DeleteBooks"] = s.DeleteBooks(ctx, 1)
+	_, errs["RestoreBooks"] = s.RestoreBooks(ctx, 1)
+	_, errs["PurgeBooks"] = s.PurgeBooks(ctx, 0)
+	_, errs["ListBookHistory"] = s.ListBookHistory(ctx, &db.HistoryQuery{BookID: 1, Limit: 25})
+	_, errs["RevertBook"] = s.RevertBook(ctx, 1, 1, 0)
+	_, errs["ListAuthors"] = s.ListAuthors(ctx, &db.AuthorQuery{Limit: 25})
+	_, errs["GetAuthor"] = s.GetAuthor(ctx, 1)
+	errs["InsertAuthor"] = s.InsertAuthor(ctx, &model.Author{Surname: "B"})
+	errs["UpdateAuthor"] = s.UpdateAuthor(ctx, &model.Author{ID: 1, Surname: "B"})
+	errs["DeleteAuthor"] = s.DeleteAuthor(ctx, 1)
+	_, errs["GetBookAuthors"] = s.GetBookAuthors(ctx, 1)
+	errs["SetBookAuthors"] = s.SetBookAuthors(ctx, 1, []model.BookAuthor{{AuthorID: 2}})
+	_, errs["ListAuthorBooks"] = s.ListAuthorBooks(ctx, &db.AuthorBooksQuery{AuthorID: 1, Limit: 25})
+	_, errs["ListPublishers"] = s.ListPublishers(ctx, &db.PublisherQuery{Limit: 25})
+	_, errs["GetPublisher"] = s.GetPublisher(ctx, 1)
+	errs["InsertPublisher"] = s.InsertPublisher(ctx, &model.Publisher{Name: "P"})
+	errs["UpdatePublisher"] = s.UpdatePublisher(ctx, &model.Publisher{ID: 1, Name: "P"})
+	errs["DeletePublisher"] = s.DeletePublisher(ctx, 1)
+	errs["MergePublishers"] = s.MergePublishers(ctx, 1, []int{2})
+	_, errs["ListPublisherDuplicates"] = s.ListPublisherDuplicates(ctx)
+	_, errs["ListPublisherBooks"] = s.ListPublisherBooks(ctx, &db.PublisherBooksQuery{PublisherID: 1, Limit: 25})
+	for op, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s with canceled context error = %v, want %v", op, err, context.Canceled)
+		}
+	}
+
+	// nothing is changed by the canceled calls
+	bks := mustList(context.Background(), t, s, &db.PageList{OrderBy: orderBy("book_id"), Limit: 25})
+	assertIDs(t, bks, 1, 2, 3, 4, 5)
+}
+
+// orderBy will parse the order by of the test case, it panic on the invalid order by
+func orderBy(s string) []db.SortField {
+	order, err := db.ParseOrderBy(s)
+	if err != nil {
+		panic(err)
+	}
+	return order
+}
+
+func mustList(ctx context.Context, t *testing.T, s db.Storage, p *db.PageList) []model.Book {
+	t.Helper()
+	bks, err := s.ListBooks(ctx, p)
+	if err != nil {
+		t.Fatalf("ListBooks(%+v) failed: %s", p, err)
+	}
+	return bks
+}
+
+func mustGet(ctx context.Context, t *testing.T, s db.Storage, f *db.BookFilter) []model.Book {
+	t.Helper()
+	bks, err := s.GetBooks(ctx, f)
+	if err != nil {
+		t.Fatalf("GetBooks(%+v) failed: %s", f, err)
+	}
+	return bks
+}
+
+// mustSearch will parse the search text and boosts like the api does and return the search results
+func mustSearch(ctx context.Context, t *testing.T, s db.Storage, q string, boost string, limit, offset int) []db.SearchResult {
+	t.Helper()
+	terms, err := db.ParseSearchQuery(q)
+	if err != nil {
+		t.Fatalf("ParseSearchQuery(%q) failed: %s", q, err)
+	}
+	boosts, err := db.ParseSearchBoosts(boost)
+	if err != nil {
+		t.Fatalf("ParseSearchBoosts(%q) failed: %s", boost, err)
+	}
+	results, err := s.SearchBooks(ctx, &db.SearchQuery{Terms: terms, Boosts: boosts, Limit: limit, OffSet: offset})
+	if err != nil {
+		t.Fatalf("SearchBooks(%q) failed: %s", q, err)
+	}
+	return results
+}
+
+func assertSearchIDs(t *testing.T, q string, results []db.SearchResult, ids ...int) {
+	t.Helper()
+	got := make([]int, len(results))
+	for i, r := range results {
+		got[i] = r.ID
+	}
+	if fmt.Sprint(got) != fmt.Sprint(ids) {
+		t.Errorf("SearchBooks(%q) book ids = %v, want %v", q, got, ids)
+	}
+}
+
+// mustHistory will return the book history of the query and fail the test if the storage return an error
+func mustHistory(ctx context.Context, t *testing.T, s db.Storage, q *db.HistoryQuery) []model.BookHistory {
+	t.Helper()
+	hs, err := s.ListBookHistory(ctx, q)
+	if err != nil {
+		t.Fatalf("ListBookHistory(%+v) failed: %s", q, err)
+	}
+	return hs
+}
+
+func mustAuthors(ctx context.Context, t *testing.T, s db.Storage, q *db.AuthorQuery) []model.Author {
+	t.Helper()
+	as, err := s.ListAuthors(ctx, q)
+	if err != nil {
+		t.Fatalf("ListAuthors(%+v) failed: %s", q, err)
+	}
+	return as
+}
+
+func assertAuthors(t *testing.T, as []model.Author, want ...string) {
+	t.Helper()
+	got := make([]string, len(as))
+	for i, a := range as {
+		got[i] = fmt.Sprintf("%d:%s", a.ID, a.Surname)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("authors = %v, want %v", got, want)
+	}
+}
+
+func assertBookAuthors(t *testing.T, bas []model.BookAuthor, err error, want ...string) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("book authors failed: %s", err)
+		return
+	}
+	got := make([]string, len(bas))
+	for i, ba := range bas {
+		got[i] = fmt.Sprintf("%d:%s:%s:%d", ba.AuthorID, ba.Surname, ba.Role, ba.Position)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("book authors = %v, want %v", got, want)
+	}
+}
+
+func assertAuthorBooks(t *testing.T, abs []model.AuthorBook, err error, want ...string) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("ListAuthorBooks failed: %s", err)
+		return
+	}
+	got := make([]string, len(abs))
+	for i, ab := range abs {
+		got[i] = fmt.Sprintf("%d:%s:%d", ab.ID, ab.Role, ab.Position)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("author books = %v, want %v", got, want)
+	}
+}
+
+func mustPublishers(ctx context.Context, t *testing.T, s db.Storage, q *db.PublisherQuery) []model.Publisher {
+	t.Helper()
+	ps, err := s.ListPublishers(ctx, q)
+	if err != nil {
+		t.Fatalf("ListPublishers(%+v) failed: %s", q, err)
+	}
+	return ps
+}
+
+func assertPublishers(t *testing.T, ps []model.Publisher, want ...string) {
+	t.Helper()
+	got := make([]string, len(ps))
+	for i, p := range ps {
+		got[i] = fmt.Sprintf("%d:%s", p.ID, p.Name)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("publishers = %v, want %v", got, want)
+	}
+}
+
+// assertListed will check the ids of the listed books in order
+func assertListed(t *testing.T, op string, bks []model.Book, err error, ids ...int) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("%s failed: %s", op, err)
+		return
+	}
+	assertIDs(t, bks, ids...)
+}
+
+// assertHistory will check the operation, actor and version of every history entry in order
+func assertHistory(t *testing.T, hs []model.BookHistory, want ...string) {
+	t.Helper()
+	got := make([]string, len(hs))
+	for i, h := range hs {
+		got[i] = fmt.Sprintf("%s:%s:%d", h.Operation, h.Actor, h.Version)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+}
+
+// assertBook will check the book fields and that updated_at is set, the time itself depend on the storage clock
+func assertBook(t *testing.T, op string, got, want model.Book) {
+	t.Helper()
+	if got.UpdatedAt.IsZero() {
+		t.Errorf("%s book updated_at is not set", op)
+	}
+	got.UpdatedAt = want.UpdatedAt
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s book = %+v, want %+v", op, got, want)
+	}
+}
+
+func assertIDs(t *testing.T, bks []model.Book, ids ...int) {
+	t.Helper()
+	got := make([]int, len(bks))
+	for i, bk := range bks {
+		got[i] = bk.ID
+	}
+	if fmt.Sprint(got) != fmt.Sprint(ids) {
+		t.Errorf("book ids = %v, want %v", got, ids)
+	}
+}
+
+// insertIDs will return the new book ids of the insert results
+func insertIDs(results []db.InsertResult, err error) ([]int, error) {
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(results))
+	for i, r := range results {
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		ids[i] = r.ID
+	}
+	return ids, nil
+}
+
+// assertInsertResults will check which books are inserted, the failed ones need to be duplicate isbn errors
+func assertInsertResults(t *testing.T, op string, results []db.InsertResult, inserted ...bool) {
+	t.Helper()
+	if len(results) != len(inserted) {
+		t.Fatalf("%s results = %+v, want %d results", op, results, len(inserted))
+	}
+	for i, r := range results {
+		if inserted[i] && (r.ID == 0 || r.Err != nil) {
+			t.Errorf("%s result[%d] = %+v, want inserted", op, i, r)
+		}
+		if !inserted[i] && r.ID != 0 {
+			t.Errorf("%s result[%d] = %+v, want not inserted", op, i, r)
+		}
+	}
+}
+
+func assertInsertedIDs(t *testing.T, op string, ids []int, err error, want ...int) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s failed: %s", op, err)
+	}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("%s ids = %v, want %v", op, ids, want)
+	}
+}
+
+func assertRowsAffected(t *testing.T, op string, n int64, err error, want int64) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s failed: %s", op, err)
+	}
+	if n != want {
+		t.Errorf("%s rows affected = %d, want %d", op, n, want)
+	}
+}
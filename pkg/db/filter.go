@@ -0,0 +1,353 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"goapp/pkg/isbn"
+	"goapp/pkg/model"
+	"goapp/pkg/pubdate"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxConditions is the maximum number of conditions that a filter can have
+const maxConditions = 32
+
+// ErrInvalidFilter is returned when a filter condition is not a known book column with a valid operator and value
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Operator is the comparison of a filter condition
+type Operator string
+
+// Filter operators, contains and starts are case-insensitive
+const (
+	OpEq       Operator = "eq"
+	OpNe       Operator = "ne"
+	OpGt       Operator = "gt"
+	OpGte      Operator = "gte"
+	OpLt       Operator = "lt"
+	OpLte      Operator = "lte"
+	OpContains Operator = "contains"
+	OpStarts   Operator = "starts"
+)
+
+// operatorSQL are the sql comparison of the operators
+var operatorSQL = map[Operator]string{
+	OpEq: "=", OpNe: "<>", OpGt: ">", OpGte: ">=", OpLt: "<", OpLte: "<=",
+}
+
+// groupName is the allowed name of a filter group
+var groupName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Condition is a single comparison of the book column with the value.
+// The published value is a partial date that is compared with its period, like 1949 is every date from 1949 to 1950
+// so published=eq:1949 match 1949-06-08 and published=gt:1949 match from 1950.
+type Condition struct {
+	Column string
+	Op     Operator
+	Value  string
+}
+
+// Filter to define the books that are listed. A book has to match every condition
+// and at least one condition of every group. The deleted books are only listed with IncludeDeleted.
+type Filter struct {
+	Conditions     []Condition
+	Groups         [][]Condition
+	IncludeDeleted bool
+}
+
+// ParseFilter will parse the query parameters into the filter, the parameters in the skip list are ignored.
+// Every parameter is a book column with op:value like published=gte:1940, the value without a known operator
+// is matched with eq. The parameters that are prefixed with the same group name like any.title=contains:farm
+// are one group of conditions.
+func ParseFilter(params url.Values, skip ...string) (*Filter, error) {
+	skipped := map[string]bool{}
+	for _, k := range skip {
+		skipped[k] = true
+	}
+	// the keys are sorted so the filter and its sql are the same for the same parameters
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if !skipped[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	f := &Filter{}
+	groups := map[string]int{}
+	n := 0
+	for _, k := range keys {
+		group, col, grouped := strings.Cut(k, ".")
+		if !grouped {
+			col, group = group, ""
+		} else if !groupName.MatchString(group) {
+			return nil, fmt.Errorf("%w: group name of %q could only have letters, digits and _", ErrInvalidFilter, k)
+		}
+		for _, expr := range params[k] {
+			cond, err := ParseCondition(col, expr)
+			if err != nil {
+				return nil, err
+			}
+			if n++; n > maxConditions {
+				return nil, fmt.Errorf("%w: at most %d conditions are allowed", ErrInvalidFilter, maxConditions)
+			}
+			if !grouped {
+				f.Conditions = append(f.Conditions, cond)
+				continue
+			}
+			i, ok := groups[group]
+			if !ok {
+				i = len(f.Groups)
+				groups[group] = i
+				f.Groups = append(f.Groups, nil)
+			}
+			f.Groups[i] = append(f.Groups[i], cond)
+		}
+	}
+	return f, nil
+}
+
+// ParseCondition will parse the op:value expression of the book column into the condition
+func ParseCondition(col string, expr string) (Condition, error) {
+	if !IsBookColumn(col) {
+		return Condition{}, fmt.Errorf("%w: unknown field %q, field must be one of %s",
+			ErrInvalidFilter, col, strings.Join(BookColumns, ", "))
+	}
+	cond := Condition{Column: col, Op: OpEq, Value: expr}
+	if op, v, ok := strings.Cut(expr, ":"); ok && isOperator(Operator(op)) {
+		cond.Op, cond.Value = Operator(op), v
+	}
+	if col == "book_id" {
+		if cond.Op == OpContains || cond.Op == OpStarts {
+			return Condition{}, fmt.Errorf("%w: %s could not be used with book_id", ErrInvalidFilter, cond.Op)
+		}
+		if _, err := strconv.Atoi(cond.Value); err != nil {
+			return Condition{}, fmt.Errorf("%w: book_id value %q is not an integer", ErrInvalidFilter, cond.Value)
+		}
+	}
+	if col == "isbn" && cond.Op != OpContains && cond.Op != OpStarts {
+		// the isbn are stored as ISBN-13, every form of a valid isbn match the same book
+		if n, err := isbn.Normalize(cond.Value); err == nil {
+			cond.Value = n
+		}
+	}
+	if cond.isPeriod() {
+		d, err := pubdate.Normalize(cond.Value)
+		if err != nil {
+			return Condition{}, fmt.Errorf("%w: published value %q: %s", ErrInvalidFilter, cond.Value, err.Error())
+		}
+		cond.Value = d
+	}
+	return cond, nil
+}
+
+// isPeriod will check if the condition compare the published date with the period of the value
+func (c Condition) isPeriod() bool {
+	return c.Column == "published" && c.Op != OpContains && c.Op != OpStarts
+}
+
+// period will return the start of the published value period and the start of the next period
+func (c Condition) period() (string, string, error) {
+	d, err := pubdate.Parse(c.Value)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: published value %q: %s", ErrInvalidFilter, c.Value, err.Error())
+	}
+	return d.String(), d.Next().String(), nil
+}
+
+// IsEmpty will check if the filter does not have any condition
+func (f *Filter) IsEmpty() bool {
+	return f == nil || (len(f.Conditions) == 0 && len(f.Groups) == 0)
+}
+
+// includeDeleted will check if the deleted books are listed, they are not without a filter
+func (f *Filter) includeDeleted() bool {
+	return f != nil && f.IncludeDeleted
+}
+
+// where will return the sql condition and bind arguments of the filter, like is the case-insensitive match operator
+func (f *Filter) where(like string) (string, []interface{}, error) {
+	var ands []string
+	var args []interface{}
+	add := func(conds []Condition, sep string) error {
+		parts := make([]string, len(conds))
+		for i, cond := range conds {
+			sql, condArgs, err := cond.sql(like)
+			if err != nil {
+				return err
+			}
+			parts[i] = sql
+			args = append(args, condArgs...)
+		}
+		ands = append(ands, "("+strings.Join(parts, sep)+")")
+		return nil
+	}
+	if len(f.Conditions) > 0 {
+		if err := add(f.Conditions, " AND "); err != nil {
+			return "", nil, err
+		}
+	}
+	for _, g := range f.Groups {
+		if err := add(g, " OR "); err != nil {
+			return "", nil, err
+		}
+	}
+	return strings.Join(ands, " AND "), args, nil
+}
+
+// sql will return the sql comparison and bind arguments of the condition
+func (c Condition) sql(like string) (string, []interface{}, error) {
+	if !IsBookColumn(c.Column) {
+		return "", nil, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, c.Column)
+	}
+	var arg interface{} = c.Value
+	if c.Column == "book_id" {
+		id, err := strconv.Atoi(c.Value)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: book_id value %q is not an integer", ErrInvalidFilter, c.Value)
+		}
+		arg = id
+	}
+	switch c.Op {
+	case OpContains:
+		return fmt.Sprintf(`%s %s ? ESCAPE '\'`, c.Column, like), []interface{}{"%" + escapeLike(c.Value) + "%"}, nil
+	case OpStarts:
+		return fmt.Sprintf(`%s %s ? ESCAPE '\'`, c.Column, like), []interface{}{escapeLike(c.Value) + "%"}, nil
+	}
+	op, ok := operatorSQL[c.Op]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, c.Op)
+	}
+	if c.isPeriod() {
+		return c.periodSQL()
+	}
+	return fmt.Sprintf("%s %s ?", c.Column, op), []interface{}{arg}, nil
+}
+
+// periodSQL will return the sql comparison of the published date with the period of the value
+func (c Condition) periodSQL() (string, []interface{}, error) {
+	start, next, err := c.period()
+	if err != nil {
+		return "", nil, err
+	}
+	switch c.Op {
+	case OpEq:
+		return fmt.Sprintf("(%s >= ? AND %s < ?)", c.Column, c.Column), []interface{}{start, next}, nil
+	case OpNe:
+		return fmt.Sprintf("(%s < ? OR %s >= ?)", c.Column, c.Column), []interface{}{start, next}, nil
+	case OpGt:
+		return fmt.Sprintf("%s >= ?", c.Column), []interface{}{next}, nil
+	case OpGte:
+		return fmt.Sprintf("%s >= ?", c.Column), []interface{}{start}, nil
+	case OpLt:
+		return fmt.Sprintf("%s < ?", c.Column), []interface{}{start}, nil
+	}
+	return fmt.Sprintf("%s < ?", c.Column), []interface{}{next}, nil
+}
+
+// validate will check that every condition of the filter is valid
+func (f *Filter) validate() error {
+	if f.IsEmpty() {
+		return nil
+	}
+	_, _, err := f.where("LIKE")
+	return err
+}
+
+// match will check if the book match the filter
+func (f *Filter) match(bk *model.Book) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	for _, c := range f.Conditions {
+		if !c.match(bk) {
+			return false
+		}
+	}
+	for _, g := range f.Groups {
+		matched := false
+		for _, c := range g {
+			if c.match(bk) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// match will check if the book column match the condition
+func (c Condition) match(bk *model.Book) bool {
+	v, ok := bookColumn(bk, c.Column)
+	if !ok {
+		return false
+	}
+	if c.isPeriod() {
+		return c.matchPeriod(v.String())
+	}
+	var cmp int
+	if c.Column == "book_id" {
+		id, _ := strconv.Atoi(c.Value)
+		cmp = compareInt(bk.ID, id)
+	} else {
+		cmp = strings.Compare(v.String(), c.Value)
+	}
+	switch c.Op {
+	case OpEq:
+		return cmp == 0
+	case OpNe:
+		return cmp != 0
+	case OpGt:
+		return cmp > 0
+	case OpGte:
+		return cmp >= 0
+	case OpLt:
+		return cmp < 0
+	case OpLte:
+		return cmp <= 0
+	case OpContains:
+		return strings.Contains(strings.ToLower(v.String()), strings.ToLower(c.Value))
+	case OpStarts:
+		return strings.HasPrefix(strings.ToLower(v.String()), strings.ToLower(c.Value))
+	}
+	return false
+}
+
+// matchPeriod will check if the published date match the period of the value like the periodSQL comparison
+func (c Condition) matchPeriod(published string) bool {
+	start, next, err := c.period()
+	if err != nil {
+		return false
+	}
+	switch c.Op {
+	case OpEq:
+		return published >= start && published < next
+	case OpNe:
+		return published < start || published >= next
+	case OpGt:
+		return published >= next
+	case OpGte:
+		return published >= start
+	case OpLt:
+		return published < start
+	}
+	return published < next
+}
+
+// isOperator will check if the operator is one of the filter operators
+func isOperator(op Operator) bool {
+	_, ok := operatorSQL[op]
+	return ok || op == OpContains || op == OpStarts
+}
+
+// escapeLike will escape the LIKE wildcards of the value with \
+func escapeLike(v string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(v)
+}
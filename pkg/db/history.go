@@ -0,0 +1,322 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goapp/pkg/isbn"
+	"goapp/pkg/model"
+	"goapp/pkg/pubdate"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrHistoryNotFound is returned when the book does not have the history entry that it is reverted to
+	ErrHistoryNotFound = errors.New("book history not found")
+	// ErrInvalidISBN is returned when the isbn of the book history entry that the book is reverted to is not valid
+	ErrInvalidISBN = errors.New("isbn is not valid")
+	// ErrInvalidPublished is returned when the published of the book history entry that the book is reverted to
+	// is not a valid publication date
+	ErrInvalidPublished = errors.New("published is not a valid publication date")
+)
+
+// SystemActor is the actor of the changes that are not made on behalf of anyone, like the scheduled purge
+const SystemActor = "system"
+
+// actorKey is the context key of the actor that the changes are recorded for
+type actorKey struct{}
+
+// WithActor will return a copy of the context that record the book changes as made by the actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom will return the actor of the context, the SystemActor if there is none
+func actorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
+
+// HistoryQuery to define the book and the page of its history, the latest change first.
+// Only the changes that are made at or before Until are returned when it is set.
+type HistoryQuery struct {
+	BookID int
+	Until  time.Time
+	Limit  int
+	OffSet int
+}
+
+// historyRow is the book_history table row with the book snapshots as json
+type historyRow struct {
+	HistoryID int            `db:"history_id"`
+	BookID    int            `db:"book_id"`
+	Operation string         `db:"operation"`
+	Actor     string         `db:"actor"`
+	Version   int            `db:"version"`
+	ChangedAt time.Time      `db:"changed_at"`
+	OldBook   sql.NullString `db:"old_book"`
+	NewBook   sql.NullString `db:"new_book"`
+}
+
+// history will return the history entry of the row with the decoded book snapshots
+func (r historyRow) history() (model.BookHistory, error) {
+	h := model.BookHistory{HistoryID: r.HistoryID, BookID: r.BookID, Operation: r.Operation,
+		Actor: r.Actor, Version: r.Version, ChangedAt: r.ChangedAt}
+	var err error
+	if h.Before, err = decodeSnapshot(r.OldBook); err != nil {
+		return h, err
+	}
+	h.After, err = decodeSnapshot(r.NewBook)
+	return h, err
+}
+
+// encodeSnapshot will return the book as json, null if there is no book
+func encodeSnapshot(bk *model.Book) (sql.NullString, error) {
+	if bk == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(bk)
+	return sql.NullString{String: string(b), Valid: true}, err
+}
+
+// decodeSnapshot will return the book of the json, nil if it is null
+func decodeSnapshot(s sql.NullString) (*model.Book, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	var bk model.Book
+	if err := json.Unmarshal([]byte(s.String), &bk); err != nil {
+		return nil, fmt.Errorf("invalid book snapshot: %w", err)
+	}
+	return &bk, nil
+}
+
+// inTx will run fn in a transaction that is committed when fn return no error and rolled back otherwise
+func (s sqlStorage) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// snapshotBook will return the book with all the BookFields whatever it is deleted, nil if there is none.
+// The book row is locked until the transaction end when the database support it.
+func (s sqlStorage) snapshotBook(ctx context.Context, tx *sqlx.Tx, id int) (*model.Book, error) {
+	cols, _ := selectColumns("", BookFields)
+	var bk model.Book
+	query := fmt.Sprintf("SELECT %s FROM book WHERE book_id = ?%s", cols, s.forUpdate)
+	err := tx.QueryRowxContext(ctx, tx.Rebind(query), id).StructScan(&bk)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bk, nil
+}
+
+// recordHistory will append the change of the book from before to after to the book history,
+// as made by the actor of the context
+func recordHistory(ctx context.Context, tx *sqlx.Tx, op string, before, after *model.Book) error {
+	row := historyRow{Operation: op, Actor: actorFrom(ctx)}
+	var err error
+	if row.OldBook, err = encodeSnapshot(before); err != nil {
+		return err
+	}
+	if row.NewBook, err = encodeSnapshot(after); err != nil {
+		return err
+	}
+	if after != nil {
+		row.BookID, row.Version = after.ID, after.Version
+	} else if before != nil {
+		row.BookID, row.Version = before.ID, before.Version
+	}
+	query := "INSERT INTO book_history (book_id, operation, actor, version, old_book, new_book, changed_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)"
+	log.Debug().Msgf("recordHistory: %s %d %s %s", query, row.BookID, row.Operation, row.Actor)
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), row.BookID, row.Operation, row.Actor, row.Version, row.OldBook, row.NewBook)
+	return err
+}
+
+// changeBook will run the statement that change the book in a transaction,
+// and record the book before and after it in the book history when the book is changed
+func (s sqlStorage) changeBook(ctx context.Context, op string, id int, query string, args ...interface{}) (int64, error) {
+	var n int64
+	err := s.inTx(ctx, func(tx *sqlx.Tx) error {
+		before, err := s.snapshotBook(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+		if n, err = s.rowsAffected(result, err); err != nil || n == 0 {
+			return err
+		}
+		after, err := s.snapshotBook(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err = s.linkBookPublisher(ctx, tx, before, after); err != nil {
+			return err
+		}
+		if authorChanged(before, after) {
+			if err = s.linkBookAuthor(ctx, tx, after); err != nil {
+				return err
+			}
+		}
+		return recordHistory(ctx, tx, op, before, after)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ListBookHistory will return the page of the book history, the latest change first
+func (s sqlStorage) ListBookHistory(ctx context.Context, q *HistoryQuery) ([]model.BookHistory, error) {
+	query := "SELECT * FROM book_history WHERE book_id = ?"
+	args := []interface{}{q.BookID}
+	if !q.Until.IsZero() {
+		query += " AND changed_at <= ?"
+		args = append(args, q.Until.UTC())
+	}
+	query += " ORDER BY history_id DESC LIMIT ? OFFSET ?"
+	args = append(args, q.Limit, q.OffSet)
+	log.Debug().Msgf("ListBookHistory: %s %v", query, args)
+
+	var rows []historyRow
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	hs := make([]model.BookHistory, len(rows))
+	for i, row := range rows {
+		h, err := row.history()
+		if err != nil {
+			return nil, err
+		}
+		hs[i] = h
+	}
+	return hs, nil
+}
+
+// RevertBook will set the book fields back to the book after the history entry and return number of book that is reverted.
+// It will return ErrBookNotFound if the book is not there or deleted, ErrHistoryNotFound if the entry is not a change of the book
+// that left it behind, and ErrVersionConflict with a version that is not the current book version.
+func (s sqlStorage) RevertBook(ctx context.Context, id, historyID, version int) (int64, error) {
+	err := s.inTx(ctx, func(tx *sqlx.Tx) error {
+		before, err := s.snapshotBook(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if before == nil || before.DeletedAt != nil {
+			return ErrBookNotFound
+		}
+		if version != 0 && version != before.Version {
+			return ErrVersionConflict
+		}
+		var row historyRow
+		query := "SELECT * FROM book_history WHERE history_id = ? AND book_id = ?"
+		err = tx.GetContext(ctx, &row, tx.Rebind(query), historyID, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrHistoryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		h, err := row.history()
+		if err != nil {
+			return err
+		}
+		if h.After == nil {
+			return ErrHistoryNotFound
+		}
+
+		bk := *h.After
+		bk.ID = id
+		if err = normalizeSnapshot(&bk); err != nil {
+			return err
+		}
+		query = "UPDATE book SET isbn = :isbn, title = :title, author_name = :author_name, " +
+			"author_surname = :author_surname, published = :published, publisher = :publisher, " +
+			"version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE book_id = :book_id"
+		log.Debug().Msgf("RevertBook: %s %d %d", query, id, historyID)
+		if _, err = tx.NamedExecContext(ctx, query, bk); err != nil {
+			return s.mapError(err)
+		}
+		after, err := s.snapshotBook(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err = s.linkBookPublisher(ctx, tx, before, after); err != nil {
+			return err
+		}
+		if authorChanged(before, after) {
+			if err = s.linkBookAuthor(ctx, tx, after); err != nil {
+				return err
+			}
+		}
+		return recordHistory(ctx, tx, model.HistoryRevert, before, after)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// normalizeSnapshot will validate and normalize the isbn and published of the book that is reverted to like an update,
+// the book history from before the validation could have values that are not valid anymore
+func normalizeSnapshot(bk *model.Book) error {
+	n, err := isbn.Normalize(bk.ISBN)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidISBN, err.Error())
+	}
+	d, err := pubdate.Normalize(bk.Published)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPublished, err.Error())
+	}
+	bk.ISBN, bk.Published = n, d
+	return nil
+}
+
+// purgeBooks will remove the deleted books that match the condition for good and record them in the book history
+func (s sqlStorage) purgeBooks(ctx context.Context, cond string, args ...interface{}) (int64, error) {
+	cols, _ := selectColumns("", BookFields)
+	query := fmt.Sprintf("SELECT %s FROM book WHERE deleted_at IS NOT NULL AND %s%s", cols, cond, s.forUpdate)
+	log.Debug().Msgf("PurgeBooks: %s %v", query, args)
+	var n int64
+	err := s.inTx(ctx, func(tx *sqlx.Tx) error {
+		var bks []model.Book
+		if err := tx.SelectContext(ctx, &bks, tx.Rebind(query), args...); err != nil {
+			return err
+		}
+		for i := range bks {
+			if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM book_author WHERE book_id = ?"), bks[i].ID); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM book WHERE book_id = ?"), bks[i].ID); err != nil {
+				return err
+			}
+			if err := recordHistory(ctx, tx, model.HistoryPurge, &bks[i], nil); err != nil {
+				return err
+			}
+		}
+		n = int64(len(bks))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	log.Debug().Msgf("RowsAffected: %d", n)
+	return n, nil
+}
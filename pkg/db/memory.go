@@ -0,0 +1,1233 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"goapp/pkg/model"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MemoryStorage is a map-backed Storage that keep the books in memory only.
+// It is safe for concurrent use and is meant for tests and demo instances.
+// The book changes are recorded in history, the oldest change first.
+// The credits are the authors of every book by book_id in the credit order, without the author names.
+type MemoryStorage struct {
+	mu              sync.RWMutex
+	books           map[int]model.Book
+	nextID          int
+	history         []model.BookHistory
+	authors         map[int]model.Author
+	nextAuthorID    int
+	credits         map[int][]model.BookAuthor
+	publishers      map[int]model.Publisher
+	nextPublisherID int
+}
+
+var _ Database = (*MemoryStorage)(nil)
+
+// NewMemoryStorage to initialize the in-memory storage with optional books to start with.
+// The books will get a new book_id in the order they are passing through.
+func NewMemoryStorage(bks ...model.Book) *MemoryStorage {
+	s := &MemoryStorage{books: map[int]model.Book{}, nextID: 1,
+		authors: map[int]model.Author{}, nextAuthorID: 1, credits: map[int][]model.BookAuthor{},
+		publishers: map[int]model.Publisher{}, nextPublisherID: 1}
+	if _, err := s.InsertBooks(context.Background(), bks, AllOrNothing); err != nil {
+		log.Error().Err(err).Msg("NewMemoryStorage failed to insert books")
+	}
+	return s
+}
+
+// CloseDB is no-op since there is no connection to close
+func (s *MemoryStorage) CloseDB() {}
+
+// MigrateUp is no-op since there is no schema to migrate
+func (s *MemoryStorage) MigrateUp() error { return nil }
+
+// MigrateDown is no-op since there is no schema to migrate
+func (s *MemoryStorage) MigrateDown(steps int) error { return nil }
+
+// MigrationStatus will return no migration since there is no schema to migrate
+func (s *MemoryStorage) MigrationStatus() ([]MigrationStatus, error) { return nil, nil }
+
+// ListBooks will return all books with order by, page id and page size configuration that passing through.
+// With the keyset the books after or before the keyset book are returned instead of the offset page.
+func (s *MemoryStorage) ListBooks(ctx context.Context, p *PageList) ([]model.Book, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := orderClause(p.OrderBy, false); err != nil {
+		return nil, err
+	}
+	if err := p.Filter.validate(); err != nil {
+		return nil, err
+	}
+	if _, err := selectColumns("", p.Columns); err != nil {
+		return nil, err
+	}
+	bks := s.sortedBooks(p.OrderBy)
+	matched := bks[:0]
+	for _, bk := range bks {
+		if (bk.DeletedAt == nil || p.Filter.includeDeleted()) && p.Filter.match(&bk) {
+			matched = append(matched, bk)
+		}
+	}
+	bks = matched
+	if p.Keyset != nil {
+		values, err := keysetValues(p.OrderBy, p.Keyset)
+		if err != nil {
+			return nil, err
+		}
+		return pickColumns(keysetPage(bks, p, values), p.Columns), nil
+	}
+	if p.OffSet >= len(bks) {
+		return []model.Book{}, nil
+	}
+	end := len(bks)
+	if p.Limit >= 0 && p.OffSet+p.Limit < end {
+		end = p.OffSet + p.Limit
+	}
+	return pickColumns(bks[p.OffSet:end], p.Columns), nil
+}
+
+// CountBooks will return the number of books that match the filter, all books if the filter is empty
+func (s *MemoryStorage) CountBooks(ctx context.Context, f *Filter) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := f.validate(); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, bk := range s.books {
+		if (bk.DeletedAt == nil || f.includeDeleted()) && f.match(&bk) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// GetBooks will return all books that match with the filter that passing through
+func (s *MemoryStorage) GetBooks(ctx context.Context, f *BookFilter) ([]model.Book, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cols, args := f.Fields()
+	if len(cols) == 0 {
+		return nil, ErrEmptyFilter
+	}
+	if _, err := selectColumns("", f.Columns); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	bks := []model.Book{}
+	for _, bk := range s.sortedBooks(nil) {
+		if bk.DeletedAt != nil && !f.IncludeDeleted {
+			continue
+		}
+		matched := f.Mode == MatchAll
+		for i, col := range cols {
+			v, _ := bookColumn(&bk, col)
+			if f.Mode == MatchAny {
+				// case-insensitive contains like the sql LIKE %value% pattern
+				if strings.Contains(strings.ToLower(fmt.Sprint(v.Interface())), strings.ToLower(fmt.Sprint(args[i]))) {
+					matched = true
+					break
+				}
+			} else if v.Interface() != args[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			bks = append(bks, bk)
+		}
+	}
+	return pickColumns(bks, f.Columns), nil
+}
+
+// InsertBooks is able to insert single/multiple books that passing through
+// and will return the outcome of every book in the same order.
+// With AllOrNothing mode none of the books are inserted and ErrBatchRolledBack returned if any of them failed.
+func (s *MemoryStorage) InsertBooks(ctx context.Context, bks []model.Book, mode InsertMode) ([]InsertResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]InsertResult, len(bks))
+	isbns := map[string]bool{}
+	failed := false
+	for i, bk := range bks {
+		if isbns[bk.ISBN] || s.isbnUsed(bk.ISBN, 0) {
+			results[i].Err = ErrDuplicateISBN
+			failed = true
+			continue
+		}
+		if _, ok := s.publishers[bk.PublisherID]; bk.PublisherID != 0 && !ok {
+			results[i].Err = fmt.Errorf("%w: publisher_id %d", ErrUnknownPublisher, bk.PublisherID)
+			failed = true
+			continue
+		}
+		isbns[bk.ISBN] = true
+	}
+	if failed && mode == AllOrNothing {
+		return results, ErrBatchRolledBack
+	}
+
+	for i, bk := range bks {
+		if results[i].Err != nil {
+			continue
+		}
+		bk.ID, bk.Version, bk.UpdatedAt, bk.DeletedAt = s.nextID, 1, now(), nil
+		if err := s.resolvePublisher(&bk); err != nil {
+			return nil, err
+		}
+		s.books[bk.ID] = bk
+		s.record(ctx, model.HistoryInsert, nil, &bk)
+		s.linkBookAuthor(&bk)
+		results[i].ID = bk.ID
+		s.nextID++
+	}
+	return results, nil
+}
+
+// GetBook will return the book with the book_id or ErrBookNotFound if there is none or it is deleted.
+// Only the columns are selected, all columns if there is none.
+func (s *MemoryStorage) GetBook(ctx context.Context, id int, columns ...string) (model.Book, error) {
+	if err := ctx.Err(); err != nil {
+		return model.Book{}, err
+	}
+	if _, err := selectColumns("", columns); err != nil {
+		return model.Book{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bk, ok := s.books[id]
+	if !ok || bk.DeletedAt != nil {
+		return model.Book{}, ErrBookNotFound
+	}
+	return pickColumns([]model.Book{bk}, columns)[0], nil
+}
+
+// UpdateBooks will update single book and all the book fields are required except the publisher_id,
+// it will return number of book that is updated and return 0 if no book update.
+// With the book version it will return ErrVersionConflict if the book has another version.
+func (s *MemoryStorage) UpdateBooks(ctx context.Context, bk *model.Book) (int64, error) {
+	return s.updateBook(ctx, model.HistoryUpdate, bk)
+}
+
+// ApplyBookPatch will save the book that a patch document is applied to like UpdateBooks,
+// the change is recorded in the book history as a patch
+func (s *MemoryStorage) ApplyBookPatch(ctx context.Context, bk *model.Book) (int64, error) {
+	return s.updateBook(ctx, model.HistoryPatch, bk)
+}
+
+// updateBook will update every field of the book and record the change with the history operation
+func (s *MemoryStorage) updateBook(ctx context.Context, op string, bk *model.Book) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.books[bk.ID]
+	if !ok || old.DeletedAt != nil {
+		return 0, nil
+	}
+	if bk.Version != 0 && bk.Version != old.Version {
+		return 0, ErrVersionConflict
+	}
+	if s.isbnUsed(bk.ISBN, bk.ID) {
+		return 0, ErrDuplicateISBN
+	}
+	updated := *bk
+	updated.Version, updated.UpdatedAt, updated.DeletedAt = old.Version+1, now(), nil
+	if updated.PublisherID == 0 {
+		updated.PublisherID = old.PublisherID
+	}
+	if err := s.linkBookPublisher(&old, &updated); err != nil {
+		return 0, err
+	}
+	s.books[bk.ID] = updated
+	if authorChanged(&old, &updated) {
+		s.linkBookAuthor(&updated)
+	}
+	s.record(ctx, op, &old, &updated)
+	return 1, nil
+}
+
+// PatchBooks will patch single book and only book_id that is required,
+// other field that is empty or not define will be ignored
+// it will return number of book that is updated and return 0 if no book update.
+// With the book version it will return ErrVersionConflict if the book has another version.
+func (s *MemoryStorage) PatchBooks(ctx context.Context, pb *model.PatchBook) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bk, ok := s.books[pb.ID]
+	cols, args := nonEmptyFields(pb)
+	patched := 0
+	for _, col := range cols {
+		if col != "book_id" && col != "version" {
+			patched++
+		}
+	}
+	if !ok || bk.DeletedAt != nil || patched == 0 {
+		return 0, nil
+	}
+	if pb.Version != 0 && pb.Version != bk.Version {
+		return 0, ErrVersionConflict
+	}
+	if pb.ISBN != "" && s.isbnUsed(pb.ISBN, pb.ID) {
+		return 0, ErrDuplicateISBN
+	}
+	old := bk
+	for i, col := range cols {
+		if v, ok := bookColumn(&bk, col); ok && col != "book_id" && col != "version" {
+			v.Set(reflect.ValueOf(args[i]))
+		}
+	}
+	bk.Version, bk.UpdatedAt = bk.Version+1, now()
+	if err := s.linkBookPublisher(&old, &bk); err != nil {
+		return 0, err
+	}
+	s.books[pb.ID] = bk
+	if authorChanged(&old, &bk) {
+		s.linkBookAuthor(&bk)
+	}
+	s.record(ctx, model.HistoryPatch, &old, &bk)
+	return 1, nil
+}
+
+// DeleteBooks will mark a book id is matched as deleted and return number of book that is deleted and return 0 if no book delete
+func (s *MemoryStorage) DeleteBooks(ctx context.Context, id int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bk, ok := s.books[id]
+	if !ok || bk.DeletedAt != nil {
+		return 0, nil
+	}
+	old := bk
+	deleted := now()
+	bk.Version, bk.UpdatedAt, bk.DeletedAt = bk.Version+1, deleted, &deleted
+	s.books[id] = bk
+	s.record(ctx, model.HistoryDelete, &old, &bk)
+	return 1, nil
+}
+
+// RestoreBooks will unmark a deleted book id is matched and return number of book that is restored and return 0 if no book restore
+func (s *MemoryStorage) RestoreBooks(ctx context.Context, id int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bk, ok := s.books[id]
+	if !ok || bk.DeletedAt == nil {
+		return 0, nil
+	}
+	old := bk
+	bk.Version, bk.UpdatedAt, bk.DeletedAt = bk.Version+1, now(), nil
+	s.books[id] = bk
+	s.record(ctx, model.HistoryRestore, &old, &bk)
+	return 1, nil
+}
+
+// PurgeBooks will remove the books that are deleted longer than olderThan ago for good
+// and return number of book that is purged, the purged books are recorded in the book history
+func (s *MemoryStorage) PurgeBooks(ctx context.Context, olderThan time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := now().Add(-olderThan)
+	var n int64
+	for _, bk := range s.sortedBooks(nil) {
+		if bk.DeletedAt != nil && bk.DeletedAt.Before(before) {
+			delete(s.books, bk.ID)
+			delete(s.credits, bk.ID)
+			s.record(ctx, model.HistoryPurge, &bk, nil)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ListBookHistory will return the page of the book history, the latest change first
+func (s *MemoryStorage) ListBookHistory(ctx context.Context, q *HistoryQuery) ([]model.BookHistory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hs := []model.BookHistory{}
+	skipped := 0
+	for i := len(s.history) - 1; i >= 0 && (q.Limit < 0 || len(hs) < q.Limit); i-- {
+		h := s.history[i]
+		if h.BookID != q.BookID || (!q.Until.IsZero() && h.ChangedAt.After(q.Until)) {
+			continue
+		}
+		if skipped < q.OffSet {
+			skipped++
+			continue
+		}
+		hs = append(hs, h)
+	}
+	return hs, nil
+}
+
+// RevertBook will set the book fields back to the book after the history entry and return number of book that is reverted.
+// It will return ErrBookNotFound if the book is not there or deleted, ErrHistoryNotFound if the entry is not a change of the book
+// that left it behind, and ErrVersionConflict with a version that is not the current book version.
+func (s *MemoryStorage) RevertBook(ctx context.Context, id, historyID, version int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.books[id]
+	if !ok || old.DeletedAt != nil {
+		return 0, ErrBookNotFound
+	}
+	if version != 0 && version != old.Version {
+		return 0, ErrVersionConflict
+	}
+	var snapshot *model.Book
+	for _, h := range s.history {
+		if h.HistoryID == historyID && h.BookID == id {
+			snapshot = h.After
+		}
+	}
+	if snapshot == nil {
+		return 0, ErrHistoryNotFound
+	}
+	bk := *snapshot
+	if err := normalizeSnapshot(&bk); err != nil {
+		return 0, err
+	}
+	if s.isbnUsed(bk.ISBN, id) {
+		return 0, ErrDuplicateISBN
+	}
+	bk.ID, bk.Version, bk.UpdatedAt, bk.DeletedAt = id, old.Version+1, now(), nil
+	// the publisher_id is kept and the publisher of the snapshot is resolved like the sql storage does
+	bk.PublisherID = old.PublisherID
+	if err := s.linkBookPublisher(&old, &bk); err != nil {
+		return 0, err
+	}
+	s.books[id] = bk
+	if authorChanged(&old, &bk) {
+		s.linkBookAuthor(&bk)
+	}
+	s.record(ctx, model.HistoryRevert, &old, &bk)
+	return 1, nil
+}
+
+// record will append the change of the book from before to after to the history, as made by the actor of the context
+func (s *MemoryStorage) record(ctx context.Context, op string, before, after *model.Book) {
+	h := model.BookHistory{HistoryID: len(s.history) + 1, Operation: op, Actor: actorFrom(ctx), ChangedAt: now()}
+	if before != nil {
+		bk := *before
+		h.Before, h.BookID, h.Version = &bk, bk.ID, bk.Version
+	}
+	if after != nil {
+		bk := *after
+		h.After, h.BookID, h.Version = &bk, bk.ID, bk.Version
+	}
+	s.history = append(s.history, h)
+}
+
+// ListAuthors will return the page of the authors, ordered by surname, name and author_id
+func (s *MemoryStorage) ListAuthors(ctx context.Context, q *AuthorQuery) ([]model.Author, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	name := strings.ToLower(q.Name)
+	as := []model.Author{}
+	for _, a := range s.authors {
+		if strings.Contains(strings.ToLower(a.Name), name) || strings.Contains(strings.ToLower(a.Surname), name) {
+			as = append(as, a)
+		}
+	}
+	sort.Slice(as, func(i, j int) bool {
+		if as[i].Surname != as[j].Surname {
+			return as[i].Surname < as[j].Surname
+		}
+		if as[i].Name != as[j].Name {
+			return as[i].Name < as[j].Name
+		}
+		return as[i].ID < as[j].ID
+	})
+	if q.OffSet >= len(as) {
+		return []model.Author{}, nil
+	}
+	end := len(as)
+	if q.Limit >= 0 && q.OffSet+q.Limit < end {
+		end = q.OffSet + q.Limit
+	}
+	return as[q.OffSet:end], nil
+}
+
+// GetAuthor will return the author with the author_id or ErrAuthorNotFound if there is none
+func (s *MemoryStorage) GetAuthor(ctx context.Context, id int) (model.Author, error) {
+	if err := ctx.Err(); err != nil {
+		return model.Author{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.authors[id]
+	if !ok {
+		return model.Author{}, ErrAuthorNotFound
+	}
+	return a, nil
+}
+
+// InsertAuthor will insert the author and set its new author_id and updated_at
+func (s *MemoryStorage) InsertAuthor(ctx context.Context, a *model.Author) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a.ID, a.UpdatedAt = s.nextAuthorID, now()
+	s.authors[a.ID] = *a
+	s.nextAuthorID++
+	return nil
+}
+
+// UpdateAuthor will set the name and surname of the author and the author_name and author_surname
+// of the books that credit it first, the books are changed with a new version and recorded in the book history.
+// It will return ErrAuthorNotFound if there is no author with the author_id.
+func (s *MemoryStorage) UpdateAuthor(ctx context.Context, a *model.Author) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.authors[a.ID]
+	if !ok {
+		return ErrAuthorNotFound
+	}
+	old.Name, old.Surname, old.UpdatedAt = a.Name, a.Surname, now()
+	s.authors[a.ID] = old
+	for _, bk := range s.sortedBooks(nil) {
+		if bas := s.credits[bk.ID]; len(bas) > 0 && bas[0].Position == 1 && bas[0].AuthorID == a.ID {
+			s.syncBookAuthor(ctx, bk.ID)
+		}
+	}
+	return nil
+}
+
+// DeleteAuthor will remove the author, it will return ErrAuthorNotFound if there is no author with the author_id
+// and ErrAuthorHasBooks if any book credit the author, the deleted books too
+func (s *MemoryStorage) DeleteAuthor(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, bas := range s.credits {
+		for _, ba := range bas {
+			if ba.AuthorID == id {
+				return ErrAuthorHasBooks
+			}
+		}
+	}
+	if _, ok := s.authors[id]; !ok {
+		return ErrAuthorNotFound
+	}
+	delete(s.authors, id)
+	return nil
+}
+
+// GetBookAuthors will return the authors that the book credit in the credit order,
+// or ErrBookNotFound if there is no book with the book_id or it is deleted
+func (s *MemoryStorage) GetBookAuthors(ctx context.Context, bookID int) ([]model.BookAuthor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if bk, ok := s.books[bookID]; !ok || bk.DeletedAt != nil {
+		return nil, ErrBookNotFound
+	}
+	bas := []model.BookAuthor{}
+	for _, ba := range s.credits[bookID] {
+		a := s.authors[ba.AuthorID]
+		ba.Name, ba.Surname = a.Name, a.Surname
+		bas = append(bas, ba)
+	}
+	return bas, nil
+}
+
+// SetBookAuthors will replace the authors of the book with the authors in the credit order,
+// the author_name and author_surname of the book are set to the first author.
+// It will return ErrBookNotFound if there is no book with the book_id or it is deleted,
+// ErrAuthorNotFound if any of the authors is not there and ErrDuplicateBookAuthor if an author is listed twice with the same role.
+func (s *MemoryStorage) SetBookAuthors(ctx context.Context, bookID int, authors []model.BookAuthor) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	bas, err := bookAuthors(authors)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if bk, ok := s.books[bookID]; !ok || bk.DeletedAt != nil {
+		return ErrBookNotFound
+	}
+	for i, ba := range bas {
+		if _, ok := s.authors[ba.AuthorID]; !ok {
+			return fmt.Errorf("%w: author_id %d", ErrAuthorNotFound, ba.AuthorID)
+		}
+		bas[i].Name, bas[i].Surname = "", ""
+	}
+	s.credits[bookID] = bas
+	s.syncBookAuthor(ctx, bookID)
+	return nil
+}
+
+// ListAuthorBooks will return the page of the books that credit the author and are not deleted,
+// ordered by published date and book_id. It will return ErrAuthorNotFound if there is no author with the author_id.
+func (s *MemoryStorage) ListAuthorBooks(ctx context.Context, q *AuthorBooksQuery) ([]model.AuthorBook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.authors[q.AuthorID]; !ok {
+		return nil, ErrAuthorNotFound
+	}
+	abs := []model.AuthorBook{}
+	for _, bk := range s.sortedBooks([]SortField{{Column: "published"}}) {
+		if bk.DeletedAt != nil {
+			continue
+		}
+		var roles []model.AuthorBook
+		for _, ba := range s.credits[bk.ID] {
+			if ba.AuthorID == q.AuthorID {
+				roles = append(roles, model.AuthorBook{Book: bk, Role: ba.Role, Position: ba.Position})
+			}
+		}
+		sort.Slice(roles, func(i, j int) bool { return roles[i].Role < roles[j].Role })
+		abs = append(abs, roles...)
+	}
+	if q.OffSet >= len(abs) {
+		return []model.AuthorBook{}, nil
+	}
+	end := len(abs)
+	if q.Limit >= 0 && q.OffSet+q.Limit < end {
+		end = q.OffSet + q.Limit
+	}
+	return abs[q.OffSet:end], nil
+}
+
+// syncBookAuthor will set the author_name and author_surname of the book to its first author
+// with a new version that is recorded in the history, the book is left as it is when it has no author
+func (s *MemoryStorage) syncBookAuthor(ctx context.Context, bookID int) {
+	bk, ok := s.books[bookID]
+	bas := s.credits[bookID]
+	if !ok || len(bas) == 0 {
+		return
+	}
+	a := s.authors[bas[0].AuthorID]
+	if bk.AuthorName == a.Name && bk.AuthorSurname == a.Surname {
+		return
+	}
+	old := bk
+	bk.AuthorName, bk.AuthorSurname, bk.Version, bk.UpdatedAt = a.Name, a.Surname, bk.Version+1, now()
+	s.books[bookID] = bk
+	s.record(ctx, model.HistoryAuthors, &old, &bk)
+}
+
+// linkBookAuthor will credit the author of the book author_name and author_surname first with the role
+// of the author it replace, the author is created when there is no author with the same name and surname
+func (s *MemoryStorage) linkBookAuthor(bk *model.Book) {
+	authorID := 0
+	for id, a := range s.authors {
+		if a.Name == bk.AuthorName && a.Surname == bk.AuthorSurname && (authorID == 0 || id < authorID) {
+			authorID = id
+		}
+	}
+	if authorID == 0 {
+		authorID = s.nextAuthorID
+		s.authors[authorID] = model.Author{ID: authorID, Name: bk.AuthorName, Surname: bk.AuthorSurname, UpdatedAt: now()}
+		s.nextAuthorID++
+	}
+
+	bas := s.credits[bk.ID]
+	first := model.BookAuthor{Role: model.RoleAuthor}
+	if len(bas) > 0 && bas[0].Position == 1 {
+		first = bas[0]
+	}
+	if first.AuthorID == authorID {
+		return
+	}
+	credited := []model.BookAuthor{{AuthorID: authorID, Role: first.Role, Position: 1}}
+	for _, ba := range bas {
+		if ba.Position != 1 && (ba.AuthorID != authorID || ba.Role != first.Role) {
+			credited = append(credited, ba)
+		}
+	}
+	s.credits[bk.ID] = credited
+}
+
+// ListPublishers will return the page of the publishers with their aliases, ordered by name and publisher_id
+func (s *MemoryStorage) ListPublishers(ctx context.Context, q *PublisherQuery) ([]model.Publisher, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	name := strings.ToLower(q.Name)
+	ps := []model.Publisher{}
+	for _, p := range s.sortedPublishers() {
+		if q.ParentID != 0 && (p.ParentID == nil || *p.ParentID != q.ParentID) {
+			continue
+		}
+		matched := strings.Contains(strings.ToLower(p.Name), name)
+		for _, alias := range p.Aliases {
+			matched = matched || strings.Contains(strings.ToLower(alias), name)
+		}
+		if matched {
+			ps = append(ps, p)
+		}
+	}
+	sort.SliceStable(ps, func(i, j int) bool { return ps[i].Name < ps[j].Name })
+	if q.OffSet >= len(ps) {
+		return []model.Publisher{}, nil
+	}
+	end := len(ps)
+	if q.Limit >= 0 && q.OffSet+q.Limit < end {
+		end = q.OffSet + q.Limit
+	}
+	return ps[q.OffSet:end], nil
+}
+
+// GetPublisher will return the publisher with its aliases or ErrPublisherNotFound if there is none
+func (s *MemoryStorage) GetPublisher(ctx context.Context, id int) (model.Publisher, error) {
+	if err := ctx.Err(); err != nil {
+		return model.Publisher{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.publishers[id]
+	if !ok {
+		return model.Publisher{}, ErrPublisherNotFound
+	}
+	return copyPublisher(p), nil
+}
+
+// InsertPublisher will insert the publisher with its aliases and set its new publisher_id and updated_at.
+// It will return ErrDuplicatePublisher if the name or an alias is used by another publisher
+// and ErrUnknownPublisher if there is no parent publisher with the parent_id.
+func (s *MemoryStorage) InsertPublisher(ctx context.Context, p *model.Publisher) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cleanPublisher(p)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkParent(p); err != nil {
+		return err
+	}
+	if err := s.checkPublisherNames(p); err != nil {
+		return err
+	}
+	p.ID, p.UpdatedAt = s.nextPublisherID, now()
+	s.publishers[p.ID] = copyPublisher(*p)
+	s.nextPublisherID++
+	return nil
+}
+
+// UpdatePublisher will set the name, parent and aliases of the publisher and the publisher of its books,
+// the books are changed with a new version and recorded in the book history when the name is changed.
+// It will return ErrPublisherNotFound if there is no publisher with the publisher_id, ErrDuplicatePublisher
+// if the name or an alias is used by another publisher, ErrUnknownPublisher if there is no parent publisher
+// with the parent_id and ErrPublisherCycle if the parent is the publisher or one of its imprints.
+func (s *MemoryStorage) UpdatePublisher(ctx context.Context, p *model.Publisher) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cleanPublisher(p)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.publishers[p.ID]; !ok {
+		return ErrPublisherNotFound
+	}
+	if err := s.checkParent(p); err != nil {
+		return err
+	}
+	if err := s.checkPublisherNames(p); err != nil {
+		return err
+	}
+	updated := copyPublisher(*p)
+	updated.UpdatedAt = now()
+	s.publishers[p.ID] = updated
+	s.syncPublisherBooks(ctx, p.ID, updated)
+	return nil
+}
+
+// DeletePublisher will remove the publisher with its aliases, its imprints get its parent.
+// It will return ErrPublisherNotFound if there is no publisher with the publisher_id
+// and ErrPublisherHasBooks if any book is published by it, the deleted books too.
+func (s *MemoryStorage) DeletePublisher(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, bk := range s.books {
+		if bk.PublisherID == id {
+			return ErrPublisherHasBooks
+		}
+	}
+	p, ok := s.publishers[id]
+	if !ok {
+		return ErrPublisherNotFound
+	}
+	for _, imprint := range s.publishers {
+		if imprint.ParentID != nil && *imprint.ParentID == id {
+			imprint.ParentID = p.ParentID
+			s.publishers[imprint.ID] = imprint
+		}
+	}
+	delete(s.publishers, id)
+	return nil
+}
+
+// MergePublishers will merge the publishers into the publisher with the publisher_id. The names and aliases of the
+// merged publishers become its aliases, their imprints become its imprints and their books are published by it
+// with a new version that is recorded in the book history, then the merged publishers are removed.
+// It will return ErrPublisherNotFound if there is no publisher with the publisher_id,
+// ErrUnknownPublisher if any of the merged publishers is not there and ErrSelfMerge if it is one of them.
+func (s *MemoryStorage) MergePublishers(ctx context.Context, id int, sourceIDs []int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target, ok := s.publishers[id]
+	if !ok {
+		return ErrPublisherNotFound
+	}
+	target = copyPublisher(target)
+	parents := map[int]*int{}
+	var sources []model.Publisher
+	for _, sourceID := range sourceIDs {
+		if sourceID == id {
+			return ErrSelfMerge
+		}
+		src, ok := s.publishers[sourceID]
+		if !ok {
+			return fmt.Errorf("%w: publisher_id %d", ErrUnknownPublisher, sourceID)
+		}
+		if _, merged := parents[sourceID]; !merged {
+			parents[sourceID] = src.ParentID
+			sources = append(sources, src)
+		}
+	}
+	for _, src := range sources {
+		target.Aliases = append(append(target.Aliases, src.Name), src.Aliases...)
+	}
+	target.ParentID = mergedParent(target, parents)
+	target.UpdatedAt = now()
+	cleanPublisher(&target)
+	s.publishers[id] = target
+
+	for _, src := range sources {
+		for _, imprint := range s.publishers {
+			if imprint.ID != id && imprint.ParentID != nil && *imprint.ParentID == src.ID {
+				parentID := id
+				imprint.ParentID = &parentID
+				s.publishers[imprint.ID] = imprint
+			}
+		}
+		s.syncPublisherBooks(ctx, src.ID, target)
+	}
+	for _, src := range sources {
+		delete(s.publishers, src.ID)
+	}
+	return nil
+}
+
+// ListPublisherDuplicates will return the groups of publishers with names that look alike for review
+func (s *MemoryStorage) ListPublisherDuplicates(ctx context.Context) ([]model.PublisherCluster, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return clusterPublishers(s.sortedPublishers()), nil
+}
+
+// ListPublisherBooks will return the page of the books of the publisher that are not deleted,
+// ordered by published date and book_id. It will return ErrPublisherNotFound if there is no publisher with the publisher_id.
+func (s *MemoryStorage) ListPublisherBooks(ctx context.Context, q *PublisherBooksQuery) ([]model.Book, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.publishers[q.PublisherID]; !ok {
+		return nil, ErrPublisherNotFound
+	}
+	ids := map[int]bool{q.PublisherID: true}
+	for added := q.IncludeImprints; added; {
+		added = false
+		for _, p := range s.publishers {
+			if p.ParentID != nil && ids[*p.ParentID] && !ids[p.ID] {
+				ids[p.ID], added = true, true
+			}
+		}
+	}
+	bks := []model.Book{}
+	for _, bk := range s.sortedBooks([]SortField{{Column: "published"}}) {
+		if bk.DeletedAt == nil && ids[bk.PublisherID] {
+			bks = append(bks, bk)
+		}
+	}
+	if q.OffSet >= len(bks) {
+		return []model.Book{}, nil
+	}
+	end := len(bks)
+	if q.Limit >= 0 && q.OffSet+q.Limit < end {
+		end = q.OffSet + q.Limit
+	}
+	return bks[q.OffSet:end], nil
+}
+
+// sortedPublishers will return a copy of all publishers in the publisher_id order
+func (s *MemoryStorage) sortedPublishers() []model.Publisher {
+	ps := make([]model.Publisher, 0, len(s.publishers))
+	for _, p := range s.publishers {
+		ps = append(ps, copyPublisher(p))
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i].ID < ps[j].ID })
+	return ps
+}
+
+// copyPublisher will return the publisher with its own aliases, so the stored publisher is not changed through it.
+// The aliases are sorted like the sql storage return them.
+func copyPublisher(p model.Publisher) model.Publisher {
+	p.Aliases = append([]string{}, p.Aliases...)
+	sort.Strings(p.Aliases)
+	return p
+}
+
+// findPublisher will return the publisher that has the key as the key of its name or an alias
+func (s *MemoryStorage) findPublisher(key string) (model.Publisher, bool) {
+	for _, p := range s.sortedPublishers() {
+		for _, name := range append([]string{p.Name}, p.Aliases...) {
+			if publisherKey(name) == key {
+				return p, true
+			}
+		}
+	}
+	return model.Publisher{}, false
+}
+
+// checkParent will return ErrUnknownPublisher if the parent of the publisher does not exist
+// and ErrPublisherCycle if the publisher is the parent or one of its parents
+func (s *MemoryStorage) checkParent(p *model.Publisher) error {
+	for id := p.ParentID; id != nil; {
+		if *id == p.ID {
+			return ErrPublisherCycle
+		}
+		parent, ok := s.publishers[*id]
+		if !ok {
+			return fmt.Errorf("%w: parent_id %d", ErrUnknownPublisher, *id)
+		}
+		id = parent.ParentID
+	}
+	return nil
+}
+
+// checkPublisherNames will return ErrDuplicatePublisher if the name or an alias of the publisher is the name
+// or an alias of another publisher
+func (s *MemoryStorage) checkPublisherNames(p *model.Publisher) error {
+	for _, name := range append([]string{p.Name}, p.Aliases...) {
+		if other, ok := s.findPublisher(publisherKey(name)); ok && other.ID != p.ID {
+			return fmt.Errorf("%w: %q is used by publisher %d", ErrDuplicatePublisher, name, other.ID)
+		}
+	}
+	return nil
+}
+
+// syncPublisherBooks will set the books of the publisher with the publisher_id to the publisher p,
+// the changed books get a new version that is recorded in the history
+func (s *MemoryStorage) syncPublisherBooks(ctx context.Context, publisherID int, p model.Publisher) {
+	for _, bk := range s.sortedBooks(nil) {
+		if bk.PublisherID != publisherID || (bk.PublisherID == p.ID && bk.Publisher == p.Name) {
+			continue
+		}
+		old := bk
+		bk.Publisher, bk.PublisherID, bk.Version, bk.UpdatedAt = p.Name, p.ID, bk.Version+1, now()
+		s.books[bk.ID] = bk
+		s.record(ctx, model.HistoryPublisher, &old, &bk)
+	}
+}
+
+// resolvePublisher will set the publisher of the book to the name of the publisher with its publisher_id,
+// or to the publisher that has the publisher name as its name or alias when it has no publisher_id.
+// The publisher is created when there is none, and it will return ErrUnknownPublisher
+// if there is no publisher with the publisher_id.
+func (s *MemoryStorage) resolvePublisher(bk *model.Book) error {
+	if bk.PublisherID != 0 {
+		p, ok := s.publishers[bk.PublisherID]
+		if !ok {
+			return fmt.Errorf("%w: publisher_id %d", ErrUnknownPublisher, bk.PublisherID)
+		}
+		bk.Publisher = p.Name
+		return nil
+	}
+	key := publisherKey(bk.Publisher)
+	if key == "" {
+		return nil
+	}
+	p, ok := s.findPublisher(key)
+	if !ok {
+		p = model.Publisher{ID: s.nextPublisherID, Name: strings.Join(strings.Fields(bk.Publisher), " "),
+			Aliases: []string{}, UpdatedAt: now()}
+		s.publishers[p.ID] = p
+		s.nextPublisherID++
+	}
+	bk.PublisherID, bk.Publisher = p.ID, p.Name
+	return nil
+}
+
+// linkBookPublisher will resolve the publisher of the book after a change,
+// a changed publisher_id win over the publisher, and a changed publisher is resolved by the name
+func (s *MemoryStorage) linkBookPublisher(before, after *model.Book) error {
+	if before.PublisherID == after.PublisherID && before.Publisher == after.Publisher {
+		return nil
+	}
+	if after.PublisherID == before.PublisherID {
+		after.PublisherID = 0
+	}
+	return s.resolvePublisher(after)
+}
+
+// SearchBooks will return the books that match every search term,
+// ranked by the number of matches in every field multiplied with the field boost
+func (s *MemoryStorage) SearchBooks(ctx context.Context, q *SearchQuery) ([]SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(q.Terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+	if _, err := selectColumns("", q.Columns); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := []SearchResult{}
+	for _, bk := range s.sortedBooks(nil) {
+		if bk.DeletedAt != nil && !q.IncludeDeleted {
+			continue
+		}
+		if r, ok := searchBook(bk, q); ok {
+			results = append(results, r)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+	if q.OffSet >= len(results) {
+		return []SearchResult{}, nil
+	}
+	end := len(results)
+	if q.Limit >= 0 && q.OffSet+q.Limit < end {
+		end = q.OffSet + q.Limit
+	}
+	results = results[q.OffSet:end]
+	if len(q.Columns) > 0 {
+		for i := range results {
+			results[i].Book = pickColumns([]model.Book{results[i].Book}, q.Columns)[0]
+		}
+	}
+	return results, nil
+}
+
+// sortedBooks will return a copy of all books in the order and then by book_id
+func (s *MemoryStorage) sortedBooks(order []SortField) []model.Book {
+	bks := make([]model.Book, 0, len(s.books))
+	for _, bk := range s.books {
+		bks = append(bks, bk)
+	}
+	sort.Slice(bks, func(i, j int) bool {
+		for _, sf := range order {
+			if c := compareColumn(&bks[i], &bks[j], sf.Column); c != 0 {
+				return (c < 0) != sf.Desc
+			}
+		}
+		return bks[i].ID < bks[j].ID
+	})
+	return bks
+}
+
+// keysetPage will return the page of the sorted books that is right after or before the keyset values
+func keysetPage(bks []model.Book, p *PageList, values []interface{}) []model.Book {
+	start := sort.Search(len(bks), func(i int) bool { return compareKeyset(&bks[i], p.OrderBy, values) > 0 })
+	end := len(bks)
+	if p.Keyset.Before {
+		start, end = 0, sort.Search(len(bks), func(i int) bool { return compareKeyset(&bks[i], p.OrderBy, values) >= 0 })
+	}
+	if p.Limit >= 0 && end-start > p.Limit {
+		if p.Keyset.Before {
+			start = end - p.Limit
+		} else {
+			end = start + p.Limit
+		}
+	}
+	return append([]model.Book{}, bks[start:end]...)
+}
+
+// pickColumns will return a copy of the books with only the column fields set, the books as they are if there is no column
+func pickColumns(bks []model.Book, cols []string) []model.Book {
+	if len(cols) == 0 {
+		return bks
+	}
+	picked := make([]model.Book, len(bks))
+	for i := range bks {
+		for _, col := range cols {
+			if v, ok := bookColumn(&bks[i], col); ok {
+				dst, _ := bookColumn(&picked[i], col)
+				dst.Set(v)
+			}
+		}
+	}
+	return picked
+}
+
+// now will return the current time with the second precision of the sql CURRENT_TIMESTAMP
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// isbnUsed will check if the isbn belong to any book other than the book id
+func (s *MemoryStorage) isbnUsed(isbn string, id int) bool {
+	for _, bk := range s.books {
+		if bk.ISBN == isbn && bk.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+// bookColumn will return the addressable book field that is tagged with the db column name
+func bookColumn(bk *model.Book, col string) (reflect.Value, bool) {
+	v := reflect.ValueOf(bk).Elem()
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		if t.Field(i).Tag.Get("db") == col {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// compareColumn will compare the column value of two books and return -1, 0 or 1
+func compareColumn(a, b *model.Book, col string) int {
+	va, ok := bookColumn(a, col)
+	if !ok {
+		return 0
+	}
+	vb, _ := bookColumn(b, col)
+	switch va.Kind() {
+	case reflect.Int:
+		switch {
+		case va.Int() < vb.Int():
+			return -1
+		case va.Int() > vb.Int():
+			return 1
+		}
+		return 0
+	default:
+		return strings.Compare(va.String(), vb.String())
+	}
+}
+
+// searchBook will match the search terms against every search column of the book
+// and return the book with its rank and the highlighted text of the best matching column
+func searchBook(bk model.Book, q *SearchQuery) (SearchResult, bool) {
+	r := SearchResult{Book: bk}
+	found := make([]bool, len(q.Terms))
+	best := 0.0
+	for _, sc := range searchColumns {
+		v, _ := bookColumn(&bk, sc.col)
+		text := v.String()
+		spans := wordSpans(text)
+		words := make([]string, len(spans))
+		for i, sp := range spans {
+			words[i] = strings.ToLower(text[sp[0]:sp[1]])
+		}
+
+		var marks [][2]int
+		score := 0.0
+		for i, t := range q.Terms {
+			for _, m := range termMatches(words, t) {
+				found[i] = true
+				score += q.boost(sc.field)
+				marks = append(marks, [2]int{spans[m][0], spans[m+len(t.Tokens)-1][1]})
+			}
+		}
+		r.Rank += score
+		if score > best {
+			best = score
+			r.Snippet = highlight(text, marks)
+		}
+	}
+	for _, ok := range found {
+		if !ok {
+			return r, false
+		}
+	}
+	return r, true
+}
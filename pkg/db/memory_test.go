@@ -0,0 +1,13 @@
+package db_test
+
+import (
+	"goapp/pkg/db"
+	"goapp/pkg/db/dbtest"
+	"testing"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	dbtest.RunStorageSuite(t, func(t *testing.T) db.Storage {
+		return db.NewMemoryStorage()
+	})
+}
@@ -0,0 +1,461 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"goapp/pkg/isbn"
+	"goapp/pkg/model"
+	"goapp/pkg/pubdate"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// Migration is a single versioned schema change with the statements to apply and revert it.
+// Data is the change of the existing rows that could not be done in sql, it is run after the Up statements
+// in the same transaction.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	Data    func(tx *sqlx.Tx) error
+}
+
+// migrationData are the data changes of the migrations by version
+var migrationData = map[int]func(tx *sqlx.Tx) error{
+	6: normalizePublished,
+	7: migrateAuthors,
+	8: migratePublishers,
+	9: normalizeISBNs,
+}
+
+// MigrationStatus to show whether a migration is applied to the database and when
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrations will return the embedded migrations of the sql dialect ordered by version.
+// The migration files are located in migrations/<dialect> and named as <version>_<name>.up.sql
+// and <version>_<name>.down.sql, every dialect need to provide the same versions.
+func Migrations(dialect string) ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, path.Join("migrations", dialect, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, f := range files {
+		base := path.Base(f)
+		parts := strings.SplitN(strings.TrimSuffix(base, ".sql"), "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid migration file name: %s", base)
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", base)
+		}
+		name, direction := parts[1], path.Ext(parts[1])
+		name = strings.TrimSuffix(name, direction)
+
+		data, err := migrationFiles.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		switch direction {
+		case ".up":
+			m.Up = string(data)
+		case ".down":
+			m.Down = string(data)
+		default:
+			return nil, fmt.Errorf("invalid migration direction: %s", base)
+		}
+	}
+
+	if len(byVersion) == 0 {
+		return nil, fmt.Errorf("no migrations found for dialect: %s", dialect)
+	}
+	var ms []Migration
+	for _, m := range byVersion {
+		m.Data = migrationData[m.Version]
+		ms = append(ms, *m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	return ms, nil
+}
+
+// MigrateUp will apply every migration that is not yet recorded in the schema_version table
+func MigrateUp(db *sqlx.DB, dialect string) error {
+	ms, applied, err := loadMigrationState(db, dialect)
+	if err != nil {
+		return err
+	}
+	for _, m := range ms {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		log.Info().Msgf("applying migration %04d_%s", m.Version, m.Name)
+		if err = runMigration(db, m.Up, m.Data, "INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)",
+			m.Version, m.Name, time.Now().UTC()); err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// MigrateDown will revert the given number of latest applied migrations
+func MigrateDown(db *sqlx.DB, dialect string, steps int) error {
+	ms, applied, err := loadMigrationState(db, dialect)
+	if err != nil {
+		return err
+	}
+	for i := len(ms) - 1; i >= 0 && steps > 0; i-- {
+		m := ms[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		log.Info().Msgf("reverting migration %04d_%s", m.Version, m.Name)
+		if err = runMigration(db, m.Down, nil, "DELETE FROM schema_version WHERE version = ?", m.Version); err != nil {
+			return fmt.Errorf("revert %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		steps--
+	}
+	return nil
+}
+
+// GetMigrationStatus will return every known migration and whether it is applied to the database
+func GetMigrationStatus(db *sqlx.DB, dialect string) ([]MigrationStatus, error) {
+	ms, applied, err := loadMigrationState(db, dialect)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]MigrationStatus, len(ms))
+	for i, m := range ms {
+		at, ok := applied[m.Version]
+		status[i] = MigrationStatus{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: at}
+	}
+	return status, nil
+}
+
+// loadMigrationState will make sure the schema_version table exists and return the embedded migrations
+// with the applied versions
+func loadMigrationState(db *sqlx.DB, dialect string) ([]Migration, map[int]time.Time, error) {
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS schema_version (" +
+		"version INTEGER PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)"); err != nil {
+		return nil, nil, err
+	}
+	ms, err := Migrations(dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []struct {
+		Version   int       `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	if err = db.Select(&rows, "SELECT version, applied_at FROM schema_version"); err != nil {
+		return nil, nil, err
+	}
+	applied := map[int]time.Time{}
+	for _, r := range rows {
+		applied[r.Version] = r.AppliedAt
+	}
+	return ms, applied, nil
+}
+
+// runMigration will execute the migration statements, the data change if any
+// and the schema_version bookkeeping in one transaction
+func runMigration(db *sqlx.DB, stmts string, data func(tx *sqlx.Tx) error, record string, args ...interface{}) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(stmts); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if data != nil {
+		if err = data(tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if _, err = tx.Exec(db.Rebind(record), args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// normalizePublished will convert the published dates of the books to the ISO 8601 form of pubdate,
+// like 1949-06 for June 1949. The original and converted values are kept in book_published_backup
+// so the migration could be reverted, the dates that could not be parsed are left as they are and reported.
+func normalizePublished(tx *sqlx.Tx) error {
+	var rows []struct {
+		ID        int            `db:"book_id"`
+		Published sql.NullString `db:"published"`
+	}
+	if err := tx.Select(&rows, "SELECT book_id, published FROM book ORDER BY book_id"); err != nil {
+		return err
+	}
+	backup := tx.Rebind("INSERT INTO book_published_backup (book_id, published, converted) VALUES (?, ?, ?)")
+	update := tx.Rebind("UPDATE book SET published = ? WHERE book_id = ?")
+	converted, unparseable := 0, 0
+	for _, r := range rows {
+		if !r.Published.Valid || strings.TrimSpace(r.Published.String) == "" {
+			continue
+		}
+		d, err := pubdate.Normalize(r.Published.String)
+		if err != nil {
+			log.Warn().Msgf("book %d published %q could not be converted: %s", r.ID, r.Published.String, err.Error())
+			unparseable++
+			continue
+		}
+		if d == r.Published.String {
+			continue
+		}
+		if _, err = tx.Exec(backup, r.ID, r.Published.String, d); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(update, d, r.ID); err != nil {
+			return err
+		}
+		converted++
+	}
+	log.Info().Msgf("converted %d published dates of %d books, %d could not be parsed", converted, len(rows), unparseable)
+	return nil
+}
+
+// normalizeISBNs will convert the isbn of the books from before the isbn validation to the ISBN-13 digits.
+// The original and converted values are kept in book_isbn_backup so the migration could be reverted,
+// the isbn that is not valid, like a wrong check digit, or whose ISBN-13 is used by another book is left as it is
+// and reported in book_isbn_backup with the problem and without the converted value.
+func normalizeISBNs(tx *sqlx.Tx) error {
+	var rows []struct {
+		ID   int            `db:"book_id"`
+		ISBN sql.NullString `db:"isbn"`
+	}
+	if err := tx.Select(&rows, "SELECT book_id, isbn FROM book ORDER BY book_id"); err != nil {
+		return err
+	}
+	used := map[string]bool{}
+	for _, r := range rows {
+		used[r.ISBN.String] = true
+	}
+	backup := tx.Rebind("INSERT INTO book_isbn_backup (book_id, isbn, converted, problem) VALUES (?, ?, ?, ?)")
+	update := tx.Rebind("UPDATE book SET isbn = ? WHERE book_id = ?")
+	converted, invalid := 0, 0
+	for _, r := range rows {
+		if !r.ISBN.Valid || strings.TrimSpace(r.ISBN.String) == "" {
+			continue
+		}
+		n, err := isbn.Normalize(r.ISBN.String)
+		if err == nil && n != r.ISBN.String && used[n] {
+			err = fmt.Errorf("ISBN-13 %s is used by another book", n)
+		}
+		if err != nil {
+			log.Warn().Msgf("book %d isbn %q could not be converted: %s", r.ID, r.ISBN.String, err.Error())
+			if _, err = tx.Exec(backup, r.ID, r.ISBN.String, nil, err.Error()); err != nil {
+				return err
+			}
+			invalid++
+			continue
+		}
+		if n == r.ISBN.String {
+			continue
+		}
+		if _, err = tx.Exec(backup, r.ID, r.ISBN.String, n, nil); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(update, n, r.ID); err != nil {
+			return err
+		}
+		used[n] = true
+		converted++
+	}
+	log.Info().Msgf("converted %d isbn of %d books, %d are not valid and reported in book_isbn_backup", converted, len(rows), invalid)
+	return nil
+}
+
+// migrateAuthors will create an author for every author_name and author_surname of the books and credit it first.
+// The names that only differ in the case and spaces are the same author with the most used spelling,
+// the other spellings are reported so the author_name and author_surname of their books could be reviewed.
+func migrateAuthors(tx *sqlx.Tx) error {
+	var rows []struct {
+		ID      int            `db:"book_id"`
+		Name    sql.NullString `db:"author_name"`
+		Surname sql.NullString `db:"author_surname"`
+	}
+	if err := tx.Select(&rows, "SELECT book_id, author_name, author_surname FROM book ORDER BY book_id"); err != nil {
+		return err
+	}
+	type cluster struct {
+		spellings map[[2]string]int
+		order     [][2]string
+		books     []int
+	}
+	clean := func(s string) string { return strings.Join(strings.Fields(s), " ") }
+	var keys []string
+	clusters := map[string]*cluster{}
+	for _, r := range rows {
+		spelling := [2]string{clean(r.Name.String), clean(r.Surname.String)}
+		if spelling[1] == "" {
+			log.Warn().Msgf("book %d has no author_surname, it is not credited to an author", r.ID)
+			continue
+		}
+		key := strings.ToLower(spelling[0] + "\x00" + spelling[1])
+		c, ok := clusters[key]
+		if !ok {
+			c = &cluster{spellings: map[[2]string]int{}}
+			clusters[key] = c
+			keys = append(keys, key)
+		}
+		if c.spellings[spelling] == 0 {
+			c.order = append(c.order, spelling)
+		}
+		c.spellings[spelling]++
+		c.books = append(c.books, r.ID)
+	}
+
+	insert := tx.Rebind("INSERT INTO author (name, surname, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP) RETURNING author_id")
+	credit := tx.Rebind("INSERT INTO book_author (book_id, author_id, role, position) VALUES (?, ?, 'author', 1)")
+	merged := 0
+	for _, key := range keys {
+		c := clusters[key]
+		// the most used spelling is the author name, the first one of the books when they are used as much
+		name := c.order[0]
+		for _, sp := range c.order {
+			if c.spellings[sp] > c.spellings[name] {
+				name = sp
+			}
+		}
+		for _, sp := range c.order {
+			if sp != name {
+				log.Warn().Msgf("author %q %q is merged into %q %q", sp[0], sp[1], name[0], name[1])
+				merged++
+			}
+		}
+		var authorID int
+		if err := tx.QueryRowx(insert, name[0], name[1]).Scan(&authorID); err != nil {
+			return err
+		}
+		for _, id := range c.books {
+			if _, err := tx.Exec(credit, id, authorID); err != nil {
+				return err
+			}
+		}
+	}
+	log.Info().Msgf("created %d authors of %d books, %d spellings were merged", len(keys), len(rows), merged)
+	return nil
+}
+
+// migratePublishers will create a publisher for every publisher of the books and set the books to it.
+// The names that only differ in the case, spaces and punctuation are the same publisher with the most used spelling,
+// the books with the other spellings get it and their original publisher is kept in book_publisher_backup.
+// The publishers with names that look alike are reported for review, they could be merged with the api.
+func migratePublishers(tx *sqlx.Tx) error {
+	var rows []struct {
+		ID        int            `db:"book_id"`
+		Publisher sql.NullString `db:"publisher"`
+	}
+	if err := tx.Select(&rows, "SELECT book_id, publisher FROM book ORDER BY book_id"); err != nil {
+		return err
+	}
+	type book struct {
+		id        int
+		publisher string
+	}
+	type cluster struct {
+		spellings map[string]int
+		order     []string
+		books     []book
+	}
+	var keys []string
+	clusters := map[string]*cluster{}
+	for _, r := range rows {
+		spelling := strings.Join(strings.Fields(r.Publisher.String), " ")
+		key := publisherKey(spelling)
+		if key == "" {
+			log.Warn().Msgf("book %d has no publisher", r.ID)
+			continue
+		}
+		c, ok := clusters[key]
+		if !ok {
+			c = &cluster{spellings: map[string]int{}}
+			clusters[key] = c
+			keys = append(keys, key)
+		}
+		if c.spellings[spelling] == 0 {
+			c.order = append(c.order, spelling)
+		}
+		c.spellings[spelling]++
+		// the original value is kept to be restored, it could have other spaces than the spelling
+		c.books = append(c.books, book{id: r.ID, publisher: r.Publisher.String})
+	}
+
+	insert := tx.Rebind("INSERT INTO publisher (name, name_key, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP) RETURNING publisher_id")
+	backup := tx.Rebind("INSERT INTO book_publisher_backup (book_id, publisher, converted) VALUES (?, ?, ?)")
+	update := tx.Rebind("UPDATE book SET publisher = ?, publisher_id = ? WHERE book_id = ?")
+	var ps []model.Publisher
+	merged := 0
+	for _, key := range keys {
+		c := clusters[key]
+		// the most used spelling is the publisher name, the first one of the books when they are used as much
+		name := c.order[0]
+		for _, sp := range c.order {
+			if c.spellings[sp] > c.spellings[name] {
+				name = sp
+			}
+		}
+		for _, sp := range c.order {
+			if sp != name {
+				log.Warn().Msgf("publisher %q is merged into %q", sp, name)
+				merged++
+			}
+		}
+		p := model.Publisher{Name: name}
+		if err := tx.QueryRowx(insert, name, key).Scan(&p.ID); err != nil {
+			return err
+		}
+		ps = append(ps, p)
+		for _, bk := range c.books {
+			if bk.publisher != name {
+				if _, err := tx.Exec(backup, bk.id, bk.publisher, name); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.Exec(update, name, p.ID, bk.id); err != nil {
+				return err
+			}
+		}
+	}
+	alike := clusterPublishers(ps)
+	for _, pc := range alike {
+		names := make([]string, len(pc.Publishers))
+		for i, p := range pc.Publishers {
+			names[i] = fmt.Sprintf("%q (%d)", p.Name, p.ID)
+		}
+		log.Warn().Msgf("publishers %s look alike, review them and merge the duplicates", strings.Join(names, ", "))
+	}
+	log.Info().Msgf("created %d publishers of %d books, %d spellings were merged and %d groups of publishers look alike",
+		len(keys), len(rows), merged, len(alike))
+	return nil
+}
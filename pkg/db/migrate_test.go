@@ -0,0 +1,73 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeISBNsMigration(t *testing.T) {
+	s, err := OpenSqliteStorage(filepath.Join(t.TempDir(), "book.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.CloseDB)
+	if err = s.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.MigrateDown(1); err != nil {
+		t.Fatal(err)
+	}
+	// the books from before the isbn validation
+	isbns := []string{"9780451524935", "0-306-40615-2", "9780451524936", "0451524934", "ISBN 978-0-451-52634-2"}
+	for _, v := range isbns {
+		if _, err = s.db.Exec("INSERT INTO book (isbn, title, author_name, author_surname, published, publisher) "+
+			"VALUES (?, 'T', 'A', 'B', '1949', 'P')", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = s.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"9780451524935", "9780306406157", "9780451524936", "0451524934", "9780451526342"}
+	var got []string
+	if err = s.db.Select(&got, "SELECT isbn FROM book ORDER BY book_id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("isbn = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("book %d isbn = %q, want %q", i+1, got[i], want[i])
+		}
+	}
+	var backup []struct {
+		ID        int            `db:"book_id"`
+		Converted sql.NullString `db:"converted"`
+		Problem   sql.NullString `db:"problem"`
+	}
+	if err = s.db.Select(&backup, "SELECT book_id, converted, problem FROM book_isbn_backup ORDER BY book_id"); err != nil {
+		t.Fatal(err)
+	}
+	// the wrong check digit and the ISBN-10 of the first book are reported without the converted isbn
+	if len(backup) != 4 || backup[0].ID != 2 || !backup[0].Converted.Valid || backup[1].ID != 3 ||
+		backup[1].Converted.Valid || !backup[1].Problem.Valid || backup[2].ID != 4 || backup[2].Converted.Valid ||
+		!backup[2].Problem.Valid || backup[3].ID != 5 || !backup[3].Converted.Valid {
+		t.Errorf("book_isbn_backup = %+v, want books 2 and 5 converted and books 3 and 4 reported", backup)
+	}
+
+	if err = s.MigrateDown(1); err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	if err = s.db.Select(&got, "SELECT isbn FROM book ORDER BY book_id"); err != nil {
+		t.Fatal(err)
+	}
+	for i := range isbns {
+		if i < len(got) && got[i] != isbns[i] {
+			t.Errorf("book %d isbn = %q after the down migration, want %q", i+1, got[i], isbns[i])
+		}
+	}
+}
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS book_search_sync ON book;
+DROP FUNCTION IF EXISTS book_search_sync();
+DROP TABLE IF EXISTS book_search;
+DROP FUNCTION IF EXISTS book_search_document(book);
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS book_search (
+	book_id	INTEGER PRIMARY KEY REFERENCES book (book_id) ON DELETE CASCADE,
+	document	TSVECTOR NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS book_search_document_idx ON book_search USING GIN (document);
+
+-- Weight labels are used for the per-field boosting: title A, author B, publisher C, isbn D
+CREATE OR REPLACE FUNCTION book_search_document(b book) RETURNS TSVECTOR AS $$
+	SELECT setweight(to_tsvector('simple', coalesce(b.title, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(b.author_name, '') || ' ' || coalesce(b.author_surname, '')), 'B') ||
+		setweight(to_tsvector('simple', coalesce(b.publisher, '')), 'C') ||
+		setweight(to_tsvector('simple', coalesce(b.isbn, '')), 'D')
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION book_search_sync() RETURNS TRIGGER AS $$
+BEGIN
+	INSERT INTO book_search (book_id, document) VALUES (NEW.book_id, book_search_document(NEW))
+	ON CONFLICT (book_id) DO UPDATE SET document = EXCLUDED.document;
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS book_search_sync ON book;
+CREATE TRIGGER book_search_sync AFTER INSERT OR UPDATE ON book
+	FOR EACH ROW EXECUTE FUNCTION book_search_sync();
+
+INSERT INTO book_search (book_id, document)
+SELECT book_id, book_search_document(book) FROM book
+ON CONFLICT (book_id) DO NOTHING;
@@ -0,0 +1,3 @@
+ALTER TABLE book
+	DROP COLUMN updated_at,
+	DROP COLUMN version;
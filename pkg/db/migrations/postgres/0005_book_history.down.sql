@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS book_history_book_id_idx;
+DROP TABLE IF EXISTS book_history;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS book_history (
+	history_id	BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	book_id	INTEGER NOT NULL,
+	operation	VARCHAR(20) NOT NULL,
+	actor	VARCHAR(100) NOT NULL DEFAULT '',
+	version	INTEGER NOT NULL DEFAULT 0,
+	old_book	JSONB,
+	new_book	JSONB,
+	changed_at	TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS book_history_book_id_idx ON book_history (book_id, history_id);
@@ -0,0 +1,7 @@
+UPDATE book SET published = (
+	SELECT b.published FROM book_published_backup b WHERE b.book_id = book.book_id
+) WHERE EXISTS (
+	SELECT 1 FROM book_published_backup b
+	WHERE b.book_id = book.book_id AND b.converted = book.published
+);
+DROP TABLE IF EXISTS book_published_backup;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS book_published_backup (
+	book_id	INTEGER PRIMARY KEY,
+	published	VARCHAR(50) NOT NULL,
+	converted	VARCHAR(50) NOT NULL
+);
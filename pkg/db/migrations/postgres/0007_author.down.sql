@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS book_author_author_id_idx;
+DROP TABLE IF EXISTS book_author;
+DROP INDEX IF EXISTS author_surname_idx;
+DROP TABLE IF EXISTS author;
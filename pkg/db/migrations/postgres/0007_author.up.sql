@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS author (
+	author_id	INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	name	VARCHAR(50) NOT NULL DEFAULT '',
+	surname	VARCHAR(50) NOT NULL,
+	updated_at	TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS author_surname_idx ON author (surname, name);
+CREATE TABLE IF NOT EXISTS book_author (
+	book_id	INTEGER NOT NULL REFERENCES book (book_id) ON DELETE CASCADE,
+	author_id	INTEGER NOT NULL REFERENCES author (author_id),
+	role	VARCHAR(20) NOT NULL DEFAULT 'author',
+	position	INTEGER NOT NULL,
+	PRIMARY KEY (book_id, author_id, role)
+);
+CREATE INDEX IF NOT EXISTS book_author_author_id_idx ON book_author (author_id, book_id);
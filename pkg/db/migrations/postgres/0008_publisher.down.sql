@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS book_publisher_id_idx;
+ALTER TABLE book DROP COLUMN publisher_id;
+UPDATE book SET publisher = (
+	SELECT b.publisher FROM book_publisher_backup b WHERE b.book_id = book.book_id
+) WHERE EXISTS (
+	SELECT 1 FROM book_publisher_backup b
+	WHERE b.book_id = book.book_id AND b.converted = book.publisher
+);
+DROP TABLE IF EXISTS book_publisher_backup;
+DROP INDEX IF EXISTS publisher_alias_publisher_id_idx;
+DROP TABLE IF EXISTS publisher_alias;
+DROP INDEX IF EXISTS publisher_parent_id_idx;
+DROP INDEX IF EXISTS publisher_name_key_idx;
+DROP TABLE IF EXISTS publisher;
@@ -0,0 +1,7 @@
+UPDATE book SET isbn = (
+	SELECT b.isbn FROM book_isbn_backup b WHERE b.book_id = book.book_id
+) WHERE EXISTS (
+	SELECT 1 FROM book_isbn_backup b
+	WHERE b.book_id = book.book_id AND b.converted = book.isbn
+);
+DROP TABLE IF EXISTS book_isbn_backup;
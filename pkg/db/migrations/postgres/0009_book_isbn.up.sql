@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS book_isbn_backup (
+	book_id	INTEGER PRIMARY KEY,
+	isbn	VARCHAR(50) NOT NULL,
+	converted	VARCHAR(50),
+	problem	VARCHAR(100)
+);
+-- converted is null for the isbn that is left as it is, the problem is why it could not be converted
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS "book" (
+	"book_id"	INTEGER,
+	"isbn"	VARCHAR(50) UNIQUE,
+	"title"	VARCHAR(50),
+	"author_name"	VARCHAR(50),
+	"author_surname"	VARCHAR(50),
+	"published"	VARCHAR(50),
+	"publisher"	VARCHAR(50),
+	PRIMARY KEY("book_id" AUTOINCREMENT)
+);
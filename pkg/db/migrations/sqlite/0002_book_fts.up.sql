@@ -0,0 +1,23 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS book_fts USING fts5(
+	title, author_name, author_surname, publisher, isbn,
+	content='book', content_rowid='book_id', tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS book_fts_insert AFTER INSERT ON book BEGIN
+	INSERT INTO book_fts (rowid, title, author_name, author_surname, publisher, isbn)
+	VALUES (new.book_id, new.title, new.author_name, new.author_surname, new.publisher, new.isbn);
+END;
+
+CREATE TRIGGER IF NOT EXISTS book_fts_delete AFTER DELETE ON book BEGIN
+	INSERT INTO book_fts (book_fts, rowid, title, author_name, author_surname, publisher, isbn)
+	VALUES ('delete', old.book_id, old.title, old.author_name, old.author_surname, old.publisher, old.isbn);
+END;
+
+CREATE TRIGGER IF NOT EXISTS book_fts_update AFTER UPDATE ON book BEGIN
+	INSERT INTO book_fts (book_fts, rowid, title, author_name, author_surname, publisher, isbn)
+	VALUES ('delete', old.book_id, old.title, old.author_name, old.author_surname, old.publisher, old.isbn);
+	INSERT INTO book_fts (rowid, title, author_name, author_surname, publisher, isbn)
+	VALUES (new.book_id, new.title, new.author_name, new.author_surname, new.publisher, new.isbn);
+END;
+
+INSERT INTO book_fts (book_fts) VALUES ('rebuild');
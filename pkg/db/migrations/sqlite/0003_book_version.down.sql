@@ -0,0 +1,2 @@
+ALTER TABLE "book" DROP COLUMN "updated_at";
+ALTER TABLE "book" DROP COLUMN "version";
@@ -0,0 +1,4 @@
+ALTER TABLE "book" ADD COLUMN "version" INTEGER NOT NULL DEFAULT 1;
+-- sqlite could not add a column with the CURRENT_TIMESTAMP default, the new books set it on insert
+ALTER TABLE "book" ADD COLUMN "updated_at" TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE "book" SET "updated_at" = CURRENT_TIMESTAMP;
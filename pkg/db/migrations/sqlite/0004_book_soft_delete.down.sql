@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "book_deleted_at_idx";
+ALTER TABLE "book" DROP COLUMN "deleted_at";
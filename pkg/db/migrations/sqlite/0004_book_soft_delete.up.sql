@@ -0,0 +1,2 @@
+ALTER TABLE "book" ADD COLUMN "deleted_at" TIMESTAMP;
+CREATE INDEX IF NOT EXISTS "book_deleted_at_idx" ON "book" ("deleted_at");
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "book_history" (
+	"history_id"	INTEGER,
+	"book_id"	INTEGER NOT NULL,
+	"operation"	VARCHAR(20) NOT NULL,
+	"actor"	VARCHAR(100) NOT NULL DEFAULT '',
+	"version"	INTEGER NOT NULL DEFAULT 0,
+	"old_book"	TEXT,
+	"new_book"	TEXT,
+	"changed_at"	TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY("history_id" AUTOINCREMENT)
+);
+CREATE INDEX IF NOT EXISTS "book_history_book_id_idx" ON "book_history" ("book_id", "history_id");
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS "publisher" (
+	"publisher_id"	INTEGER,
+	"name"	VARCHAR(50) NOT NULL,
+	"name_key"	VARCHAR(50) NOT NULL,
+	"parent_id"	INTEGER REFERENCES "publisher" ("publisher_id"),
+	"updated_at"	TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY("publisher_id" AUTOINCREMENT)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "publisher_name_key_idx" ON "publisher" ("name_key");
+CREATE INDEX IF NOT EXISTS "publisher_parent_id_idx" ON "publisher" ("parent_id");
+CREATE TABLE IF NOT EXISTS "publisher_alias" (
+	"alias_key"	VARCHAR(50) NOT NULL,
+	"alias"	VARCHAR(50) NOT NULL,
+	"publisher_id"	INTEGER NOT NULL REFERENCES "publisher" ("publisher_id"),
+	PRIMARY KEY("alias_key")
+);
+CREATE INDEX IF NOT EXISTS "publisher_alias_publisher_id_idx" ON "publisher_alias" ("publisher_id");
+CREATE TABLE IF NOT EXISTS "book_publisher_backup" (
+	"book_id"	INTEGER PRIMARY KEY,
+	"publisher"	VARCHAR(50) NOT NULL,
+	"converted"	VARCHAR(50) NOT NULL
+);
+-- 0 is the books without a publisher, the publisher_id is checked by the storage
+ALTER TABLE "book" ADD COLUMN "publisher_id" INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS "book_publisher_id_idx" ON "book" ("publisher_id");
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"goapp/pkg/model"
+	"strings"
+)
+
+// ErrInvalidOrderBy is returned when the order by is not a list of the known book columns
+var ErrInvalidOrderBy = errors.New("invalid order by")
+
+// SortField is a single column of the order by, Desc will order the column in descending order
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// ParseOrderBy will parse the comma separated column list like author_surname,-published,title
+// where the - prefix is the descending order. Every column has to be one of the BookColumns and
+// used once, book_id is added as the last column if it is not in the list so every book has its own position.
+func ParseOrderBy(s string) ([]SortField, error) {
+	var order []SortField
+	used := map[string]bool{}
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		sf := SortField{Column: strings.TrimPrefix(f, "-"), Desc: strings.HasPrefix(f, "-")}
+		if !IsBookColumn(sf.Column) {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidOrderBy, f)
+		}
+		if used[sf.Column] {
+			return nil, fmt.Errorf("%w: field %q is used more than once", ErrInvalidOrderBy, sf.Column)
+		}
+		used[sf.Column] = true
+		order = append(order, sf)
+		// book_id is unique so the columns after it would never be compared
+		if sf.Column == "book_id" {
+			return order, nil
+		}
+	}
+	return append(order, SortField{Column: "book_id"}), nil
+}
+
+// FormatOrderBy will return the order by in the same format that ParseOrderBy accept
+func FormatOrderBy(order []SortField) string {
+	cols := make([]string, len(order))
+	for i, sf := range order {
+		cols[i] = sf.Column
+		if sf.Desc {
+			cols[i] = "-" + cols[i]
+		}
+	}
+	return strings.Join(cols, ",")
+}
+
+// KeysetOf will return the keyset position of the book in the list order
+func KeysetOf(bk *model.Book, order []SortField, before bool) *Keyset {
+	k := &Keyset{ID: bk.ID, Before: before}
+	for _, sf := range order {
+		if v, ok := bookColumn(bk, sf.Column); ok && sf.Column != "book_id" {
+			k.Values = append(k.Values, v.String())
+		}
+	}
+	return k
+}
+
+// orderClause will return the sql ORDER BY columns, in the reverse direction when reverse is set
+func orderClause(order []SortField, reverse bool) (string, error) {
+	cols := make([]string, len(order))
+	for i, sf := range order {
+		if !IsBookColumn(sf.Column) {
+			return "", fmt.Errorf("%w: unknown field %q", ErrInvalidOrderBy, sf.Column)
+		}
+		dir := "ASC"
+		if sf.Desc != reverse {
+			dir = "DESC"
+		}
+		cols[i] = fmt.Sprintf("%s %s", sf.Column, dir)
+	}
+	return strings.Join(cols, ", "), nil
+}
+
+// keysetCondition will return the sql condition and bind arguments of the books that are after the keyset,
+// or before it when the keyset is Before, in the list order. Every column can have its own direction
+// so the condition is expanded into (a > ?) OR (a = ? AND b < ?) OR ...
+func keysetCondition(order []SortField, k *Keyset) (string, []interface{}, error) {
+	values, err := keysetValues(order, k)
+	if err != nil {
+		return "", nil, err
+	}
+	var ors []string
+	var args []interface{}
+	for i, sf := range order {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = ?", order[j].Column))
+			args = append(args, values[j])
+		}
+		cmp := ">"
+		if sf.Desc != k.Before {
+			cmp = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s ?", sf.Column, cmp))
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return strings.Join(ors, " OR "), args, nil
+}
+
+// compareKeyset will compare the list position of the book with the keyset values and return -1, 0 or 1
+func compareKeyset(bk *model.Book, order []SortField, values []interface{}) int {
+	for i, sf := range order {
+		var c int
+		if sf.Column == "book_id" {
+			c = compareInt(bk.ID, values[i].(int))
+		} else {
+			v, _ := bookColumn(bk, sf.Column)
+			c = strings.Compare(v.String(), values[i].(string))
+		}
+		if sf.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// keysetValues will return the keyset value of every order by column
+func keysetValues(order []SortField, k *Keyset) ([]interface{}, error) {
+	values := make([]interface{}, len(order))
+	n := 0
+	for i, sf := range order {
+		if sf.Column == "book_id" {
+			values[i] = k.ID
+			continue
+		}
+		if n >= len(k.Values) {
+			return nil, fmt.Errorf("%w: keyset does not have the %s value", ErrInvalidOrderBy, sf.Column)
+		}
+		values[i] = k.Values[n]
+		n++
+	}
+	if n != len(k.Values) {
+		return nil, fmt.Errorf("%w: keyset has %d values, want %d", ErrInvalidOrderBy, len(k.Values), n)
+	}
+	return values, nil
+}
+
+// compareInt will compare the two integers and return -1, 0 or 1
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
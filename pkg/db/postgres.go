@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+type PostgresStorage struct{ sqlStorage }
+
+var _ Database = (*PostgresStorage)(nil)
+
+// OpenPostgresStorage to initialize the postgres database connection with the data source name
+func OpenPostgresStorage(dsn string) (*PostgresStorage, error) {
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// ILIKE to keep the case-insensitive search behaviour of sqlite LIKE
+	return &PostgresStorage{sqlStorage{db: db, dialect: "postgres", like: "ILIKE", forUpdate: " FOR UPDATE",
+		uniqueConstraint: postgresUniqueConstraint}}, nil
+}
+
+// postgresUniqueConstraint will return the name of the unique constraint that the error is caused by
+func postgresUniqueConstraint(err error) (string, bool) {
+	var e *pq.Error
+	if !errors.As(err, &e) || e.Code != "23505" {
+		return "", false
+	}
+	return e.Constraint, true
+}
+
+// SearchBooks will return the books that match every search term from the book_search tsvector index,
+// ranked by ts_rank with the field boosts as the weights of the title, author, publisher and isbn labels
+func (s PostgresStorage) SearchBooks(ctx context.Context, q *SearchQuery) ([]SearchResult, error) {
+	if len(q.Terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+	cols, err := selectColumns("book.", q.Columns)
+	if err != nil {
+		return nil, err
+	}
+	// ts_rank weights are in {D, C, B, A} label order and could not be greater than 1
+	weights := []float64{q.boost("isbn"), q.boost("publisher"), q.boost("author"), q.boost("title")}
+	top := 0.0
+	for _, w := range weights {
+		if w > top {
+			top = w
+		}
+	}
+	for i := range weights {
+		weights[i] /= top
+	}
+	headline := fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=12, MinWords=4`, snippetStart, snippetEnd)
+	query := "SELECT " + cols + ", ts_rank(?::float4[], s.document, q) AS rank, " +
+		"ts_headline('simple', concat_ws(' ', book.title, book.author_name, book.author_surname, book.publisher), q, ?) AS snippet " +
+		"FROM book JOIN book_search s ON s.book_id = book.book_id, to_tsquery('simple', ?) q " +
+		"WHERE s.document @@ q " + searchDeleted(q) + "ORDER BY rank DESC, book.book_id LIMIT ? OFFSET ?"
+	tsq := tsQuery(q.Terms)
+	log.Debug().Msgf("SearchBooks: %s %s %v", query, tsq, weights)
+	return s.selectSearchResults(ctx, query, pq.Array(weights), headline, tsq, q.Limit, q.OffSet)
+}
+
+// PurgeBooks will remove the books that are deleted longer than olderThan ago for good
+// and return number of book that is purged, the purged books are recorded in the book history
+func (s PostgresStorage) PurgeBooks(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return s.purgeBooks(ctx, "deleted_at < now() - ?::float8 * interval '1 second'", olderThan.Seconds())
+}
@@ -0,0 +1,46 @@
+package db_test
+
+import (
+	"goapp/pkg/db"
+	"goapp/pkg/db/dbtest"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// postgresDSNEnv is the environment variable with the data source name of a throwaway postgres database,
+// its public schema is dropped before every test case
+const postgresDSNEnv = "TEST_POSTGRES_DSN"
+
+func TestPostgresStorage(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+	dbtest.RunStorageSuite(t, func(t *testing.T) db.Storage {
+		resetPostgres(t, dsn)
+		s, err := db.OpenPostgresStorage(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(s.CloseDB)
+		if err = s.MigrateUp(); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
+
+// resetPostgres will drop every table of the public schema so the test case start with an empty database
+func resetPostgres(t *testing.T, dsn string) {
+	t.Helper()
+	conn, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err = conn.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,582 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goapp/pkg/model"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrPublisherNotFound is returned when there is no publisher with the publisher_id
+	ErrPublisherNotFound = errors.New("publisher not found")
+	// ErrPublisherHasBooks is returned when a publisher that still has books is deleted
+	ErrPublisherHasBooks = errors.New("publisher has books")
+	// ErrUnknownPublisher is returned when a book, an imprint or a merge refer to a publisher that does not exist
+	ErrUnknownPublisher = errors.New("publisher does not exist")
+	// ErrDuplicatePublisher is returned when the name or an alias of a publisher is already used by another publisher
+	ErrDuplicatePublisher = errors.New("publisher name is already used")
+	// ErrPublisherCycle is returned when a publisher would be an imprint of itself or of one of its imprints
+	ErrPublisherCycle = errors.New("publisher could not be an imprint of itself or its imprints")
+	// ErrSelfMerge is returned when a publisher is merged into itself
+	ErrSelfMerge = errors.New("publisher could not be merged into itself")
+)
+
+// PublisherQuery to define the page of the publishers, ordered by name and publisher_id.
+// Only the publishers with Name in their name or aliases are listed when it is set,
+// and only the imprints of the parent publisher when ParentID is set.
+type PublisherQuery struct {
+	Name     string
+	ParentID int
+	Limit    int
+	OffSet   int
+}
+
+// PublisherBooksQuery to define the publisher and the page of its books that are not deleted,
+// ordered by published date and book_id. The books of its imprints are included with IncludeImprints.
+type PublisherBooksQuery struct {
+	PublisherID     int
+	IncludeImprints bool
+	Limit           int
+	OffSet          int
+}
+
+// publisherColumns are the publisher table columns of model.Publisher
+const publisherColumns = "publisher_id, name, parent_id, updated_at"
+
+// publisherKey will return the name that the spellings of the same publisher have in common,
+// lower case without punctuation and with & as and
+func publisherKey(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "&", " and ")
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// publisherNoise are the words of the publisher names that do not tell the publishers apart
+var publisherNoise = map[string]bool{"the": true, "books": true, "book": true, "publishing": true,
+	"publishers": true, "publisher": true, "press": true, "group": true, "inc": true, "ltd": true,
+	"limited": true, "llc": true, "co": true, "company": true, "corp": true, "corporation": true}
+
+// publisherClusterKey will return the publisher key without the noise words,
+// the names with the same cluster key are possibly the same publisher
+func publisherClusterKey(name string) string {
+	key := publisherKey(name)
+	var words []string
+	for _, w := range strings.Fields(key) {
+		if !publisherNoise[w] {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		return key
+	}
+	return strings.Join(words, " ")
+}
+
+// cleanPublisher will collapse the spaces of the publisher name and aliases,
+// the empty aliases and the aliases that are the same as the name or another alias are left out
+func cleanPublisher(p *model.Publisher) {
+	p.Name = strings.Join(strings.Fields(p.Name), " ")
+	keys := map[string]bool{publisherKey(p.Name): true}
+	aliases := []string{}
+	for _, alias := range p.Aliases {
+		alias = strings.Join(strings.Fields(alias), " ")
+		if key := publisherKey(alias); key != "" && !keys[key] {
+			keys[key] = true
+			aliases = append(aliases, alias)
+		}
+	}
+	p.Aliases = aliases
+}
+
+// clusterPublishers will return the groups of publishers that have the same cluster key for their name or any alias,
+// ordered by the key with the publishers in the publisher_id order
+func clusterPublishers(ps []model.Publisher) []model.PublisherCluster {
+	members := map[string][]model.Publisher{}
+	for _, p := range ps {
+		seen := map[string]bool{}
+		for _, name := range append([]string{p.Name}, p.Aliases...) {
+			key := publisherClusterKey(name)
+			if key != "" && !seen[key] {
+				seen[key] = true
+				members[key] = append(members[key], p)
+			}
+		}
+	}
+	clusters := []model.PublisherCluster{}
+	for key, group := range members {
+		if len(group) > 1 {
+			sort.Slice(group, func(i, j int) bool { return group[i].ID < group[j].ID })
+			clusters = append(clusters, model.PublisherCluster{Key: key, Publishers: group})
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Key < clusters[j].Key })
+	return clusters
+}
+
+// ListPublishers will return the page of the publishers with their aliases, ordered by name and publisher_id
+func (s sqlStorage) ListPublishers(ctx context.Context, q *PublisherQuery) ([]model.Publisher, error) {
+	query := "SELECT " + publisherColumns + " FROM publisher"
+	var conds []string
+	var args []interface{}
+	if q.Name != "" {
+		conds = append(conds, fmt.Sprintf(`(name %s ? ESCAPE '\' OR publisher_id IN `+
+			`(SELECT publisher_id FROM publisher_alias WHERE alias %s ? ESCAPE '\'))`, s.like, s.like))
+		pattern := "%" + escapeLike(q.Name) + "%"
+		args = append(args, pattern, pattern)
+	}
+	if q.ParentID != 0 {
+		conds = append(conds, "parent_id = ?")
+		args = append(args, q.ParentID)
+	}
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY name, publisher_id LIMIT ? OFFSET ?"
+	args = append(args, q.Limit, q.OffSet)
+	log.Debug().Msgf("ListPublishers: %s %v", query, args)
+	ps := []model.Publisher{}
+	if err := s.db.SelectContext(ctx, &ps, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	return ps, s.loadAliases(ctx, s.db, ps)
+}
+
+// GetPublisher will return the publisher with its aliases or ErrPublisherNotFound if there is none
+func (s sqlStorage) GetPublisher(ctx context.Context, id int) (model.Publisher, error) {
+	return s.getPublisher(ctx, s.db, id)
+}
+
+// InsertPublisher will insert the publisher with its aliases and set its new publisher_id and updated_at.
+// It will return ErrDuplicatePublisher if the name or an alias is used by another publisher
+// and ErrUnknownPublisher if there is no parent publisher with the parent_id.
+func (s sqlStorage) InsertPublisher(ctx context.Context, p *model.Publisher) error {
+	cleanPublisher(p)
+	log.Debug().Msgf("InsertPublisher: %v", p)
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.checkParent(ctx, tx, p); err != nil {
+			return err
+		}
+		if err := s.checkPublisherNames(ctx, tx, p); err != nil {
+			return err
+		}
+		query := "INSERT INTO publisher (name, name_key, parent_id, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP) " +
+			"RETURNING publisher_id, updated_at"
+		err := tx.QueryRowxContext(ctx, tx.Rebind(query), p.Name, publisherKey(p.Name), p.ParentID).Scan(&p.ID, &p.UpdatedAt)
+		if err != nil {
+			return s.mapError(err)
+		}
+		return s.insertAliases(ctx, tx, p.ID, p.Aliases)
+	})
+}
+
+// UpdatePublisher will set the name, parent and aliases of the publisher and the publisher of its books,
+// the books are changed with a new version and recorded in the book history when the name is changed.
+// It will return ErrPublisherNotFound if there is no publisher with the publisher_id, ErrDuplicatePublisher
+// if the name or an alias is used by another publisher, ErrUnknownPublisher if there is no parent publisher
+// with the parent_id and ErrPublisherCycle if the parent is the publisher or one of its imprints.
+func (s sqlStorage) UpdatePublisher(ctx context.Context, p *model.Publisher) error {
+	cleanPublisher(p)
+	log.Debug().Msgf("UpdatePublisher: %v", p)
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := s.getPublisher(ctx, tx, p.ID); err != nil {
+			return err
+		}
+		if err := s.checkParent(ctx, tx, p); err != nil {
+			return err
+		}
+		if err := s.checkPublisherNames(ctx, tx, p); err != nil {
+			return err
+		}
+		query := "UPDATE publisher SET name = ?, name_key = ?, parent_id = ?, updated_at = CURRENT_TIMESTAMP " +
+			"WHERE publisher_id = ?"
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), p.Name, publisherKey(p.Name), p.ParentID, p.ID); err != nil {
+			return s.mapError(err)
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM publisher_alias WHERE publisher_id = ?"), p.ID); err != nil {
+			return err
+		}
+		if err := s.insertAliases(ctx, tx, p.ID, p.Aliases); err != nil {
+			return err
+		}
+		return s.syncPublisherBooks(ctx, tx, p.ID, *p)
+	})
+}
+
+// DeletePublisher will remove the publisher with its aliases, its imprints get its parent.
+// It will return ErrPublisherNotFound if there is no publisher with the publisher_id
+// and ErrPublisherHasBooks if any book is published by it, the deleted books too.
+func (s sqlStorage) DeletePublisher(ctx context.Context, id int) error {
+	log.Debug().Msgf("DeletePublisher: %d", id)
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		var n int
+		if err := tx.GetContext(ctx, &n, tx.Rebind("SELECT COUNT(*) FROM book WHERE publisher_id = ?"), id); err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrPublisherHasBooks
+		}
+		p, err := s.getPublisher(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, tx.Rebind("UPDATE publisher SET parent_id = ? WHERE parent_id = ?"), p.ParentID, id); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, tx.Rebind("DELETE FROM publisher_alias WHERE publisher_id = ?"), id); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, tx.Rebind("DELETE FROM publisher WHERE publisher_id = ?"), id)
+		return err
+	})
+}
+
+// MergePublishers will merge the publishers into the publisher with the publisher_id. The names and aliases of the
+// merged publishers become its aliases, their imprints become its imprints and their books are published by it
+// with a new version that is recorded in the book history, then the merged publishers are removed.
+// It will return ErrPublisherNotFound if there is no publisher with the publisher_id,
+// ErrUnknownPublisher if any of the merged publishers is not there and ErrSelfMerge if it is one of them.
+func (s sqlStorage) MergePublishers(ctx context.Context, id int, sourceIDs []int) error {
+	log.Debug().Msgf("MergePublishers: %d %v", id, sourceIDs)
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		target, err := s.getPublisher(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		sources, err := s.mergeSources(ctx, tx, id, sourceIDs)
+		if err != nil {
+			return err
+		}
+		parents := map[int]*int{}
+		aliases := append([]string{}, target.Aliases...)
+		for _, src := range sources {
+			parents[src.ID] = src.ParentID
+			aliases = append(aliases, src.Name)
+			aliases = append(aliases, src.Aliases...)
+		}
+		target.ParentID = mergedParent(target, parents)
+		target.Aliases = aliases
+		cleanPublisher(&target)
+
+		for _, src := range sources {
+			query := "UPDATE publisher SET parent_id = ? WHERE parent_id = ? AND publisher_id <> ?"
+			if _, err = tx.ExecContext(ctx, tx.Rebind(query), id, src.ID, id); err != nil {
+				return err
+			}
+			if err = s.syncPublisherBooks(ctx, tx, src.ID, target); err != nil {
+				return err
+			}
+			if _, err = tx.ExecContext(ctx, tx.Rebind("DELETE FROM publisher_alias WHERE publisher_id = ?"), src.ID); err != nil {
+				return err
+			}
+		}
+		query := "UPDATE publisher SET parent_id = ?, updated_at = CURRENT_TIMESTAMP WHERE publisher_id = ?"
+		if _, err = tx.ExecContext(ctx, tx.Rebind(query), target.ParentID, id); err != nil {
+			return err
+		}
+		for _, src := range sources {
+			if _, err = tx.ExecContext(ctx, tx.Rebind("DELETE FROM publisher WHERE publisher_id = ?"), src.ID); err != nil {
+				return err
+			}
+		}
+		if _, err = tx.ExecContext(ctx, tx.Rebind("DELETE FROM publisher_alias WHERE publisher_id = ?"), id); err != nil {
+			return err
+		}
+		return s.insertAliases(ctx, tx, id, target.Aliases)
+	})
+}
+
+// ListPublisherDuplicates will return the groups of publishers with names that look alike for review
+func (s sqlStorage) ListPublisherDuplicates(ctx context.Context) ([]model.PublisherCluster, error) {
+	ps := []model.Publisher{}
+	query := "SELECT " + publisherColumns + " FROM publisher ORDER BY publisher_id"
+	log.Debug().Msgf("ListPublisherDuplicates: %s", query)
+	if err := s.db.SelectContext(ctx, &ps, query); err != nil {
+		return nil, err
+	}
+	if err := s.loadAliases(ctx, s.db, ps); err != nil {
+		return nil, err
+	}
+	return clusterPublishers(ps), nil
+}
+
+// ListPublisherBooks will return the page of the books of the publisher that are not deleted,
+// ordered by published date and book_id. It will return ErrPublisherNotFound if there is no publisher with the publisher_id.
+func (s sqlStorage) ListPublisherBooks(ctx context.Context, q *PublisherBooksQuery) ([]model.Book, error) {
+	if _, err := s.GetPublisher(ctx, q.PublisherID); err != nil {
+		return nil, err
+	}
+	ids := []int{q.PublisherID}
+	for parents := ids; q.IncludeImprints && len(parents) > 0; {
+		query, args, err := sqlx.In("SELECT publisher_id FROM publisher WHERE parent_id IN (?) ORDER BY publisher_id", parents)
+		if err != nil {
+			return nil, err
+		}
+		var imprints []int
+		if err = s.db.SelectContext(ctx, &imprints, s.db.Rebind(query), args...); err != nil {
+			return nil, err
+		}
+		parents = imprints
+		ids = append(ids, imprints...)
+	}
+	query, args, err := sqlx.In("SELECT * FROM book WHERE publisher_id IN (?) AND "+notDeleted+
+		" ORDER BY published, book_id LIMIT ? OFFSET ?", ids, q.Limit, q.OffSet)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Msgf("ListPublisherBooks: %s %v", query, args)
+	return s.selectBooks(ctx, query, args...)
+}
+
+// getPublisher will return the publisher with its aliases or ErrPublisherNotFound if there is none
+func (s sqlStorage) getPublisher(ctx context.Context, q sqlx.QueryerContext, id int) (model.Publisher, error) {
+	var p model.Publisher
+	query := s.db.Rebind("SELECT " + publisherColumns + " FROM publisher WHERE publisher_id = ?")
+	err := sqlx.GetContext(ctx, q, &p, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, ErrPublisherNotFound
+	}
+	if err != nil {
+		return p, err
+	}
+	ps := []model.Publisher{p}
+	err = s.loadAliases(ctx, q, ps)
+	return ps[0], err
+}
+
+// loadAliases will set the aliases of the publishers in the alias order
+func (s sqlStorage) loadAliases(ctx context.Context, q sqlx.QueryerContext, ps []model.Publisher) error {
+	if len(ps) == 0 {
+		return nil
+	}
+	byID := map[int]int{}
+	ids := make([]int, len(ps))
+	for i := range ps {
+		ps[i].Aliases = []string{}
+		byID[ps[i].ID] = i
+		ids[i] = ps[i].ID
+	}
+	query, args, err := sqlx.In("SELECT publisher_id, alias FROM publisher_alias WHERE publisher_id IN (?) ORDER BY alias", ids)
+	if err != nil {
+		return err
+	}
+	var rows []struct {
+		PublisherID int    `db:"publisher_id"`
+		Alias       string `db:"alias"`
+	}
+	if err = sqlx.SelectContext(ctx, q, &rows, s.db.Rebind(query), args...); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		i := byID[r.PublisherID]
+		ps[i].Aliases = append(ps[i].Aliases, r.Alias)
+	}
+	return nil
+}
+
+// insertAliases will add the aliases to the publisher,
+// it will return ErrDuplicatePublisher if an alias is added to another publisher at the same time
+func (s sqlStorage) insertAliases(ctx context.Context, tx *sqlx.Tx, id int, aliases []string) error {
+	query := tx.Rebind("INSERT INTO publisher_alias (alias_key, alias, publisher_id) VALUES (?, ?, ?)")
+	for _, alias := range aliases {
+		if _, err := tx.ExecContext(ctx, query, publisherKey(alias), alias, id); err != nil {
+			return s.mapError(err)
+		}
+	}
+	return nil
+}
+
+// checkParent will return ErrUnknownPublisher if the parent of the publisher does not exist
+// and ErrPublisherCycle if the publisher is the parent or one of its parents
+func (s sqlStorage) checkParent(ctx context.Context, tx *sqlx.Tx, p *model.Publisher) error {
+	if p.ParentID == nil {
+		return nil
+	}
+	query := tx.Rebind("SELECT parent_id FROM publisher WHERE publisher_id = ?")
+	for id := p.ParentID; id != nil; {
+		if *id == p.ID {
+			return ErrPublisherCycle
+		}
+		var parent *int
+		err := tx.GetContext(ctx, &parent, query, *id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: parent_id %d", ErrUnknownPublisher, *id)
+		}
+		if err != nil {
+			return err
+		}
+		id = parent
+	}
+	return nil
+}
+
+// checkPublisherNames will return ErrDuplicatePublisher if the name or an alias of the publisher is the name
+// or an alias of another publisher
+func (s sqlStorage) checkPublisherNames(ctx context.Context, tx *sqlx.Tx, p *model.Publisher) error {
+	for _, name := range append([]string{p.Name}, p.Aliases...) {
+		other, ok, err := s.findPublisher(ctx, tx, publisherKey(name))
+		if err != nil {
+			return err
+		}
+		if ok && other.ID != p.ID {
+			return fmt.Errorf("%w: %q is used by publisher %d", ErrDuplicatePublisher, name, other.ID)
+		}
+	}
+	return nil
+}
+
+// findPublisher will return the publisher that has the key as the key of its name or an alias
+func (s sqlStorage) findPublisher(ctx context.Context, tx *sqlx.Tx, key string) (model.Publisher, bool, error) {
+	var p model.Publisher
+	query := "SELECT publisher_id, name FROM publisher WHERE name_key = ? UNION ALL " +
+		"SELECT p.publisher_id, p.name FROM publisher_alias a JOIN publisher p ON p.publisher_id = a.publisher_id " +
+		"WHERE a.alias_key = ?"
+	err := tx.QueryRowxContext(ctx, tx.Rebind(query), key, key).Scan(&p.ID, &p.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, false, nil
+	}
+	return p, err == nil, err
+}
+
+// mergeSources will return the publishers that are merged into the publisher with the publisher_id,
+// every one of them once
+func (s sqlStorage) mergeSources(ctx context.Context, tx *sqlx.Tx, id int, sourceIDs []int) ([]model.Publisher, error) {
+	var sources []model.Publisher
+	seen := map[int]bool{}
+	for _, sourceID := range sourceIDs {
+		if sourceID == id {
+			return nil, ErrSelfMerge
+		}
+		if seen[sourceID] {
+			continue
+		}
+		seen[sourceID] = true
+		src, err := s.getPublisher(ctx, tx, sourceID)
+		if errors.Is(err, ErrPublisherNotFound) {
+			return nil, fmt.Errorf("%w: publisher_id %d", ErrUnknownPublisher, sourceID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+// mergedParent will return the parent of the publisher after the publishers with the parents are merged into it,
+// an imprint of a merged publisher get the first parent that is not merged, none if that is the publisher itself
+func mergedParent(p model.Publisher, parents map[int]*int) *int {
+	parent := p.ParentID
+	for i := 0; parent != nil && i <= len(parents); i++ {
+		next, merged := parents[*parent]
+		if !merged {
+			break
+		}
+		parent = next
+	}
+	if parent == nil || *parent == p.ID {
+		return nil
+	}
+	if _, merged := parents[*parent]; merged {
+		return nil
+	}
+	return parent
+}
+
+// syncPublisherBooks will set the books of the publisher with the publisher_id to the publisher p,
+// the changed books get a new version that is recorded in the book history
+func (s sqlStorage) syncPublisherBooks(ctx context.Context, tx *sqlx.Tx, publisherID int, p model.Publisher) error {
+	var ids []int
+	query := "SELECT book_id FROM book WHERE publisher_id = ? ORDER BY book_id"
+	if err := tx.SelectContext(ctx, &ids, tx.Rebind(query), publisherID); err != nil {
+		return err
+	}
+	for _, bookID := range ids {
+		before, err := s.snapshotBook(ctx, tx, bookID)
+		if err != nil {
+			return err
+		}
+		if before.PublisherID == p.ID && before.Publisher == p.Name {
+			continue
+		}
+		query = "UPDATE book SET publisher = ?, publisher_id = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP " +
+			"WHERE book_id = ?"
+		if _, err = tx.ExecContext(ctx, tx.Rebind(query), p.Name, p.ID, bookID); err != nil {
+			return err
+		}
+		after, err := s.snapshotBook(ctx, tx, bookID)
+		if err != nil {
+			return err
+		}
+		if err = recordHistory(ctx, tx, model.HistoryPublisher, before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolvePublisher will set the publisher of the book to the name of the publisher with its publisher_id,
+// or to the publisher that has the publisher name as its name or alias when it has no publisher_id.
+// The publisher is created when there is none, and it will return ErrUnknownPublisher
+// if there is no publisher with the publisher_id and ErrDuplicatePublisher if the publisher is created meanwhile.
+func (s sqlStorage) resolvePublisher(ctx context.Context, tx *sqlx.Tx, bk *model.Book) error {
+	if bk.PublisherID != 0 {
+		p, err := s.getPublisher(ctx, tx, bk.PublisherID)
+		if errors.Is(err, ErrPublisherNotFound) {
+			return fmt.Errorf("%w: publisher_id %d", ErrUnknownPublisher, bk.PublisherID)
+		}
+		bk.Publisher = p.Name
+		return err
+	}
+	key := publisherKey(bk.Publisher)
+	if key == "" {
+		return nil
+	}
+	p, ok, err := s.findPublisher(ctx, tx, key)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		p.Name = strings.Join(strings.Fields(bk.Publisher), " ")
+		query := "INSERT INTO publisher (name, name_key, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP) RETURNING publisher_id"
+		// the publisher could be created by another book change at the same time
+		if err = tx.QueryRowxContext(ctx, tx.Rebind(query), p.Name, key).Scan(&p.ID); err != nil {
+			return s.mapError(err)
+		}
+	}
+	bk.PublisherID, bk.Publisher = p.ID, p.Name
+	return nil
+}
+
+// linkBookPublisher will resolve the publisher of the book after a change in the same version,
+// a changed publisher_id win over the publisher, and a changed publisher is resolved by the name.
+// The book after the change is set to the resolved publisher.
+func (s sqlStorage) linkBookPublisher(ctx context.Context, tx *sqlx.Tx, before, after *model.Book) error {
+	if before == nil || after == nil || (before.PublisherID == after.PublisherID && before.Publisher == after.Publisher) {
+		return nil
+	}
+	bk := *after
+	if bk.PublisherID == before.PublisherID {
+		bk.PublisherID = 0
+	}
+	if err := s.resolvePublisher(ctx, tx, &bk); err != nil {
+		return err
+	}
+	if bk.PublisherID == after.PublisherID && bk.Publisher == after.Publisher {
+		return nil
+	}
+	query := "UPDATE book SET publisher = ?, publisher_id = ? WHERE book_id = ?"
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), bk.Publisher, bk.PublisherID, bk.ID); err != nil {
+		return err
+	}
+	after.Publisher, after.PublisherID = bk.Publisher, bk.PublisherID
+	return nil
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"database/sql"
+	"reflect"
+
+	"github.com/rs/zerolog/log"
+)
+
+// nonEmptyFields will return the db column names and values of the struct fields that are not empty.
+// Empty string and 0 int fields are considered as not define.
+func nonEmptyFields(i interface{}) ([]string, []interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(i))
+	t := v.Type()
+	var cols []string
+	var args []interface{}
+	for j := 0; j < v.NumField(); j++ {
+		if v.Field(j).IsZero() {
+			continue
+		}
+		cols = append(cols, t.Field(j).Tag.Get("db"))
+		args = append(args, v.Field(j).Interface())
+	}
+	return cols, args
+}
+
+// rowsAffected will return the number of rows that the executed statement changed
+func rowsAffected(result sql.Result, err error) (int64, error) {
+	var n int64
+	if err != nil {
+		return n, err
+	}
+	n, err = result.RowsAffected()
+	if err != nil {
+		return n, err
+	}
+	log.Debug().Msgf("RowsAffected: %d", n)
+	return n, nil
+}
@@ -0,0 +1,263 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"goapp/pkg/model"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Highlight markers that wrap the matched terms in the search snippets
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// snippetStart and snippetEnd are the control characters that the sql storages wrap the matched terms with,
+// they could not be in the book text so they are replaced with the highlight markers after the text is escaped
+const (
+	snippetStart = "\x02"
+	snippetEnd   = "\x03"
+)
+
+// maxSearchTerms is the maximum number of terms that a search query can have
+const maxSearchTerms = 16
+
+var (
+	// ErrEmptySearch is returned when the search query does not have any term to look for
+	ErrEmptySearch = errors.New("no search terms defined")
+	// ErrTooManySearchTerms is returned when the search query has more than maxSearchTerms terms
+	ErrTooManySearchTerms = errors.New("too many search terms")
+	// ErrInvalidBoost is returned when the search boost is not a known field with a positive weight
+	ErrInvalidBoost = errors.New("invalid search boost")
+)
+
+// SearchFields are the book fields that can be boosted, author is both author_name and author_surname
+var SearchFields = []string{"title", "author", "publisher", "isbn"}
+
+// searchColumns are the book columns of the full-text index with the search field they belong to
+var searchColumns = []struct{ col, field string }{
+	{"title", "title"}, {"author_name", "author"}, {"author_surname", "author"}, {"publisher", "publisher"}, {"isbn", "isbn"},
+}
+
+// defaultBoosts are the field weights that are used when the search does not boost the field
+var defaultBoosts = map[string]float64{"title": 3, "author": 2, "publisher": 1, "isbn": 1}
+
+// SearchTerm is a single term of the search query.
+// Tokens are the lower case words that need to be found next to each other,
+// the last token is matched as a prefix when Prefix is set.
+type SearchTerm struct {
+	Tokens []string
+	Prefix bool
+}
+
+// SearchQuery to define the full-text search terms, field boosts and page that is returned.
+// A book has to match every term to be found. Only the book Columns are selected, all columns if it is empty.
+// The deleted books are only found with IncludeDeleted.
+type SearchQuery struct {
+	Columns        []string
+	Terms          []SearchTerm
+	Boosts         map[string]float64
+	Limit          int
+	OffSet         int
+	IncludeDeleted bool
+}
+
+// SearchResult is a book that match the search with its relevance rank, higher rank is more relevant,
+// and a snippet of the matched text where the matched terms are wrapped with the highlight markers
+type SearchResult struct {
+	model.Book
+	Rank    float64 `json:"rank" db:"rank"`
+	Snippet string  `json:"snippet" db:"snippet"`
+}
+
+// ParseSearchQuery will parse the user search text into search terms.
+// Words are matched anywhere in the book, "double quoted" words as a phrase
+// and a trailing * will match the words that start with the term.
+func ParseSearchQuery(q string) ([]SearchTerm, error) {
+	var terms []SearchTerm
+	for q = strings.TrimSpace(q); q != ""; q = strings.TrimSpace(q) {
+		var text string
+		if q[0] == '"' {
+			// an unclosed phrase will run until the end of the query
+			end := strings.IndexByte(q[1:], '"')
+			if end < 0 {
+				text, q = q[1:], ""
+			} else {
+				text, q = q[1:end+1], q[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(q, unicode.IsSpace)
+			if end < 0 {
+				end = len(q)
+			}
+			text, q = q[:end], q[end:]
+		}
+		prefix := strings.HasSuffix(text, "*")
+		// a * right after the phrase quote is the prefix of the phrase
+		if strings.HasPrefix(q, "*") {
+			prefix, q = true, q[1:]
+		}
+		if tokens := searchTokens(text); len(tokens) > 0 {
+			terms = append(terms, SearchTerm{Tokens: tokens, Prefix: prefix})
+		}
+	}
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+	if len(terms) > maxSearchTerms {
+		return nil, fmt.Errorf("%w: at most %d terms are allowed", ErrTooManySearchTerms, maxSearchTerms)
+	}
+	return terms, nil
+}
+
+// ParseSearchBoosts will parse the comma separated field:weight list like title:5,author:2
+// and return the weight of every search field, the fields that are not in the list keep the default weight
+func ParseSearchBoosts(s string) (map[string]float64, error) {
+	boosts := make(map[string]float64, len(defaultBoosts))
+	for f, w := range defaultBoosts {
+		boosts[f] = w
+	}
+	if strings.TrimSpace(s) == "" {
+		return boosts, nil
+	}
+	for _, b := range strings.Split(s, ",") {
+		field, weight, ok := strings.Cut(strings.TrimSpace(b), ":")
+		if _, known := defaultBoosts[field]; !ok || !known {
+			return nil, fmt.Errorf("%w %q: field must be one of %s", ErrInvalidBoost, b, strings.Join(SearchFields, ", "))
+		}
+		w, err := strconv.ParseFloat(weight, 64)
+		if err != nil || w <= 0 || w > 100 {
+			return nil, fmt.Errorf("%w %q: weight must be a number between 0 and 100", ErrInvalidBoost, b)
+		}
+		boosts[field] = w
+	}
+	return boosts, nil
+}
+
+// boost will return the weight of the search field
+func (q *SearchQuery) boost(field string) float64 {
+	if w, ok := q.Boosts[field]; ok {
+		return w
+	}
+	return defaultBoosts[field]
+}
+
+// searchTokens will split the text into lower case words the same way the sql full-text tokenizers do
+func searchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// wordSpans will return the start and end byte offsets of every word in the text
+func wordSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+// termMatches will return the index of every word where the term tokens are found next to each other
+func termMatches(words []string, t SearchTerm) []int {
+	var matches []int
+	for i := 0; i+len(t.Tokens) <= len(words); i++ {
+		ok := true
+		for j, tok := range t.Tokens {
+			if t.Prefix && j == len(t.Tokens)-1 {
+				ok = strings.HasPrefix(words[i+j], tok)
+			} else {
+				ok = words[i+j] == tok
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// highlight will wrap the marked byte ranges of the text with the highlight markers,
+// a range that overlap with the previous one is skipped.
+// The text is HTML escaped so only the markers are tags when the snippet is shown as HTML.
+func highlight(text string, marks [][2]int) string {
+	sort.Slice(marks, func(i, j int) bool { return marks[i][0] < marks[j][0] })
+	var b strings.Builder
+	last := 0
+	for _, m := range marks {
+		if m[0] < last {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:m[0]]))
+		b.WriteString(HighlightStart + html.EscapeString(text[m[0]:m[1]]) + HighlightEnd)
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// escapeSnippet will HTML escape the snippet of the sql storages and replace their snippet markers
+// with the highlight markers, like highlight does
+func escapeSnippet(snippet string) string {
+	return strings.NewReplacer(snippetStart, HighlightStart, snippetEnd, HighlightEnd).Replace(html.EscapeString(snippet))
+}
+
+// ftsQuery will return the sqlite FTS5 match expression of the search terms,
+// every term is a quoted phrase so the user text can not use the FTS5 query syntax
+func ftsQuery(terms []SearchTerm) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = `"` + strings.Join(t.Tokens, " ") + `"`
+		if t.Prefix {
+			parts[i] += "*"
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// tsQuery will return the postgres tsquery of the search terms,
+// every token is a quoted lexeme so the user text can not use the tsquery syntax
+func tsQuery(terms []SearchTerm) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		lexemes := make([]string, len(t.Tokens))
+		for j, tok := range t.Tokens {
+			lexemes[j] = "'" + strings.ReplaceAll(tok, "'", "''") + "'"
+		}
+		if t.Prefix {
+			lexemes[len(lexemes)-1] += ":*"
+		}
+		parts[i] = strings.Join(lexemes, " <-> ")
+		if len(lexemes) > 1 {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+// searchDeleted will return the sql condition that leave out the deleted books of the search, none if they are included
+func searchDeleted(q *SearchQuery) string {
+	if q.IncludeDeleted {
+		return ""
+	}
+	return "AND book." + notDeleted + " "
+}
@@ -0,0 +1,32 @@
+package db_test
+
+import (
+	"errors"
+	"goapp/pkg/db"
+	"strings"
+	"testing"
+)
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want error
+	}{
+		{"empty", "", db.ErrEmptySearch},
+		{"only punctuation", ` "" * - `, db.ErrEmptySearch},
+		{"too many terms", strings.Repeat("word ", 17), db.ErrTooManySearchTerms},
+	}
+	for _, tc := range tests {
+		_, err := db.ParseSearchQuery(tc.q)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("ParseSearchQuery %s error = %v, want %v", tc.name, err, tc.want)
+		}
+		if tc.want == db.ErrTooManySearchTerms && errors.Is(err, db.ErrEmptySearch) {
+			t.Errorf("ParseSearchQuery %s error = %v, want it is not %v", tc.name, err, db.ErrEmptySearch)
+		}
+	}
+	if terms, err := db.ParseSearchQuery(strings.Repeat("word ", 16)); err != nil || len(terms) != 16 {
+		t.Errorf("ParseSearchQuery 16 terms = %d terms, %v, want 16 terms", len(terms), err)
+	}
+}
@@ -0,0 +1,414 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goapp/config"
+	"goapp/pkg/model"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+// notDeleted is the sql condition of the books that are not deleted
+const notDeleted = "deleted_at IS NULL"
+
+// sqlStorage is the Storage implementation shared by the sql databases.
+// The dialect selects the migrations, like is the case-insensitive pattern match operator,
+// forUpdate is the row lock clause of the select statements if the database support it
+// and uniqueConstraint return the unique constraint that the driver specific error is caused by.
+// Every book change is recorded in the book_history table in the same transaction,
+// a book with a new author_name or author_surname credit the author of the name first in the book_author table.
+type sqlStorage struct {
+	db               *sqlx.DB
+	dialect          string
+	like             string
+	forUpdate        string
+	uniqueConstraint func(err error) (string, bool)
+}
+
+// duplicateErrors are the errors of the unique constraints by the sqlite column and the postgres constraint name
+var duplicateErrors = map[string]error{
+	"book.isbn":                 ErrDuplicateISBN,
+	"book_isbn_key":             ErrDuplicateISBN,
+	"publisher.name_key":        ErrDuplicatePublisher,
+	"publisher_name_key_idx":    ErrDuplicatePublisher,
+	"publisher_alias.alias_key": ErrDuplicatePublisher,
+	"publisher_alias_pkey":      ErrDuplicatePublisher,
+}
+
+// CloseDB to close the database connection
+func (s sqlStorage) CloseDB() {
+	if err := s.db.Close(); err != nil {
+		log.Fatal().Err(err).Msg(config.DBCloseErrMsg)
+	}
+}
+
+// MigrateUp will apply all pending schema migrations
+func (s sqlStorage) MigrateUp() error {
+	return MigrateUp(s.db, s.dialect)
+}
+
+// MigrateDown will revert the given number of latest applied schema migrations
+func (s sqlStorage) MigrateDown(steps int) error {
+	return MigrateDown(s.db, s.dialect, steps)
+}
+
+// MigrationStatus will return the applied state of every schema migration
+func (s sqlStorage) MigrationStatus() ([]MigrationStatus, error) {
+	return GetMigrationStatus(s.db, s.dialect)
+}
+
+// ListBooks will return all books with order by, page id and page size configuration that passing through.
+// With the keyset the books after or before the keyset book are returned instead of the offset page.
+func (s sqlStorage) ListBooks(ctx context.Context, p *PageList) ([]model.Book, error) {
+	before := p.Keyset != nil && p.Keyset.Before
+	// the books before the keyset are selected in the reverse order
+	order, err := orderClause(p.OrderBy, before)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := selectColumns("", p.Columns)
+	if err != nil {
+		return nil, err
+	}
+	var conds []string
+	var args []interface{}
+	if !p.Filter.includeDeleted() {
+		conds = append(conds, notDeleted)
+	}
+	if !p.Filter.IsEmpty() {
+		where, whereArgs, err := p.Filter.where(s.like)
+		if err != nil {
+			return nil, err
+		}
+		conds, args = append(conds, where), append(args, whereArgs...)
+	}
+	if p.Keyset != nil {
+		where, keysetArgs, err := keysetCondition(p.OrderBy, p.Keyset)
+		if err != nil {
+			return nil, err
+		}
+		conds, args = append(conds, "("+where+")"), append(args, keysetArgs...)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM book", cols)
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT ?", order)
+	args = append(args, p.Limit)
+	if p.Keyset == nil {
+		query += " OFFSET ?"
+		args = append(args, p.OffSet)
+	}
+	log.Debug().Msgf("ListBooks: %s %v", query, args)
+	bks, err := s.selectBooks(ctx, query, args...)
+	if err != nil || !before {
+		return bks, err
+	}
+	for i, j := 0, len(bks)-1; i < j; i, j = i+1, j-1 {
+		bks[i], bks[j] = bks[j], bks[i]
+	}
+	return bks, nil
+}
+
+// CountBooks will return the number of books that match the filter, all books if the filter is empty
+func (s sqlStorage) CountBooks(ctx context.Context, f *Filter) (int, error) {
+	query := "SELECT COUNT(*) FROM book"
+	var conds []string
+	var args []interface{}
+	if !f.includeDeleted() {
+		conds = append(conds, notDeleted)
+	}
+	if !f.IsEmpty() {
+		where, whereArgs, err := f.where(s.like)
+		if err != nil {
+			return 0, err
+		}
+		conds, args = append(conds, where), whereArgs
+	}
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	log.Debug().Msgf("CountBooks: %s %v", query, args)
+	var n int
+	err := s.db.GetContext(ctx, &n, s.db.Rebind(query), args...)
+	return n, err
+}
+
+// GetBooks will return all books that match with the filter that passing through
+func (s sqlStorage) GetBooks(ctx context.Context, f *BookFilter) ([]model.Book, error) {
+	fields, args := f.Fields()
+	if len(fields) == 0 {
+		return nil, ErrEmptyFilter
+	}
+	cols, err := selectColumns("", f.Columns)
+	if err != nil {
+		return nil, err
+	}
+	conds := make([]string, len(fields))
+	for i, col := range fields {
+		if f.Mode == MatchAny {
+			conds[i] = fmt.Sprintf(`CAST(%s AS TEXT) %s ? ESCAPE '\'`, col, s.like)
+			args[i] = "%" + escapeLike(fmt.Sprint(args[i])) + "%"
+		} else {
+			conds[i] = fmt.Sprintf("%s = ?", col)
+		}
+	}
+	sep := " AND "
+	if f.Mode == MatchAny {
+		sep = " OR "
+	}
+	query := fmt.Sprintf("SELECT %s FROM book WHERE (%s)", cols, strings.Join(conds, sep))
+	if !f.IncludeDeleted {
+		query += " AND " + notDeleted
+	}
+	log.Debug().Msgf("GetBooks: %s %v", query, args)
+	return s.selectBooks(ctx, query, args...)
+}
+
+// InsertBooks is able to insert single/multiple books that passing through in one transaction
+// and will return the outcome of every book in the same order.
+// Every book is inserted in its own savepoint so the failed books can be reported one by one,
+// with AllOrNothing mode the transaction is rolled back and ErrBatchRolledBack returned if any of them failed.
+func (s sqlStorage) InsertBooks(ctx context.Context, bks []model.Book, mode InsertMode) ([]InsertResult, error) {
+	if len(bks) == 0 {
+		return []InsertResult{}, nil
+	}
+	query := "INSERT INTO book (isbn, title, author_name, author_surname, published, publisher, publisher_id, updated_at) " +
+		"VALUES (:isbn, :title, :author_name, :author_surname, :published, :publisher, :publisher_id, CURRENT_TIMESTAMP) " +
+		"RETURNING book_id"
+	log.Debug().Msgf("InsertBooks: %s %v", query, bks)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	results := make([]InsertResult, len(bks))
+	failed := false
+	for i := range bks {
+		if _, err = tx.ExecContext(ctx, "SAVEPOINT insert_book"); err != nil {
+			return nil, err
+		}
+		bk := bks[i]
+		if err = s.resolvePublisher(ctx, tx, &bk); err == nil {
+			err = stmt.QueryRowxContext(ctx, bk).Scan(&results[i].ID)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT insert_book"); rbErr != nil {
+				return nil, rbErr
+			}
+			results[i] = InsertResult{Err: s.mapError(err)}
+			failed = true
+			continue
+		}
+		var after *model.Book
+		if after, err = s.snapshotBook(ctx, tx, results[i].ID); err != nil {
+			return nil, err
+		}
+		if err = recordHistory(ctx, tx, model.HistoryInsert, nil, after); err != nil {
+			return nil, err
+		}
+		if err = s.linkBookAuthor(ctx, tx, after); err != nil {
+			return nil, err
+		}
+		if _, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT insert_book"); err != nil {
+			return nil, err
+		}
+	}
+
+	if failed && mode == AllOrNothing {
+		for i := range results {
+			results[i].ID = 0
+		}
+		return results, ErrBatchRolledBack
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Debug().Msgf("InsertBooks results: %v", results)
+	return results, nil
+}
+
+// GetBook will return the book with the book_id or ErrBookNotFound if there is none or it is deleted.
+// Only the columns are selected, all columns if there is none.
+func (s sqlStorage) GetBook(ctx context.Context, id int, columns ...string) (model.Book, error) {
+	var bk model.Book
+	cols, err := selectColumns("", columns)
+	if err != nil {
+		return bk, err
+	}
+	query := fmt.Sprintf("SELECT %s FROM book WHERE book_id = ? AND %s", cols, notDeleted)
+	log.Debug().Msgf("GetBook: %s %d", query, id)
+	err = s.db.QueryRowxContext(ctx, s.db.Rebind(query), id).StructScan(&bk)
+	if errors.Is(err, sql.ErrNoRows) {
+		return bk, ErrBookNotFound
+	}
+	return bk, err
+}
+
+// UpdateBooks will update single book and all the book fields are required except the publisher_id,
+// it will return number of book that is updated and return 0 if no book update.
+// With the book version it will return ErrVersionConflict if the book has another version.
+func (s sqlStorage) UpdateBooks(ctx context.Context, bk *model.Book) (int64, error) {
+	return s.updateBook(ctx, model.HistoryUpdate, bk)
+}
+
+// ApplyBookPatch will save the book that a patch document is applied to like UpdateBooks,
+// the change is recorded in the book history as a patch
+func (s sqlStorage) ApplyBookPatch(ctx context.Context, bk *model.Book) (int64, error) {
+	return s.updateBook(ctx, model.HistoryPatch, bk)
+}
+
+// updateBook will update every field of the book and record the change with the history operation
+func (s sqlStorage) updateBook(ctx context.Context, op string, bk *model.Book) (int64, error) {
+	publisher := "publisher = :publisher, "
+	if bk.PublisherID != 0 {
+		publisher += "publisher_id = :publisher_id, "
+	}
+	query := "UPDATE book SET isbn = :isbn, title = :title, author_name = :author_name, " +
+		"author_surname = :author_surname, published = :published, " + publisher +
+		"version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE book_id = :book_id AND " + notDeleted
+	if bk.Version != 0 {
+		query += " AND version = :version"
+	}
+	log.Debug().Msgf("UpdateBooks: %s %v", query, bk)
+	query, args, err := sqlx.Named(query, bk)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.changeBook(ctx, op, bk.ID, query, args...)
+	if err == nil && n == 0 && bk.Version != 0 {
+		err = s.versionConflict(ctx, bk.ID)
+	}
+	return n, err
+}
+
+// PatchBooks will patch single book and only book_id that is required,
+// other field that is empty or not define will be ignored
+// it will return number of book that is updated and return 0 if no book update.
+// With the book version it will return ErrVersionConflict if the book has another version.
+func (s sqlStorage) PatchBooks(ctx context.Context, bk *model.PatchBook) (int64, error) {
+	cols, args := nonEmptyFields(bk)
+	var set []string
+	var setArgs []interface{}
+	for i, col := range cols {
+		if col != "book_id" && col != "version" {
+			set = append(set, fmt.Sprintf("%s = ?", col))
+			setArgs = append(setArgs, args[i])
+		}
+	}
+	if len(set) == 0 {
+		return 0, nil
+	}
+	set = append(set, "version = version + 1", "updated_at = CURRENT_TIMESTAMP")
+	query := fmt.Sprintf("UPDATE book SET %s WHERE book_id = ? AND %s", strings.Join(set, ", "), notDeleted)
+	setArgs = append(setArgs, bk.ID)
+	if bk.Version != 0 {
+		query += " AND version = ?"
+		setArgs = append(setArgs, bk.Version)
+	}
+	log.Debug().Msgf("PatchBooks: %s %v", query, setArgs)
+	n, err := s.changeBook(ctx, model.HistoryPatch, bk.ID, query, setArgs...)
+	if err == nil && n == 0 && bk.Version != 0 {
+		err = s.versionConflict(ctx, bk.ID)
+	}
+	return n, err
+}
+
+// versionConflict will return ErrVersionConflict if the book that was not updated with its version exists,
+// nil if there is no such book or it is deleted
+func (s sqlStorage) versionConflict(ctx context.Context, id int) error {
+	var n int
+	query := "SELECT COUNT(*) FROM book WHERE book_id = ? AND " + notDeleted
+	if err := s.db.GetContext(ctx, &n, s.db.Rebind(query), id); err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// DeleteBooks will mark a book id is matched as deleted and return number of book that is deleted and return 0 if no book delete
+func (s sqlStorage) DeleteBooks(ctx context.Context, id int) (int64, error) {
+	query := "UPDATE book SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP " +
+		"WHERE book_id = ? AND " + notDeleted
+	log.Debug().Msgf("DeleteBooks: %s %d", query, id)
+	return s.changeBook(ctx, model.HistoryDelete, id, query, id)
+}
+
+// RestoreBooks will unmark a deleted book id is matched and return number of book that is restored and return 0 if no book restore
+func (s sqlStorage) RestoreBooks(ctx context.Context, id int) (int64, error) {
+	query := "UPDATE book SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP " +
+		"WHERE book_id = ? AND deleted_at IS NOT NULL"
+	log.Debug().Msgf("RestoreBooks: %s %d", query, id)
+	return s.changeBook(ctx, model.HistoryRestore, id, query, id)
+}
+
+// selectBooks will run the query with the bind arguments and scan every row into a book
+func (s sqlStorage) selectBooks(ctx context.Context, query string, args ...interface{}) ([]model.Book, error) {
+	rows, err := s.db.QueryxContext(ctx, s.db.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	bks := []model.Book{}
+	defer rows.Close()
+	for rows.Next() {
+		var bk model.Book
+		if err = rows.StructScan(&bk); err != nil {
+			return nil, err
+		}
+		bks = append(bks, bk)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	log.Debug().Msgf("%v", bks)
+	return bks, nil
+}
+
+// selectSearchResults will run the search query with the bind arguments and scan every row into a search result,
+// the snippets are HTML escaped with the highlight markers
+func (s sqlStorage) selectSearchResults(ctx context.Context, query string, args ...interface{}) ([]SearchResult, error) {
+	results := []SearchResult{}
+	if err := s.db.SelectContext(ctx, &results, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Snippet = escapeSnippet(results[i].Snippet)
+	}
+	return results, nil
+}
+
+// rowsAffected will return the number of rows that the executed statement changed
+func (s sqlStorage) rowsAffected(result sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, s.mapError(err)
+	}
+	return rowsAffected(result, err)
+}
+
+// mapError will return the unique constraint errors of the isbn as ErrDuplicateISBN,
+// of the publisher names and aliases as ErrDuplicatePublisher and other errors as it is
+func (s sqlStorage) mapError(err error) error {
+	if constraint, ok := s.uniqueConstraint(err); ok && duplicateErrors[constraint] != nil {
+		return fmt.Errorf("%w: %s", duplicateErrors[constraint], err.Error())
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestMapErrorUniqueConstraint(t *testing.T) {
+	s, err := OpenSqliteStorage(filepath.Join(t.TempDir(), "book.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.CloseDB)
+	if err = s.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	setup := []string{
+		"INSERT INTO book (isbn, title, author_name, author_surname, published, publisher) " +
+			"VALUES ('9780451524935', 'T', 'A', 'B', '1949', 'P')",
+		"INSERT INTO publisher (name, name_key) VALUES ('Penguin Books', 'penguin books')",
+		"INSERT INTO publisher_alias (alias_key, alias, publisher_id) VALUES ('penguin', 'Penguin', 1)",
+	}
+	for _, query := range setup {
+		if _, err = s.db.Exec(query); err != nil {
+			t.Fatalf("%s failed: %s", query, err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  error
+	}{
+		{"isbn", "INSERT INTO book (isbn, title, author_name, author_surname, published, publisher) " +
+			"VALUES ('9780451524935', 'T', 'A', 'B', '1949', 'P')", ErrDuplicateISBN},
+		{"publisher name", "INSERT INTO publisher (name, name_key) VALUES ('PENGUIN BOOKS', 'penguin books')",
+			ErrDuplicatePublisher},
+		{"publisher alias", "INSERT INTO publisher_alias (alias_key, alias, publisher_id) VALUES ('penguin', 'penguin', 1)",
+			ErrDuplicatePublisher},
+	}
+	for _, tc := range tests {
+		_, err := s.db.Exec(tc.query)
+		if err == nil {
+			t.Fatalf("%s duplicate did not fail", tc.name)
+		}
+		if got := s.mapError(err); !errors.Is(got, tc.want) {
+			t.Errorf("%s duplicate mapError = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// other errors are returned as they are
+	_, err = s.db.Exec("INSERT INTO book_author (book_id, author_id, role, position) VALUES (1, 1, 'author', 1), (1, 1, 'author', 2)")
+	if err == nil {
+		t.Fatal("book_author duplicate did not fail")
+	}
+	if got := s.mapError(err); errors.Is(got, ErrDuplicateISBN) || errors.Is(got, ErrDuplicatePublisher) {
+		t.Errorf("book_author duplicate mapError = %v, want the error as it is", got)
+	}
+}
@@ -1,170 +1,74 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"goapp/config"
-	"goapp/pkg/model"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog/log"
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
-type SqliteStorage struct{ db *sqlx.DB }
-type PageList struct {
-	OrderBy string
-	Limit   int
-	OffSet  int
-}
-type Storage interface {
-	ListBooks(p *PageList) ([]model.Book, error)
-	GetBooks(str string) ([]model.Book, error)
-	InsertBooks(str string) (int64, error)
-	UpdateBooks(bk *model.Book) (int64, error)
-	PatchBooks(str string) (int64, error)
-	DeleteBooks(str string) (int64, error)
-}
-
-// OpenSqliteStorage to initialize the sqlite database
-func OpenSqliteStorage() *SqliteStorage {
-	return &SqliteStorage{db: OpenDB()}
-}
-
-// OpenDB will access to sqlite database that locate in local file path
-func OpenDB() *sqlx.DB {
-	db, err := sqlx.Open("sqlite", config.DBFile)
-	if err != nil {
-		log.Fatal().Err(err).Msg(config.DBConnectErrMsg)
-	}
-	return db
-}
-
-// CloseDB to close the database connection
-func (s SqliteStorage) CloseDB() {
-	if err := s.db.Close(); err != nil {
-		log.Fatal().Err(err).Msg(config.DBCloseErrMsg)
-	}
-}
+type SqliteStorage struct{ sqlStorage }
 
-// ListBooks will return all books with order by, page id and page size configuration that passing through
-func (s SqliteStorage) ListBooks(p *PageList) ([]model.Book, error) {
-	query := fmt.Sprintf("SELECT * FROM book ORDER BY %v LIMIT %v OFFSET %v", p.OrderBy, p.Limit, p.OffSet)
-	rows, err := s.db.Queryx(query)
-	log.Debug().Msgf("ListBooks: %s", query)
-	if err != nil {
-		return nil, err
-	}
+var _ Database = (*SqliteStorage)(nil)
 
-	bks := []model.Book{}
-	defer rows.Close()
-	for rows.Next() {
-		var bk model.Book
-		if err = rows.StructScan(&bk); err != nil {
-			return nil, err
-		}
-		bks = append(bks, bk)
-	}
-	log.Debug().Msgf("%v", bks)
-	return bks, nil
-}
-
-// GetBooks will return all books that match with condition string that passing through
-func (s SqliteStorage) GetBooks(str string) ([]model.Book, error) {
-	query := fmt.Sprintf("SELECT * FROM book WHERE %s", str)
-	rows, err := s.db.Queryx(query)
-	log.Debug().Msgf("FindAllBooks: %s", query)
+// OpenSqliteStorage to initialize the sqlite database that locate in local file path
+func OpenSqliteStorage(file string) (*SqliteStorage, error) {
+	db, err := sqlx.Open("sqlite", file)
 	if err != nil {
 		return nil, err
 	}
-
-	bks := []model.Book{}
-	defer rows.Close()
-	for rows.Next() {
-		var bk model.Book
-		if err = rows.StructScan(&bk); err != nil {
-			return nil, err
-		}
-		bks = append(bks, bk)
-	}
-	log.Debug().Msgf("%v", bks)
-	return bks, nil
+	return &SqliteStorage{sqlStorage{db: db, dialect: "sqlite", like: "LIKE",
+		uniqueConstraint: sqliteUniqueConstraint}}, nil
 }
 
-// InsertBooks is able to insert single/multiple books depends on string that passing through
-// and will return the number of rows that inserted.
-func (s SqliteStorage) InsertBooks(str string) (int64, error) {
-	query := fmt.Sprintf("INSERT INTO book (isbn, title, author_name, author_surname, published, publisher) "+
-		"VALUES %s", str)
-	result, err := s.db.Exec(query)
-	log.Debug().Msgf("InsertBooks: %s", query)
-
-	var rowsAffected int64
-	if err != nil {
-		return rowsAffected, err
-	}
-	rowsAffected, err = result.RowsAffected()
-	if err != nil {
-		return rowsAffected, err
+// sqliteUniqueConstraint will return the table.column of the unique or primary key constraint that the error is caused by
+func sqliteUniqueConstraint(err error) (string, bool) {
+	var e *sqlite.Error
+	if !errors.As(err, &e) || (e.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE && e.Code() != sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+		return "", false
 	}
-	log.Debug().Msgf("RowsAffected: %d", rowsAffected)
-	return rowsAffected, nil
-}
-
-// UpdateBooks will update single book and all the book fields are required
-// it will return number of book that is updated and return 0 if no book update
-func (s SqliteStorage) UpdateBooks(bk *model.Book) (int64, error) {
-	query := fmt.Sprintf("UPDATE book SET isbn = '%v', title = '%v', author_name = '%v', author_surname = '%v', "+
-		"published = '%v', publisher = '%v' WHERE book_id = '%v'", bk.ISBN, bk.Title, bk.AuthorName, bk.AuthorSurname,
-		bk.Published, bk.Publisher, bk.ID)
-	result, err := s.db.Exec(query)
-	log.Debug().Msgf("UpdateBooks: %s", query)
-
-	var rowsAffected int64
-	if err != nil {
-		return rowsAffected, err
+	// the message is like UNIQUE constraint failed: book.isbn (2067)
+	msg := e.Error()
+	if i := strings.LastIndex(msg, "failed: "); i >= 0 {
+		msg = msg[i+len("failed: "):]
 	}
-	rowsAffected, err = result.RowsAffected()
-	if err != nil {
-		return rowsAffected, err
+	if f := strings.Fields(msg); len(f) > 0 {
+		return f[0], true
 	}
-	log.Debug().Msgf("RowsAffected: %d", rowsAffected)
-	return rowsAffected, nil
+	return "", true
 }
 
-// PatchBooks will patch single book and only book_id that is required,
-// other field that is empty or not define will be ignored
-// it will return number of book that is updated and return 0 if no book update
-func (s SqliteStorage) PatchBooks(str string) (int64, error) {
-	query := fmt.Sprintf("UPDATE book SET %s", str)
-	result, err := s.db.Exec(query)
-	log.Debug().Msgf("UpdateBooks: %s", query)
-
-	var rowsAffected int64
-	if err != nil {
-		return rowsAffected, err
+// SearchBooks will return the books that match every search term from the book_fts FTS5 index,
+// ranked by bm25 with the field boosts as the column weights
+func (s SqliteStorage) SearchBooks(ctx context.Context, q *SearchQuery) ([]SearchResult, error) {
+	if len(q.Terms) == 0 {
+		return nil, ErrEmptySearch
 	}
-	rowsAffected, err = result.RowsAffected()
+	cols, err := selectColumns("book.", q.Columns)
 	if err != nil {
-		return rowsAffected, err
+		return nil, err
 	}
-	log.Debug().Msgf("RowsAffected: %d", rowsAffected)
-	return rowsAffected, nil
+	// bm25 is lower for the better matches, the rank is negated so higher is more relevant
+	query := "SELECT " + cols + ", -bm25(book_fts, ?, ?, ?, ?, ?) AS rank, " +
+		"snippet(book_fts, -1, ?, ?, '…', 12) AS snippet " +
+		"FROM book_fts JOIN book ON book.book_id = book_fts.rowid " +
+		"WHERE book_fts MATCH ? " + searchDeleted(q) + "ORDER BY rank DESC, book.book_id LIMIT ? OFFSET ?"
+	match := ftsQuery(q.Terms)
+	log.Debug().Msgf("SearchBooks: %s %s %v", query, match, q.Boosts)
+	author := q.boost("author")
+	return s.selectSearchResults(ctx, query, q.boost("title"), author, author, q.boost("publisher"), q.boost("isbn"),
+		snippetStart, snippetEnd, match, q.Limit, q.OffSet)
 }
 
-// DeleteBooks will delete a book id is matched and return number of book that is deleted and return 0 if no book delete
-func (s SqliteStorage) DeleteBooks(str string) (int64, error) {
-	query := fmt.Sprintf("DELETE from book WHERE book_id = '%s'", str)
-	result, err := s.db.Exec(query)
-	log.Debug().Msgf("DeleteBooks: %s", query)
-
-	var rowsAffected int64
-	if err != nil {
-		return rowsAffected, err
-	}
-	rowsAffected, err = result.RowsAffected()
-	if err != nil {
-		return rowsAffected, err
-	}
-	log.Debug().Msgf("RowsAffected: %d", rowsAffected)
-	return rowsAffected, nil
+// PurgeBooks will remove the books that are deleted longer than olderThan ago for good
+// and return number of book that is purged, the purged books are recorded in the book history
+func (s SqliteStorage) PurgeBooks(ctx context.Context, olderThan time.Duration) (int64, error) {
+	modifier := fmt.Sprintf("%+d seconds", -int64(olderThan.Seconds()))
+	return s.purgeBooks(ctx, "deleted_at < datetime('now', ?)", modifier)
 }
@@ -0,0 +1,22 @@
+package db_test
+
+import (
+	"goapp/pkg/db"
+	"goapp/pkg/db/dbtest"
+	"path/filepath"
+	"testing"
+)
+
+func TestSqliteStorage(t *testing.T) {
+	dbtest.RunStorageSuite(t, func(t *testing.T) db.Storage {
+		s, err := db.OpenSqliteStorage(filepath.Join(t.TempDir(), "book.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(s.CloseDB)
+		if err = s.MigrateUp(); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapp/pkg/model"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrEmptyFilter is returned when a filter does not have any field to look for
+	ErrEmptyFilter = errors.New("no filter fields define")
+	// ErrBookNotFound is returned when there is no book with the book_id
+	ErrBookNotFound = errors.New("book not found")
+	// ErrDuplicateISBN is returned when the isbn is already used by another book
+	ErrDuplicateISBN = errors.New("isbn already exists")
+	// ErrBatchRolledBack is returned when an AllOrNothing insert is rolled back because some of the books failed
+	ErrBatchRolledBack = errors.New("batch rolled back")
+	// ErrVersionConflict is returned when the book is updated with a version that is not its current version
+	ErrVersionConflict = errors.New("book version conflict")
+)
+
+// PageList to define the order and the page of the listed books.
+// The page start at OffSet, or right after/before the Keyset book when it is set.
+// Books with the same OrderBy values are ordered by book_id, only the books that match the Filter are listed.
+// Only the Columns are selected, all columns if it is empty.
+type PageList struct {
+	Columns []string
+	Filter  *Filter
+	OrderBy []SortField
+	Limit   int
+	OffSet  int
+	Keyset  *Keyset
+}
+
+// Keyset is the position of a book in the list order that the keyset page start from.
+// Values are the order by column values of the book in the OrderBy order, without the book_id.
+// The books after the position are listed, or the books before it when Before is set,
+// both are returned in the list order.
+type Keyset struct {
+	Values []string
+	ID     int
+	Before bool
+}
+
+// FilterMode define how the fields of a BookFilter are combined in the query
+type FilterMode int
+
+const (
+	// MatchAll will match books where every non-empty field is equal (column = value joined with AND)
+	MatchAll FilterMode = iota
+	// MatchAny will match books where any non-empty field contains the value (column LIKE %value% joined with OR)
+	MatchAny
+)
+
+// BookFilter to define the book fields to look for and how they are matched.
+// Empty string fields and book_id 0 will be ignored. Only the Columns are selected, all columns if it is empty.
+// The deleted books are only matched with IncludeDeleted.
+type BookFilter struct {
+	Book           model.Book
+	Mode           FilterMode
+	Columns        []string
+	IncludeDeleted bool
+}
+
+// InsertMode define what happen to the batch when some of the books could not be inserted
+type InsertMode int
+
+const (
+	// AllOrNothing will not insert any book if one of them fail
+	AllOrNothing InsertMode = iota
+	// BestEffort will insert every book that does not fail
+	BestEffort
+)
+
+// InsertResult is the outcome of a single book insert.
+// ID is the new book_id, it is 0 when the book failed with Err or was rolled back with the batch.
+type InsertResult struct {
+	ID  int
+	Err error
+}
+
+// Storage is the behaviour contract that every book storage backend need to follow.
+// Every method will stop and return the context error when the context is canceled or its deadline exceeded,
+// and every change of a book is recorded in its history with the actor of the context.
+type Storage interface {
+	ListBooks(ctx context.Context, p *PageList) ([]model.Book, error)
+	CountBooks(ctx context.Context, f *Filter) (int, error)
+	GetBooks(ctx context.Context, f *BookFilter) ([]model.Book, error)
+	GetBook(ctx context.Context, id int, columns ...string) (model.Book, error)
+	SearchBooks(ctx context.Context, q *SearchQuery) ([]SearchResult, error)
+	// InsertBooks will set the publisher of every book like UpdateBooks
+	// and credit the author of its author_name and author_surname, a new one when there is none.
+	InsertBooks(ctx context.Context, bks []model.Book, mode InsertMode) ([]InsertResult, error)
+	// UpdateBooks, ApplyBookPatch and PatchBooks will increase the book version and set its updated_at,
+	// when the book version is set they will return ErrVersionConflict if it is not the current version.
+	// A book with a new publisher_id get the name of the publisher, a book with a new publisher get
+	// the publisher with the name or alias, a new one when there is none, and a book with a new author name
+	// credit the author of the name first.
+	UpdateBooks(ctx context.Context, bk *model.Book) (int64, error)
+	ApplyBookPatch(ctx context.Context, bk *model.Book) (int64, error)
+	PatchBooks(ctx context.Context, bk *model.PatchBook) (int64, error)
+	// DeleteBooks will only mark the book as deleted, the deleted books are left out by the other methods
+	// unless they are included, could be restored with RestoreBooks and are removed for good by PurgeBooks.
+	DeleteBooks(ctx context.Context, id int) (int64, error)
+	RestoreBooks(ctx context.Context, id int) (int64, error)
+	PurgeBooks(ctx context.Context, olderThan time.Duration) (int64, error)
+	ListBookHistory(ctx context.Context, q *HistoryQuery) ([]model.BookHistory, error)
+	// RevertBook will set the book back to how it was after one of its changes.
+	RevertBook(ctx context.Context, id, historyID, version int) (int64, error)
+	ListAuthors(ctx context.Context, q *AuthorQuery) ([]model.Author, error)
+	GetAuthor(ctx context.Context, id int) (model.Author, error)
+	InsertAuthor(ctx context.Context, a *model.Author) error
+	// UpdateAuthor will set the author_name and author_surname of the books that credit the author first.
+	UpdateAuthor(ctx context.Context, a *model.Author) error
+	DeleteAuthor(ctx context.Context, id int) error
+	GetBookAuthors(ctx context.Context, bookID int) ([]model.BookAuthor, error)
+	// SetBookAuthors will credit the authors with a role in the credit order,
+	// the book get the name of its first author as author_name and author_surname.
+	SetBookAuthors(ctx context.Context, bookID int, authors []model.BookAuthor) error
+	ListAuthorBooks(ctx context.Context, q *AuthorBooksQuery) ([]model.AuthorBook, error)
+	ListPublishers(ctx context.Context, q *PublisherQuery) ([]model.Publisher, error)
+	GetPublisher(ctx context.Context, id int) (model.Publisher, error)
+	InsertPublisher(ctx context.Context, p *model.Publisher) error
+	// UpdatePublisher and MergePublishers will set the publisher of the books to the name of their publisher.
+	UpdatePublisher(ctx context.Context, p *model.Publisher) error
+	DeletePublisher(ctx context.Context, id int) error
+	MergePublishers(ctx context.Context, id int, sourceIDs []int) error
+	ListPublisherDuplicates(ctx context.Context) ([]model.PublisherCluster, error)
+	ListPublisherBooks(ctx context.Context, q *PublisherBooksQuery) ([]model.Book, error)
+}
+
+// Database is a Storage that manage its own connection and schema migrations
+type Database interface {
+	Storage
+	CloseDB()
+	MigrateUp() error
+	MigrateDown(steps int) error
+	MigrationStatus() ([]MigrationStatus, error)
+}
+
+// BookColumns are the book table columns that the books can be ordered by
+var BookColumns = []string{"book_id", "isbn", "title", "author_name", "author_surname", "published", "publisher"}
+
+// IsBookColumn will check if the column is one of the BookColumns
+func IsBookColumn(col string) bool {
+	for _, c := range BookColumns {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// BookFields are the book table columns that can be selected, the BookColumns with the publisher_id,
+// version and delete columns
+var BookFields = append(append([]string{}, BookColumns...), "publisher_id", "version", "updated_at", "deleted_at")
+
+// IsBookField will check if the column is one of the BookFields
+func IsBookField(col string) bool {
+	for _, c := range BookFields {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrUnknownColumn is returned when a selected column is not one of the BookFields
+var ErrUnknownColumn = errors.New("unknown column")
+
+// selectColumns will return the sql select list of the book columns with the table prefix, * if there is none
+func selectColumns(table string, cols []string) (string, error) {
+	if len(cols) == 0 {
+		return table + "*", nil
+	}
+	list := make([]string, len(cols))
+	for i, col := range cols {
+		if !IsBookField(col) {
+			return "", fmt.Errorf("%w: %q", ErrUnknownColumn, col)
+		}
+		list[i] = table + col
+	}
+	return strings.Join(list, ", "), nil
+}
+
+// Fields will return the db column names and values of the filter fields that are not empty
+func (f *BookFilter) Fields() ([]string, []interface{}) {
+	return nonEmptyFields(&f.Book)
+}
+
+// OpenStorage will open the database that is selected by the url scheme.
+// Supported urls are sqlite://<file path> and postgres://<user>:<password>@<host>/<dbname>?<options>
+func OpenStorage(url string) (Database, error) {
+	switch {
+	case strings.HasPrefix(url, "sqlite://"):
+		return OpenSqliteStorage(strings.TrimPrefix(url, "sqlite://"))
+	case strings.HasPrefix(url, "postgres://"), strings.HasPrefix(url, "postgresql://"):
+		return OpenPostgresStorage(url)
+	}
+	return nil, fmt.Errorf("unsupported database url: %s", url)
+}
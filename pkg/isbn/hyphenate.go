@@ -0,0 +1,121 @@
+package isbn
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownRange is returned when the isbn is not in a registration group or registrant range of the range table
+var ErrUnknownRange = errors.New("isbn is not in a known registration range")
+
+//go:embed ranges.txt
+var rangesFile string
+
+// registrantRange is a range of the 7 digits that follow the group and the number of registrant digits in it
+type registrantRange struct {
+	start, end int
+	length     int
+}
+
+// group is a registration group of the prefix with its registrant ranges
+type group struct {
+	prefix string
+	id     string
+	agency string
+	ranges []registrantRange
+}
+
+// groups of the embedded range table
+var groups = mustParseRanges(rangesFile)
+
+// mustParseRanges will parse the range table, it panic on the malformed table since it is embedded
+func mustParseRanges(table string) []group {
+	var gs []group
+	for n, line := range strings.Split(table, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		start, end, isRange := strings.Cut(key, "-")
+		if !isRange {
+			panic(fmt.Sprintf("isbn: invalid range table line %d: %q", n+1, line))
+		}
+		if len(start) == 3 {
+			gs = append(gs, group{prefix: start, id: end, agency: value})
+			continue
+		}
+		s, err1 := strconv.Atoi(start)
+		e, err2 := strconv.Atoi(end)
+		l, err3 := strconv.Atoi(value)
+		if len(gs) == 0 || len(start) != 7 || len(end) != 7 || err1 != nil || err2 != nil || err3 != nil {
+			panic(fmt.Sprintf("isbn: invalid range table line %d: %q", n+1, line))
+		}
+		g := &gs[len(gs)-1]
+		g.ranges = append(g.ranges, registrantRange{start: s, end: e, length: l})
+	}
+	return gs
+}
+
+// split will return the prefix, group, registrant, publication and check digit parts of the ISBN-13 digits
+func split(digits string) ([]string, error) {
+	for _, g := range groups {
+		if !strings.HasPrefix(digits[3:], g.id) || digits[:3] != g.prefix {
+			continue
+		}
+		rest := digits[3+len(g.id) : 12]
+		// the ranges are compared with the 7 digits that follow the group
+		key, _ := strconv.Atoi((rest + "0000000")[:7])
+		for _, r := range g.ranges {
+			if key < r.start || key > r.end {
+				continue
+			}
+			if r.length == 0 || r.length >= len(rest) {
+				return nil, ErrUnknownRange
+			}
+			return []string{g.prefix, g.id, rest[:r.length], rest[r.length:], digits[12:]}, nil
+		}
+		return nil, ErrUnknownRange
+	}
+	return nil, ErrUnknownRange
+}
+
+// Hyphenate will return the isbn with the hyphens between its prefix, registration group, registrant,
+// publication and check digit, like 978-0-306-40615-7. ISBN-10 is hyphenated as ISBN-10 without the prefix.
+// It will return ErrUnknownRange when the group or the registrant range is not in the range table.
+func Hyphenate(s string) (string, error) {
+	digits, err := parse(s)
+	if err != nil {
+		return "", err
+	}
+	d13 := digits
+	if len(digits) == 10 {
+		d13 = to13(digits)
+	}
+	parts, err := split(d13)
+	if err != nil {
+		return "", err
+	}
+	if len(digits) == 10 {
+		parts = append(parts[1:4], digits[9:])
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+// Agency will return the registration group agency of the isbn, like English language or Japan.
+// It will return ErrUnknownRange when the group is not in the range table.
+func Agency(s string) (string, error) {
+	d13, err := Normalize(s)
+	if err != nil {
+		return "", err
+	}
+	for _, g := range groups {
+		if d13[:3] == g.prefix && strings.HasPrefix(d13[3:], g.id) {
+			return g.agency, nil
+		}
+	}
+	return "", ErrUnknownRange
+}
@@ -0,0 +1,144 @@
+// Package isbn will validate, normalize, convert and hyphenate ISBN-10 and ISBN-13 book numbers.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrLength is returned when the isbn does not have 10 or 13 digits
+	ErrLength = errors.New("isbn must have 10 or 13 digits")
+	// ErrCharacter is returned when the isbn has other characters than digits, hyphens and spaces,
+	// only the ISBN-10 check digit could be X
+	ErrCharacter = errors.New("isbn may only have digits, hyphens and spaces")
+	// ErrChecksum is returned when the check digit does not match the other digits
+	ErrChecksum = errors.New("isbn check digit is not valid")
+	// ErrPrefix is returned when the ISBN-13 does not start with the 978 or 979 book prefix
+	ErrPrefix = errors.New("ISBN-13 must start with 978 or 979")
+	// ErrNoISBN10 is returned when an ISBN-13 that does not start with 978 is converted to ISBN-10
+	ErrNoISBN10 = errors.New("only ISBN-13 that start with 978 have an ISBN-10")
+)
+
+// parse will return the digits of the isbn without the hyphens, spaces and the optional ISBN label,
+// with an uppercase X check digit of ISBN-10 and checked for the length, characters and check digit
+func parse(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 4 && strings.EqualFold(s[:4], "ISBN") {
+		s = strings.TrimPrefix(strings.TrimPrefix(s[4:], "-10"), "-13")
+		s = strings.TrimPrefix(strings.TrimSpace(s), ":")
+	}
+	digits := make([]byte, 0, 13)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case c == 'X' || c == 'x':
+			digits = append(digits, 'X')
+		case c == '-' || c == ' ':
+		default:
+			return "", ErrCharacter
+		}
+	}
+	switch len(digits) {
+	case 10:
+		if strings.IndexByte(string(digits[:9]), 'X') >= 0 {
+			return "", ErrCharacter
+		}
+		if checkDigit10(string(digits[:9])) != digits[9] {
+			return "", ErrChecksum
+		}
+	case 13:
+		if strings.IndexByte(string(digits), 'X') >= 0 {
+			return "", ErrCharacter
+		}
+		if !strings.HasPrefix(string(digits), "978") && !strings.HasPrefix(string(digits), "979") {
+			return "", ErrPrefix
+		}
+		if checkDigit13(string(digits[:12])) != digits[12] {
+			return "", ErrChecksum
+		}
+	default:
+		return "", ErrLength
+	}
+	return string(digits), nil
+}
+
+// checkDigit10 will return the ISBN-10 check digit of the first 9 digits, X for 10
+func checkDigit10(digits string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(digits[i]-'0') * (10 - i)
+	}
+	switch d := (11 - sum%11) % 11; d {
+	case 10:
+		return 'X'
+	default:
+		return byte('0' + d)
+	}
+}
+
+// checkDigit13 will return the ISBN-13 check digit of the first 12 digits
+func checkDigit13(digits string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		w := 1
+		if i%2 == 1 {
+			w = 3
+		}
+		sum += int(digits[i]-'0') * w
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// Validate will return nil if the isbn is a valid ISBN-10 or ISBN-13, hyphens and spaces are allowed
+func Validate(s string) error {
+	_, err := parse(s)
+	return err
+}
+
+// Normalize will return the isbn as the canonical ISBN-13 digits without hyphens,
+// ISBN-10 is converted to ISBN-13 so both forms of the same book have the same value
+func Normalize(s string) (string, error) {
+	digits, err := parse(s)
+	if err != nil {
+		return "", err
+	}
+	if len(digits) == 10 {
+		return to13(digits), nil
+	}
+	return digits, nil
+}
+
+// To13 will convert the isbn to ISBN-13 digits, ISBN-13 is returned as it is without hyphens
+func To13(s string) (string, error) {
+	return Normalize(s)
+}
+
+// To10 will convert the isbn to ISBN-10 digits, ISBN-10 is returned as it is without hyphens.
+// Only the ISBN-13 that start with 978 could be converted, otherwise it will return ErrNoISBN10.
+func To10(s string) (string, error) {
+	digits, err := parse(s)
+	if err != nil {
+		return "", err
+	}
+	if len(digits) == 10 {
+		return digits, nil
+	}
+	if !strings.HasPrefix(digits, "978") {
+		return "", ErrNoISBN10
+	}
+	return to10(digits), nil
+}
+
+// to13 will convert the valid ISBN-10 digits to ISBN-13 digits
+func to13(digits string) string {
+	d := "978" + digits[:9]
+	return d + string(checkDigit13(d))
+}
+
+// to10 will convert the valid 978 ISBN-13 digits to ISBN-10 digits
+func to10(digits string) string {
+	d := digits[3:12]
+	return d + string(checkDigit10(d))
+}
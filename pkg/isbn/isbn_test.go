@@ -0,0 +1,154 @@
+package isbn
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want error
+	}{
+		{"ISBN-10", "0306406152", nil},
+		{"ISBN-13", "9780306406157", nil},
+		{"979 prefix", "9791090636071", nil},
+		{"hyphens", "978-0-306-40615-7", nil},
+		{"spaces", " 978 0 306 40615 7 ", nil},
+		{"label", "ISBN-13: 978-0-306-40615-7", nil},
+		{"X check digit", "0-8044-2957-X", nil},
+		{"lowercase x check digit", "080442957x", nil},
+		{"ISBN-10 check digit", "0306406153", ErrChecksum},
+		{"ISBN-10 X instead of digit", "030640615X", ErrChecksum},
+		{"ISBN-13 check digit", "9780306406158", ErrChecksum},
+		{"X before the check digit", "0X06406152", ErrCharacter},
+		{"X in ISBN-13", "978030640615X", ErrCharacter},
+		{"letter", "97803064O6157", ErrCharacter},
+		{"other separator", "978.0.306.40615.7", ErrCharacter},
+		{"too short", "030640615", ErrLength},
+		{"too long", "97803064061570", ErrLength},
+		{"empty", "", ErrLength},
+		{"not a book prefix", "9770306406157", ErrPrefix},
+	}
+	for _, tc := range tests {
+		if err := Validate(tc.in); !errors.Is(err, tc.want) {
+			t.Errorf("Validate %s %q = %v, want %v", tc.name, tc.in, err, tc.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		want13 string
+		want10 string
+		err10  error
+	}{
+		{"ISBN-10", "0306406152", "9780306406157", "0306406152", nil},
+		{"ISBN-13", "978-0-306-40615-7", "9780306406157", "0306406152", nil},
+		{"ISBN-10 with X", "080442957X", "9780804429573", "080442957X", nil},
+		{"ISBN-13 to X", "9780804429573", "9780804429573", "080442957X", nil},
+		{"979 France", "979-10-90636-07-1", "9791090636071", "", ErrNoISBN10},
+		{"979 without group", "9798000000007", "9798000000007", "", ErrNoISBN10},
+	}
+	for _, tc := range tests {
+		if got, err := Normalize(tc.in); err != nil || got != tc.want13 {
+			t.Errorf("Normalize %s %q = %q, %v, want %q", tc.name, tc.in, got, err, tc.want13)
+		}
+		if got, err := To13(tc.in); err != nil || got != tc.want13 {
+			t.Errorf("To13 %s %q = %q, %v, want %q", tc.name, tc.in, got, err, tc.want13)
+		}
+		if got, err := To10(tc.in); !errors.Is(err, tc.err10) || got != tc.want10 {
+			t.Errorf("To10 %s %q = %q, %v, want %q, %v", tc.name, tc.in, got, err, tc.want10, tc.err10)
+		}
+	}
+	if _, err := To10("9780306406158"); !errors.Is(err, ErrChecksum) {
+		t.Errorf("To10 invalid isbn error = %v, want %v", err, ErrChecksum)
+	}
+}
+
+func TestHyphenate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  error
+	}{
+		{"English", "9780306406157", "978-0-306-40615-7", nil},
+		{"English ISBN-10", "0306406152", "0-306-40615-2", nil},
+		{"English ISBN-10 with X", "080442957X", "0-8044-2957-X", nil},
+		{"English 1 group", "9781934356685", "978-1-934356-68-5", nil},
+		{"German", "9783161484100", "978-3-16-148410-0", nil},
+		{"Japan", "9784873113685", "978-4-87311-368-5", nil},
+		{"France 979", "979-10-90636-07-1", "979-10-90636-07-1", nil},
+		{"misplaced hyphens", "97-80306-406157", "978-0-306-40615-7", nil},
+		{"unknown group", "9786000000004", "", ErrUnknownRange},
+		{"unknown 979 group", "9798000000007", "", ErrUnknownRange},
+		{"English 7 digit registrant", "9780639800004", "978-0-6398000-0-4", nil},
+		{"range not in use", "9788730000002", "", ErrUnknownRange},
+		{"invalid", "9780306406158", "", ErrChecksum},
+	}
+	for _, tc := range tests {
+		if got, err := Hyphenate(tc.in); !errors.Is(err, tc.err) || got != tc.want {
+			t.Errorf("Hyphenate %s %q = %q, %v, want %q, %v", tc.name, tc.in, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestAgency(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"0306406152", "English language", nil},
+		{"9791090636071", "France", nil},
+		{"9786000000004", "", ErrUnknownRange},
+	}
+	for _, tc := range tests {
+		if got, err := Agency(tc.in); !errors.Is(err, tc.err) || got != tc.want {
+			t.Errorf("Agency %q = %q, %v, want %q, %v", tc.in, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	table := `# comment
+
+978-0 English language
+0000000-1999999 2
+2000000-6999999 3
+
+979-10 France
+0000000-1999999 2
+`
+	want := []group{
+		{prefix: "978", id: "0", agency: "English language",
+			ranges: []registrantRange{{0, 1999999, 2}, {2000000, 6999999, 3}}},
+		{prefix: "979", id: "10", agency: "France", ranges: []registrantRange{{0, 1999999, 2}}},
+	}
+	if got := mustParseRanges(table); !reflect.DeepEqual(got, want) {
+		t.Errorf("mustParseRanges = %+v, want %+v", got, want)
+	}
+
+	for _, malformed := range []string{
+		"0000000-1999999 2",                // range before any group
+		"978-0 English\n0000000 2",         // range without end
+		"978-0 English\n00000-1999999 2",   // start is not 7 digits
+		"978-0 English\n0000000-199 2",     // end is not 7 digits
+		"978-0 English\n000000a-1999999 2", // start is not a number
+		"978-0 English\n0000000-1999999 x", // length is not a number
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("mustParseRanges(%q) did not panic", malformed)
+				}
+			}()
+			mustParseRanges(malformed)
+		}()
+	}
+}
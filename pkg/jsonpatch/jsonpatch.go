@@ -0,0 +1,368 @@
+// Package jsonpatch will apply JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902) documents to JSON documents.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Media types of the patch documents
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalidPatch is returned when the patch document is not valid JSON or has an invalid operation
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPathNotFound is returned when the operation path does not exist in the document
+	ErrPathNotFound = errors.New("path not found")
+	// ErrTestFailed is returned when the value of a test operation is not the document value
+	ErrTestFailed = errors.New("test operation failed")
+)
+
+// MergePatch will apply the merge patch to the document and return the patched document.
+// A null member of the patch will remove the member from the document.
+func MergePatch(doc []byte, patch []byte) ([]byte, error) {
+	d, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err.Error())
+	}
+	return json.Marshal(mergePatch(d, p))
+}
+
+// mergePatch will merge the patch value into the target value like RFC 7396 define
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+// Operation is a single JSON Patch operation, Value is nil when the operation does not have a value
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch is a JSON Patch document, the operations are applied in order
+type Patch []Operation
+
+// DecodePatch will decode and check the operations of the JSON Patch document
+func DecodePatch(b []byte) (Patch, error) {
+	var p Patch
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err.Error())
+	}
+	for i, op := range p {
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d %s does not have a value", ErrInvalidPatch, i, op.Op)
+			}
+		case "move", "copy":
+			if _, err := parsePointer(op.From); err != nil {
+				return nil, fmt.Errorf("%w: operation %d from: %s", ErrInvalidPatch, i, err.Error())
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: operation %d has unknown op %q", ErrInvalidPatch, i, op.Op)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return nil, fmt.Errorf("%w: operation %d path: %s", ErrInvalidPatch, i, err.Error())
+		}
+	}
+	return p, nil
+}
+
+// Apply will apply every operation of the patch to the document and return the patched document.
+// The document is not changed if any of the operations fail.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	d, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		if d, err = op.apply(d); err != nil {
+			return nil, fmt.Errorf("operation %d %s %s: %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(d)
+}
+
+// apply will apply the operation to the decoded document and return the patched document
+func (op Operation) apply(doc interface{}) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err.Error())
+	}
+	var value interface{}
+	if op.Value != nil {
+		if value, err = decode(op.Value); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err.Error())
+		}
+	}
+	switch op.Op {
+	case "add":
+		return add(doc, path, value)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		if len(path) == 0 {
+			return value, nil
+		}
+		if doc, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "test":
+		v, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(v, value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err.Error())
+		}
+		v, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			// the copy need to be a new value so the later operations do not change both
+			b, _ := json.Marshal(v)
+			v, _ = decode(b)
+			return add(doc, path, v)
+		}
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, fmt.Errorf("%w: could not move %s into its own child", ErrInvalidPatch, op.From)
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, v)
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+}
+
+// decode will decode the JSON value with the numbers kept as they are written
+func decode(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+// parsePointer will split the JSON Pointer (RFC 6901) into its unescaped reference tokens, none for the whole document
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, fmt.Errorf("pointer %q does not start with /", p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// isPrefix will check if the prefix tokens are the start of the path tokens
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex will parse the array index token, the index has to be less than n
+func arrayIndex(token string, n int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i >= n || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: array index %q", ErrPathNotFound, token)
+	}
+	return i, nil
+}
+
+// get will return the document value at the path
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, t := range path {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			v, ok := d[t]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q", ErrPathNotFound, t)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(d))
+			if err != nil {
+				return nil, err
+			}
+			doc = d[i]
+		default:
+			return nil, fmt.Errorf("%w: %q is not in an object or array", ErrPathNotFound, t)
+		}
+	}
+	return doc, nil
+}
+
+// walk will call fn with the object or array that hold the last path token
+// and return the document with the container that fn returned
+func walk(doc interface{}, path []string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		child, ok := d[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q", ErrPathNotFound, path[0])
+		}
+		c, err := walk(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		d[path[0]] = c
+		return d, nil
+	case []interface{}:
+		i, err := arrayIndex(path[0], len(d))
+		if err != nil {
+			return nil, err
+		}
+		c, err := walk(d[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		d[i] = c
+		return d, nil
+	}
+	return nil, fmt.Errorf("%w: %q is not in an object or array", ErrPathNotFound, path[0])
+}
+
+// add will add the value at the path, an array value is inserted at the index or appended with -
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return walk(doc, path, func(container interface{}, t string) (interface{}, error) {
+		switch d := container.(type) {
+		case map[string]interface{}:
+			d[t] = value
+			return d, nil
+		case []interface{}:
+			if t == "-" {
+				return append(d, value), nil
+			}
+			i, err := arrayIndex(t, len(d)+1)
+			if err != nil {
+				return nil, err
+			}
+			d = append(d, nil)
+			copy(d[i+1:], d[i:])
+			d[i] = value
+			return d, nil
+		}
+		return nil, fmt.Errorf("%w: %q is not in an object or array", ErrPathNotFound, t)
+	})
+}
+
+// remove will remove the value at the path
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: the whole document could not be removed", ErrInvalidPatch)
+	}
+	return walk(doc, path, func(container interface{}, t string) (interface{}, error) {
+		switch d := container.(type) {
+		case map[string]interface{}:
+			if _, ok := d[t]; !ok {
+				return nil, fmt.Errorf("%w: member %q", ErrPathNotFound, t)
+			}
+			delete(d, t)
+			return d, nil
+		case []interface{}:
+			i, err := arrayIndex(t, len(d))
+			if err != nil {
+				return nil, err
+			}
+			return append(d[:i], d[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%w: %q is not in an object or array", ErrPathNotFound, t)
+	})
+}
+
+// equal will compare two decoded JSON values, numbers are equal when they have the same value
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			if w, ok := y[k]; !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, errx := x.Float64()
+		fy, erry := y.Float64()
+		return errx == nil && erry == nil && fx == fy
+	}
+	return a == b
+}
@@ -0,0 +1,166 @@
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`},
+		{"add replaces member", `{"a":1}`, `[{"op":"add","path":"/a","value":[1]}]`, `{"a":[1]}`},
+		{"add nested", `{"a":{"b":1}}`, `[{"op":"add","path":"/a/c","value":null}]`, `{"a":{"b":1,"c":null}}`},
+		{"add whole document", `{"a":1}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`},
+		{"add array index", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`},
+		{"add array first", `{"a":[1]}`, `[{"op":"add","path":"/a/0","value":0}]`, `{"a":[0,1]}`},
+		{"add array length", `{"a":[1]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2]}`},
+		{"add array end", `{"a":[1]}`, `[{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`},
+		{"add empty array end", `{"a":[]}`, `[{"op":"add","path":"/a/-","value":{"b":1}}]`, `{"a":[{"b":1}]}`},
+		{"remove member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`},
+		{"remove array index", `{"a":[1,2,3]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1,3]}`},
+		{"replace member", `{"a":1}`, `[{"op":"replace","path":"/a","value":"x"}]`, `{"a":"x"}`},
+		{"replace array index", `[1,2]`, `[{"op":"replace","path":"/1","value":3}]`, `[1,3]`},
+		{"replace whole document", `{"a":1}`, `[{"op":"replace","path":"","value":{"b":2}}]`, `{"b":2}`},
+		{"move member", `{"a":{"b":1},"c":{}}`, `[{"op":"move","from":"/a/b","path":"/c/d"}]`, `{"a":{},"c":{"d":1}}`},
+		{"move array item", `{"a":[1,2,3]}`, `[{"op":"move","from":"/a/0","path":"/a/-"}]`, `{"a":[2,3,1]}`},
+		{"copy member", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b","value":2}]`,
+			`{"a":{"b":1},"c":{"b":2}}`},
+		{"copy array item", `{"a":[1,2]}`, `[{"op":"copy","from":"/a/1","path":"/a/0"}]`, `{"a":[2,1,2]}`},
+		{"test", `{"a":[1,{"b":"x"}]}`, `[{"op":"test","path":"/a","value":[1,{"b":"x"}]}]`, `{"a":[1,{"b":"x"}]}`},
+		{"test number", `{"a":1.0}`, `[{"op":"test","path":"/a","value":1}]`, `{"a":1.0}`},
+		{"escaped slash", `{"a/b":1}`, `[{"op":"replace","path":"/a~1b","value":2}]`, `{"a/b":2}`},
+		{"escaped tilde", `{"a~b":1}`, `[{"op":"remove","path":"/a~0b"}]`, `{}`},
+		{"escaped tilde before 1", `{"~1":1}`, `[{"op":"add","path":"/~01","value":2}]`, `{"~1":2}`},
+		{"empty member", `{"":1}`, `[{"op":"test","path":"/","value":1}]`, `{"":1}`},
+		{"operations in order", `{"a":1}`,
+			`[{"op":"add","path":"/b","value":[]},{"op":"add","path":"/b/-","value":1},{"op":"remove","path":"/a"}]`,
+			`{"b":[1]}`},
+	}
+	for _, tc := range tests {
+		p, err := DecodePatch([]byte(tc.patch))
+		if err != nil {
+			t.Errorf("%s: DecodePatch failed: %s", tc.name, err)
+			continue
+		}
+		if got, err := p.Apply([]byte(tc.doc)); err != nil || string(got) != tc.want {
+			t.Errorf("%s: Apply = %s, %v, want %s", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  error
+	}{
+		{"add missing parent", `{}`, `[{"op":"add","path":"/a/b","value":1}]`, ErrPathNotFound},
+		{"add index out of range", `{"a":[1]}`, `[{"op":"add","path":"/a/2","value":1}]`, ErrPathNotFound},
+		{"add negative index", `{"a":[1]}`, `[{"op":"add","path":"/a/-1","value":1}]`, ErrPathNotFound},
+		{"add leading zero index", `{"a":[1,2]}`, `[{"op":"add","path":"/a/01","value":1}]`, ErrPathNotFound},
+		{"add into number", `{"a":1}`, `[{"op":"add","path":"/a/b","value":1}]`, ErrPathNotFound},
+		{"remove missing member", `{"a":1}`, `[{"op":"remove","path":"/b"}]`, ErrPathNotFound},
+		{"remove index out of range", `{"a":[1]}`, `[{"op":"remove","path":"/a/1"}]`, ErrPathNotFound},
+		{"remove array end", `{"a":[1]}`, `[{"op":"remove","path":"/a/-"}]`, ErrPathNotFound},
+		{"remove whole document", `{"a":1}`, `[{"op":"remove","path":""}]`, ErrInvalidPatch},
+		{"replace missing member", `{"a":1}`, `[{"op":"replace","path":"/b","value":1}]`, ErrPathNotFound},
+		{"replace index out of range", `[1]`, `[{"op":"replace","path":"/1","value":1}]`, ErrPathNotFound},
+		{"move missing from", `{"a":1}`, `[{"op":"move","from":"/b","path":"/c"}]`, ErrPathNotFound},
+		{"move into its own child", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, ErrInvalidPatch},
+		{"copy index out of range", `{"a":[1]}`, `[{"op":"copy","from":"/a/1","path":"/b"}]`, ErrPathNotFound},
+		{"test missing member", `{"a":1}`, `[{"op":"test","path":"/b","value":1}]`, ErrPathNotFound},
+		{"test other value", `{"a":1}`, `[{"op":"test","path":"/a","value":2}]`, ErrTestFailed},
+		{"test other type", `{"a":1}`, `[{"op":"test","path":"/a","value":"1"}]`, ErrTestFailed},
+		{"test longer array", `{"a":[1]}`, `[{"op":"test","path":"/a","value":[1,2]}]`, ErrTestFailed},
+		{"escaped member not found", `{"a/b":1}`, `[{"op":"remove","path":"/a/b"}]`, ErrPathNotFound},
+	}
+	for _, tc := range tests {
+		p, err := DecodePatch([]byte(tc.patch))
+		if err != nil {
+			t.Errorf("%s: DecodePatch failed: %s", tc.name, err)
+			continue
+		}
+		if got, err := p.Apply([]byte(tc.doc)); !errors.Is(err, tc.want) || got != nil {
+			t.Errorf("%s: Apply = %s, %v, want %v", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+// TestApplyFailedTest will check that the operations before a failed test are not applied to the document
+func TestApplyFailedTest(t *testing.T) {
+	doc := []byte(`{"version":1,"tags":["a"]}`)
+	p, err := DecodePatch([]byte(`[{"op":"replace","path":"/tags/0","value":"b"},{"op":"add","path":"/tags/-","value":"c"},` +
+		`{"op":"test","path":"/version","value":2},{"op":"remove","path":"/version"}]`))
+	if err != nil {
+		t.Fatalf("DecodePatch failed: %s", err)
+	}
+	got, err := p.Apply(doc)
+	if !errors.Is(err, ErrTestFailed) || got != nil {
+		t.Errorf("Apply = %s, %v, want %v", got, err, ErrTestFailed)
+	}
+	if string(doc) != `{"version":1,"tags":["a"]}` {
+		t.Errorf("the document is changed to %s", doc)
+	}
+}
+
+func TestDecodePatch(t *testing.T) {
+	for _, patch := range []string{
+		``,
+		`{"op":"add","path":"/a","value":1}`,
+		`[{"op":"add","path":"/a","value":1}] []`,
+		`[{"op":"add","path":"/a"}]`,
+		`[{"op":"replace","path":"/a"}]`,
+		`[{"op":"test","path":"/a"}]`,
+		`[{"op":"move","from":"a","path":"/b"}]`,
+		`[{"op":"copy","from":"a","path":"/b"}]`,
+		`[{"op":"remove","path":"a"}]`,
+		`[{"op":"merge","path":"/a","value":1}]`,
+		`[{"path":"/a","value":1}]`,
+	} {
+		if _, err := DecodePatch([]byte(patch)); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("DecodePatch(%s) = %v, want %v", patch, err, ErrInvalidPatch)
+		}
+	}
+	if p, err := DecodePatch([]byte(`[{"op":"add","path":"/a","value":null},{"op":"move","from":"","path":"/b"}]`)); err != nil ||
+		len(p) != 2 {
+		t.Errorf("DecodePatch = %+v, %v, want 2 operations", p, err)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null removes member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"null removes missing member", `{"a":"b"}`, `{"c":null}`, `{"a":"b"}`},
+		{"null removes nested member", `{"a":{"b":1,"c":2}}`, `{"a":{"b":null}}`, `{"a":{"c":2}}`},
+		{"array is replaced", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{"object replaces value", `{"a":"b"}`, `{"a":{"c":null,"d":1}}`, `{"a":{"d":1}}`},
+		{"non object patch", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"null patch", `{"a":"b"}`, `null`, `null`},
+		{"non object document", `["a"]`, `{"a":"b"}`, `{"a":"b"}`},
+		{"empty patch", `{"a":1.50}`, `{}`, `{"a":1.50}`},
+	}
+	for _, tc := range tests {
+		if got, err := MergePatch([]byte(tc.doc), []byte(tc.patch)); err != nil || string(got) != tc.want {
+			t.Errorf("%s: MergePatch = %s, %v, want %s", tc.name, got, err, tc.want)
+		}
+	}
+	if _, err := MergePatch([]byte(`{}`), []byte(`{"a":`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("MergePatch invalid patch error = %v, want %v", err, ErrInvalidPatch)
+	}
+	if _, err := MergePatch([]byte(`{`), []byte(`{}`)); err == nil || errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("MergePatch invalid document error = %v, want the document error", err)
+	}
+}
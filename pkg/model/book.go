@@ -1,17 +1,40 @@
 package model
 
-// Book is main struct for most of the handlers which is no require flag set
+import (
+	"goapp/pkg/pubdate"
+	"time"
+)
+
+// Book is main struct for most of the handlers which is no require flag set.
+// Version and UpdatedAt are set by the storage on every change, a non-zero Version
+// that is passing through to an update is the version that the book is expected to have.
+// DeletedAt is set when the book is deleted until it is restored or purged.
+// PublisherID is the publisher that the Publisher is the name of, 0 when the book has no publisher.
+// Published is the ISO 8601 string of the partial publication date, like 1949, 1949-06 or 1949-06-08,
+// since the storages order, filter and page the books by comparing it as text, see PublishedDate for the typed date.
 type Book struct {
-	ID            int    `json:"book_id" db:"book_id"`
-	ISBN          string `json:"isbn" db:"isbn"`
-	Title         string `json:"title" db:"title"`
-	AuthorName    string `json:"author_name" db:"author_name"`
-	AuthorSurname string `json:"author_surname" db:"author_surname"`
-	Published     string `json:"published" db:"published"`
-	Publisher     string `json:"publisher" db:"publisher"`
+	ID            int        `json:"book_id" db:"book_id"`
+	ISBN          string     `json:"isbn" db:"isbn"`
+	Title         string     `json:"title" db:"title"`
+	AuthorName    string     `json:"author_name" db:"author_name"`
+	AuthorSurname string     `json:"author_surname" db:"author_surname"`
+	Published     string     `json:"published" db:"published"`
+	Publisher     string     `json:"publisher" db:"publisher"`
+	PublisherID   int        `json:"publisher_id,omitempty" db:"publisher_id"`
+	Version       int        `json:"version,omitempty" db:"version"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// PublishedDate will return the typed partial date of Published, its Precision is year, month or day.
+// It will return the pubdate error of the published dates from before their validation that could not be parsed.
+func (b Book) PublishedDate() (pubdate.Date, error) {
+	return pubdate.Parse(b.Published)
 }
 
-// PatchBook for handler that need to have required flag set like patch api
+// PatchBook for handler that need to have required flag set like patch api.
+// A non-zero Version is the version that the book is expected to have,
+// a non-zero PublisherID is the publisher that the book get the name of instead of the Publisher.
 type PatchBook struct {
 	ID            int    `json:"book_id" db:"book_id" binding:"required"`
 	ISBN          string `json:"isbn,omitempty" db:"isbn"`
@@ -20,11 +43,194 @@ type PatchBook struct {
 	AuthorSurname string `json:"author_surname,omitempty" db:"author_surname"`
 	Published     string `json:"published,omitempty" db:"published"`
 	Publisher     string `json:"publisher,omitempty" db:"publisher"`
+	PublisherID   int    `json:"publisher_id,omitempty" db:"publisher_id" binding:"omitempty,min=1"`
+	Version       int    `json:"version,omitempty" db:"version"`
+}
+
+// BookHistory is a change of a book that is recorded with the book before and after it.
+// Before is nil for the inserted books and After for the purged books, Version is the book version after the change.
+type BookHistory struct {
+	HistoryID int       `json:"history_id" db:"history_id"`
+	BookID    int       `json:"book_id" db:"book_id"`
+	Operation string    `json:"operation" db:"operation"`
+	Actor     string    `json:"actor" db:"actor"`
+	Version   int       `json:"version" db:"version"`
+	ChangedAt time.Time `json:"changed_at" db:"changed_at"`
+	Before    *Book     `json:"before"`
+	After     *Book     `json:"after"`
+}
+
+// Operations of BookHistory
+const (
+	HistoryInsert    = "insert"
+	HistoryUpdate    = "update"
+	HistoryPatch     = "patch"
+	HistoryDelete    = "delete"
+	HistoryRestore   = "restore"
+	HistoryPurge     = "purge"
+	HistoryRevert    = "revert"
+	HistoryAuthors   = "authors"
+	HistoryPublisher = "publisher"
+)
+
+// Author is a person that is credited for books with a role like author or translator.
+// The author_name and author_surname of a book are the name of its first credited author.
+type Author struct {
+	ID        int       `json:"author_id" db:"author_id"`
+	Name      string    `json:"name" db:"name"`
+	Surname   string    `json:"surname" db:"surname" binding:"required"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Roles of BookAuthor
+const (
+	RoleAuthor      = "author"
+	RoleEditor      = "editor"
+	RoleTranslator  = "translator"
+	RoleIllustrator = "illustrator"
+)
+
+// Roles are the roles that an author could be credited with
+var Roles = []string{RoleAuthor, RoleEditor, RoleTranslator, RoleIllustrator}
+
+// BookAuthor is an author that is credited for a book with the role, author when it is empty.
+// Position is the order of the author in the book credits from 1, the name and surname are of the author.
+type BookAuthor struct {
+	AuthorID int    `json:"author_id" db:"author_id"`
+	Name     string `json:"name" db:"name"`
+	Surname  string `json:"surname" db:"surname"`
+	Role     string `json:"role" db:"role"`
+	Position int    `json:"position" db:"position"`
 }
 
-// ListBookRequest to define the order by filed, page id and page size to list the books
+// AuthorBook is a book of an author with the role and position that the author is credited with
+type AuthorBook struct {
+	Book
+	Role     string `json:"role" db:"role"`
+	Position int    `json:"position" db:"position"`
+}
+
+// Publisher is a publisher of books, an imprint of the parent publisher when ParentID is set.
+// The aliases are the other spellings of the name, a book with any of them is published by the publisher.
+type Publisher struct {
+	ID        int       `json:"publisher_id" db:"publisher_id"`
+	Name      string    `json:"name" db:"name" binding:"required"`
+	ParentID  *int      `json:"parent_id" db:"parent_id" binding:"omitempty,min=1"`
+	Aliases   []string  `json:"aliases" db:"-"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PublisherCluster is a group of publishers with names that look alike, they are possibly the same publisher.
+// Key is the name that the publishers have in common without the case, punctuation and words like books or press.
+type PublisherCluster struct {
+	Key        string      `json:"key"`
+	Publishers []Publisher `json:"publishers"`
+}
+
+// Pagination modes of ListBookRequest
+const (
+	PaginationPage   = "page"
+	PaginationCursor = "cursor"
+)
+
+// Metadata modes of ListBookRequest
+const (
+	MetaHeaders = "headers"
+	MetaBody    = "body"
+)
+
+// ListBookRequest to define the order by filed, page id and page size to list the books.
+// With cursor pagination the page start from the cursor instead of the page id.
+// Meta select if the total and the page links are returned in the headers or in the response body.
+// The deleted books are only listed with IncludeDeleted.
 type ListBookRequest struct {
-	OrderBy  string `form:"order_by,default=book_id" binding:"omitempty"`
+	OrderBy        string `form:"order_by,default=book_id" binding:"omitempty"`
+	PageID         int    `form:"page_id,default=1" binding:"omitempty,min=1"`
+	PageSize       int    `form:"page_size,default=25" binding:"omitempty,min=5,max=1000"`
+	Pagination     string `form:"pagination,default=page" binding:"oneof=page cursor"`
+	Cursor         string `form:"cursor"`
+	Meta           string `form:"meta,default=headers" binding:"oneof=headers body"`
+	IncludeDeleted bool   `form:"include_deleted"`
+}
+
+// GetBookRequest to define if the book is returned when it is deleted
+type GetBookRequest struct {
+	IncludeDeleted bool `form:"include_deleted"`
+}
+
+// PurgeBookRequest to define how long the deleted books are kept before they are purged,
+// the configured retention is used when it is empty
+type PurgeBookRequest struct {
+	OlderThan string `form:"older_than"`
+}
+
+// BookHistoryRequest to define the page of the book history, the latest change first
+type BookHistoryRequest struct {
+	PageID   int `form:"page_id,default=1" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size,default=25" binding:"omitempty,min=5,max=1000"`
+}
+
+// RevertBookRequest to define the book history entry that the book is reverted to,
+// or the time that the book is reverted to the latest change before it when there is no history id
+type RevertBookRequest struct {
+	HistoryID int       `form:"history_id" binding:"omitempty,min=1"`
+	At        time.Time `form:"at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// ListAuthorRequest to define the page of the authors, ordered by surname and name.
+// Only the authors with Q in their name or surname are listed when it is set.
+type ListAuthorRequest struct {
+	Q        string `form:"q"`
+	PageID   int    `form:"page_id,default=1" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size,default=25" binding:"omitempty,min=5,max=1000"`
+}
+
+// AuthorBooksRequest to define the page of the author books, ordered by published date
+type AuthorBooksRequest struct {
+	PageID   int `form:"page_id,default=1" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size,default=25" binding:"omitempty,min=5,max=1000"`
+}
+
+// ListPublisherRequest to define the page of the publishers, ordered by name.
+// Only the publishers with Q in their name or aliases are listed when it is set,
+// and only the imprints of the parent publisher when ParentID is set.
+type ListPublisherRequest struct {
+	Q        string `form:"q"`
+	ParentID int    `form:"parent_id" binding:"omitempty,min=1"`
 	PageID   int    `form:"page_id,default=1" binding:"omitempty,min=1"`
 	PageSize int    `form:"page_size,default=25" binding:"omitempty,min=5,max=1000"`
 }
+
+// PublisherBooksRequest to define the page of the publisher books, ordered by published date.
+// The books of the imprints of the publisher are listed too with IncludeImprints.
+type PublisherBooksRequest struct {
+	IncludeImprints bool `form:"include_imprints"`
+	PageID          int  `form:"page_id,default=1" binding:"omitempty,min=1"`
+	PageSize        int  `form:"page_size,default=25" binding:"omitempty,min=5,max=1000"`
+}
+
+// MergePublisherRequest to define the publishers that are merged into another publisher
+type MergePublisherRequest struct {
+	PublisherIDs []int `json:"publisher_ids" binding:"required,min=1"`
+}
+
+// FullTextSearchRequest to define the search text, the field boosts and the page of the full-text search.
+// The deleted books are only found with IncludeDeleted.
+type FullTextSearchRequest struct {
+	Q              string `form:"q" binding:"required"`
+	Boost          string `form:"boost"`
+	PageID         int    `form:"page_id,default=1" binding:"omitempty,min=1"`
+	PageSize       int    `form:"page_size,default=25" binding:"omitempty,min=5,max=1000"`
+	IncludeDeleted bool   `form:"include_deleted"`
+}
+
+// Insert modes of InsertBookRequest
+const (
+	InsertAllOrNothing = "all_or_nothing"
+	InsertBestEffort   = "best_effort"
+)
+
+// InsertBookRequest to define whether a batch insert is all or nothing or best effort
+type InsertBookRequest struct {
+	Mode string `form:"mode,default=all_or_nothing" binding:"oneof=all_or_nothing best_effort"`
+}
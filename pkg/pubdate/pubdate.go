@@ -0,0 +1,207 @@
+// Package pubdate will parse and normalize the partial publication dates of the books,
+// a date could be a year, a year and month or a full date.
+package pubdate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrFormat is returned when the date is not in one of the known formats
+	ErrFormat = errors.New("date must be a year, year and month or full date like 1949, 1949-06, June 1949 or 1949-06-08")
+	// ErrRange is returned when the year, month or day of the date does not exist
+	ErrRange = errors.New("date has a year, month or day that does not exist")
+)
+
+// Precision is the most precise part of the date that is known
+type Precision int
+
+// Precisions of Date
+const (
+	Year Precision = iota + 1
+	Month
+	Day
+)
+
+// String will return the name of the precision, year, month or day
+func (p Precision) String() string {
+	switch p {
+	case Year:
+		return "year"
+	case Month:
+		return "month"
+	case Day:
+		return "day"
+	}
+	return fmt.Sprintf("Precision(%d)", int(p))
+}
+
+// Date is a partial date, the Month and Day are 0 when they are not known.
+// Its String is the ISO 8601 form 1949, 1949-06 or 1949-06-08 that sort in the chronological order.
+type Date struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+// Precision will return the most precise part of the date that is known
+func (d Date) Precision() Precision {
+	switch {
+	case d.Day > 0:
+		return Day
+	case d.Month > 0:
+		return Month
+	}
+	return Year
+}
+
+// String will return the date in the ISO 8601 form of its precision
+func (d Date) String() string {
+	switch d.Precision() {
+	case Day:
+		return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+	case Month:
+		return fmt.Sprintf("%04d-%02d", d.Year, d.Month)
+	}
+	return fmt.Sprintf("%04d", d.Year)
+}
+
+// Next will return the first date after the period of the date with the same precision,
+// like 1950 for 1949, 1950-01 for 1949-12 and 1949-07-01 for 1949-06-30
+func (d Date) Next() Date {
+	switch d.Precision() {
+	case Day:
+		t := time.Date(d.Year, time.Month(d.Month), d.Day+1, 0, 0, 0, 0, time.UTC)
+		return Date{Year: t.Year(), Month: int(t.Month()), Day: t.Day()}
+	case Month:
+		if d.Month == 12 {
+			return Date{Year: d.Year + 1, Month: 1}
+		}
+		return Date{Year: d.Year, Month: d.Month + 1}
+	}
+	return Date{Year: d.Year + 1}
+}
+
+// months are the month names and their abbreviations in lower case
+var months = map[string]int{
+	"january": 1, "february": 2, "march": 3, "april": 4, "may": 5, "june": 6, "july": 7,
+	"august": 8, "september": 9, "october": 10, "november": 11, "december": 12,
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "sept": 9,
+	"oct": 10, "nov": 11, "dec": 12,
+}
+
+var (
+	// isoDate match 1949, 1949-06 and 1949-06-08 with - / or . separators
+	isoDate = regexp.MustCompile(`^(\d{4})(?:[-/.](\d{1,2})(?:[-/.](\d{1,2}))?)?$`)
+	// monthYear match 06/1949 and 06.1949
+	monthYear = regexp.MustCompile(`^(\d{1,2})[/.](\d{4})$`)
+	// dottedDate match the 08.06.1949 day first date
+	dottedDate = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})\.(\d{4})$`)
+	// copyright match the c1949 and ©1949 year of the copyright notice
+	copyright = regexp.MustCompile(`^(?:c|©)\.?\s*(\d{4})$`)
+	// ordinal match the day with the st, nd, rd or th suffix
+	ordinal = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+)
+
+// Parse will parse the date of the common formats: 1949, 1949-06, 1949-06-08, 1949/06/08, 06/1949, 08.06.1949,
+// June 1949, Jun. 1949, 8 June 1949, June 8, 1949, c1949 and the RFC 3339 time.
+// The numeric day first and month first dates with / are ambiguous so they are not supported.
+func Parse(s string) (Date, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) > 10 && s[10] == 't' {
+		if t, err := time.Parse(time.RFC3339, strings.ToUpper(s)); err == nil {
+			return Date{Year: t.Year(), Month: int(t.Month()), Day: t.Day()}, nil
+		}
+	}
+	if m := isoDate.FindStringSubmatch(s); m != nil {
+		return newDate(m[1], m[2], m[3])
+	}
+	if m := monthYear.FindStringSubmatch(s); m != nil {
+		return newDate(m[2], m[1], "")
+	}
+	if m := dottedDate.FindStringSubmatch(s); m != nil {
+		return newDate(m[3], m[2], m[1])
+	}
+	if m := copyright.FindStringSubmatch(s); m != nil {
+		return newDate(m[1], "", "")
+	}
+	return parseMonthName(s)
+}
+
+// parseMonthName will parse the dates with the month name like June 1949, 8 June 1949 and June 8, 1949
+func parseMonthName(s string) (Date, error) {
+	fields := strings.Fields(strings.NewReplacer(",", " ", ".", " ").Replace(s))
+	month := func(f string) (string, bool) {
+		m, ok := months[f]
+		return strconv.Itoa(m), ok
+	}
+	day := func(f string) (string, bool) {
+		m := ordinal.FindStringSubmatch(f)
+		if m == nil {
+			return "", false
+		}
+		return m[1], true
+	}
+	switch len(fields) {
+	case 2:
+		if m, ok := month(fields[0]); ok {
+			return newDate(fields[1], m, "")
+		}
+	case 3:
+		if m, ok := month(fields[0]); ok {
+			if d, ok := day(fields[1]); ok {
+				return newDate(fields[2], m, d)
+			}
+		}
+		if m, ok := month(fields[1]); ok {
+			if d, ok := day(fields[0]); ok {
+				return newDate(fields[2], m, d)
+			}
+		}
+	}
+	return Date{}, ErrFormat
+}
+
+// newDate will return the date of the year, month and day digits, the empty month and day are not known
+func newDate(year, month, day string) (Date, error) {
+	var d Date
+	var err error
+	if len(year) != 4 {
+		return d, ErrFormat
+	}
+	if d.Year, err = strconv.Atoi(year); err != nil {
+		return d, ErrFormat
+	}
+	if month != "" {
+		if d.Month, err = strconv.Atoi(month); err != nil {
+			return d, ErrFormat
+		}
+	}
+	if day != "" {
+		if d.Day, err = strconv.Atoi(day); err != nil {
+			return d, ErrFormat
+		}
+	}
+	if d.Year < 1 || (month != "" && (d.Month < 1 || d.Month > 12)) || (day != "" && d.Day < 1) {
+		return Date{}, ErrRange
+	}
+	// time.Date normalize the days that overflow the month into the next month
+	if day != "" && time.Date(d.Year, time.Month(d.Month), d.Day, 0, 0, 0, 0, time.UTC).Day() != d.Day {
+		return Date{}, ErrRange
+	}
+	return d, nil
+}
+
+// Normalize will return the date in the ISO 8601 form of its precision, like 1949-06 for June 1949
+func Normalize(s string) (string, error) {
+	d, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return d.String(), nil
+}
@@ -0,0 +1,156 @@
+package pubdate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in        string
+		want      Date
+		precision Precision
+	}{
+		{"1949", Date{Year: 1949}, Year},
+		{"1949-06", Date{Year: 1949, Month: 6}, Month},
+		{"1949-6", Date{Year: 1949, Month: 6}, Month},
+		{"1949-06-08", Date{Year: 1949, Month: 6, Day: 8}, Day},
+		{"1949/06/08", Date{Year: 1949, Month: 6, Day: 8}, Day},
+		{"1949.6.8", Date{Year: 1949, Month: 6, Day: 8}, Day},
+		{"06/1949", Date{Year: 1949, Month: 6}, Month},
+		{"08.06.1949", Date{Year: 1949, Month: 6, Day: 8}, Day},
+		{"June 1949", Date{Year: 1949, Month: 6}, Month},
+		{"Jun. 1949", Date{Year: 1949, Month: 6}, Month},
+		{"8 June 1949", Date{Year: 1949, Month: 6, Day: 8}, Day},
+		{"8th June 1949", Date{Year: 1949, Month: 6, Day: 8}, Day},
+		{"June 8, 1949", Date{Year: 1949, Month: 6, Day: 8}, Day},
+		{"c1949", Date{Year: 1949}, Year},
+		{"©1949", Date{Year: 1949}, Year},
+		{"c. 1949", Date{Year: 1949}, Year},
+		{" 1949 ", Date{Year: 1949}, Year},
+		{"1949-06-08T10:30:00Z", Date{Year: 1949, Month: 6, Day: 8}, Day},
+		{"2000-02-29", Date{Year: 2000, Month: 2, Day: 29}, Day},
+		{"2024-02-29", Date{Year: 2024, Month: 2, Day: 29}, Day},
+	}
+	for _, tc := range tests {
+		got, err := Parse(tc.in)
+		if err != nil || got != tc.want || got.Precision() != tc.precision {
+			t.Errorf("Parse(%q) = %+v %s, %v, want %+v %s", tc.in, got, got.Precision(), err, tc.want, tc.precision)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		in   string
+		want error
+	}{
+		{"1949-00", ErrRange},
+		{"1949-13", ErrRange},
+		{"13/1949", ErrRange},
+		{"1949-06-00", ErrRange},
+		{"1949-06-31", ErrRange},
+		{"1949-01-32", ErrRange},
+		{"1949-02-29", ErrRange},
+		{"2023-02-29", ErrRange},
+		{"1900-02-29", ErrRange},
+		{"30 February 2000", ErrRange},
+		{"0000", ErrRange},
+		{"", ErrFormat},
+		{"49", ErrFormat},
+		{"12345", ErrFormat},
+		{"06/08/1949", ErrFormat},
+		{"1949-06-08-01", ErrFormat},
+		{"June", ErrFormat},
+		{"Juny 1949", ErrFormat},
+		{"June 49", ErrFormat},
+	}
+	for _, tc := range tests {
+		if got, err := Parse(tc.in); !errors.Is(err, tc.want) {
+			t.Errorf("Parse(%q) = %+v, %v, want %v", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"1949":         "1949",
+		"June 1949":    "1949-06",
+		"8 June 1949":  "1949-06-08",
+		"1949/6/8":     "1949-06-08",
+		"0800":         "0800",
+		"December 999": "",
+	}
+	for in, want := range tests {
+		got, err := Normalize(in)
+		if want == "" {
+			if err == nil {
+				t.Errorf("Normalize(%q) = %q, want an error", in, got)
+			}
+		} else if err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		in   Date
+		want Date
+	}{
+		{Date{Year: 1949}, Date{Year: 1950}},
+		{Date{Year: 1949, Month: 6}, Date{Year: 1949, Month: 7}},
+		{Date{Year: 1949, Month: 12}, Date{Year: 1950, Month: 1}},
+		{Date{Year: 1949, Month: 6, Day: 8}, Date{Year: 1949, Month: 6, Day: 9}},
+		{Date{Year: 1949, Month: 6, Day: 30}, Date{Year: 1949, Month: 7, Day: 1}},
+		{Date{Year: 1949, Month: 12, Day: 31}, Date{Year: 1950, Month: 1, Day: 1}},
+		{Date{Year: 2000, Month: 2, Day: 28}, Date{Year: 2000, Month: 2, Day: 29}},
+		{Date{Year: 1900, Month: 2, Day: 28}, Date{Year: 1900, Month: 3, Day: 1}},
+	}
+	for _, tc := range tests {
+		if got := tc.in.Next(); got != tc.want {
+			t.Errorf("%s Next = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+// TestPeriod will check that the dates of a period sort from the date until its Next date,
+// which is how the storages compare the published period as text
+func TestPeriod(t *testing.T) {
+	tests := []struct {
+		period  string
+		inside  []string
+		outside []string
+	}{
+		{"1949", []string{"1949", "1949-01", "1949-01-01", "1949-12", "1949-12-31"},
+			[]string{"1948", "1948-12-31", "1950", "1950-01-01"}},
+		{"1949-06", []string{"1949-06", "1949-06-01", "1949-06-30"},
+			[]string{"1949", "1949-05-31", "1949-07", "1949-07-01"}},
+		{"1949-12-31", []string{"1949-12-31"}, []string{"1949-12", "1949-12-30", "1950-01-01", "1950-01-02"}},
+	}
+	for _, tc := range tests {
+		d, err := Parse(tc.period)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %s", tc.period, err)
+		}
+		start, end := d.String(), d.Next().String()
+		for _, v := range tc.inside {
+			if v < start || v >= end {
+				t.Errorf("%q is not in the period %s until %s", v, start, end)
+			}
+		}
+		for _, v := range tc.outside {
+			if v >= start && v < end {
+				t.Errorf("%q is in the period %s until %s", v, start, end)
+			}
+		}
+	}
+}
+
+func TestPrecisionString(t *testing.T) {
+	for p, want := range map[Precision]string{Year: "year", Month: "month", Day: "day", 0: "Precision(0)"} {
+		if got := p.String(); got != want {
+			t.Errorf("Precision(%d).String() = %q, want %q", int(p), got, want)
+		}
+	}
+}